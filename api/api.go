@@ -1,12 +1,14 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"yacoid_server/auth"
 	"yacoid_server/constants"
+	"yacoid_server/database"
 	"yacoid_server/types"
 
 	"github.com/go-playground/validator/v10"
@@ -36,7 +38,7 @@ func StartAPI() error {
 
 	api.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(allowedOrigins, ","),
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Last-Event-ID",
 		AllowCredentials: true,
 	}))
 
@@ -44,6 +46,18 @@ func StartAPI() error {
 	validate.RegisterValidation("is-author-type", ValidateAuthorType)
 	validate.RegisterValidation("is-source-type", ValidateSourceType)
 	validate.RegisterValidation("is-definition-category", ValidateDefinitionCategory)
+	validate.RegisterValidation("is-sort-field", ValidateSortField)
+	validate.RegisterValidation("is-sort-order", ValidateSortOrder)
+	validate.RegisterValidation("is-language", ValidateLanguage)
+	validate.RegisterValidation("is-search-mode", ValidateSearchMode)
+	validate.RegisterValidation("is-locator-type", ValidateLocatorType)
+	validate.RegisterValidation("is-source-relation", ValidateSourceRelation)
+	validate.RegisterValidation("is-note-entity", ValidateNoteEntity)
+	validate.RegisterValidation("is-vote", ValidateVote)
+	validate.RegisterValidation("is-collection-visibility", ValidateCollectionVisibility)
+	validate.RegisterValidation("is-notification-type", ValidateNotificationType)
+	validate.RegisterValidation("is-webhook-event", ValidateWebhookEvent)
+	validate.RegisterValidation("is-webhook-delivery-status", ValidateWebhookDeliveryStatus)
 
 	v1 := api.Group("/v1")
 
@@ -56,6 +70,36 @@ func StartAPI() error {
 	sourceApi := v1.Group("/sources")
 	AddSourcesRequests(&sourceApi, validate)
 
+	categoryApi := v1.Group("/categories")
+	AddCategoryRequests(&categoryApi, validate)
+
+	commentApi := v1.Group("/comments")
+	AddCommentRequests(&commentApi, validate)
+
+	ratingApi := v1.Group("/ratings")
+	AddRatingRequests(&ratingApi, validate)
+
+	collectionApi := v1.Group("/collections")
+	AddCollectionRequests(&collectionApi, validate)
+
+	noteApi := v1.Group("/notes")
+	AddModeratorNoteRequests(&noteApi, validate)
+
+	tagApi := v1.Group("/tags")
+	AddTagRequests(&tagApi, validate)
+
+	notificationApi := v1.Group("/notifications")
+	AddNotificationRequests(&notificationApi, validate)
+
+	webhookApi := v1.Group("/webhooks")
+	AddWebhookRequests(&webhookApi, validate)
+
+	moderationApi := v1.Group("/moderation")
+	AddModerationRequests(&moderationApi, validate)
+
+	savedSearchApi := v1.Group("/saved_searches")
+	AddSavedSearchRequests(&savedSearchApi, validate)
+
 	commonApi := v1.Group("/common")
 	AddCommonRequests(&commonApi, validate)
 
@@ -109,7 +153,91 @@ func ValidateSourceType(fieldLevel validator.FieldLevel) bool {
 
 func ValidateDefinitionCategory(fieldLevel validator.FieldLevel) bool {
 
-	_, err := types.ParseStringToDefinitionCategory(fieldLevel.Field().String())
+	exists, err := database.CategoryExists(types.DefinitionCategory(fieldLevel.Field().String()))
+	return err == nil && exists
+
+}
+
+func ValidateSortField(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToSortField(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateSortOrder(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToSortOrder(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateLanguage(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToLanguage(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateSearchMode(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToSearchMode(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateLocatorType(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToLocatorType(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateSourceRelation(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToSourceRelation(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateNoteEntity(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToNoteEntity(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateVote(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToVote(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateCollectionVisibility(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToCollectionVisibility(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateNotificationType(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToNotificationType(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateWebhookEvent(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToWebhookEvent(fieldLevel.Field().String())
+	return err == nil
+
+}
+
+func ValidateWebhookDeliveryStatus(fieldLevel validator.FieldLevel) bool {
+
+	_, err := types.ParseStringToWebhookDeliveryStatus(fieldLevel.Field().String())
 	return err == nil
 
 }
@@ -152,6 +280,11 @@ func GetErrorCode(err error) int {
 		return code
 	}
 
+	// errors with details (e.g. the position of a query syntax error) wrap one of the mapped errors
+	if wrapped := errors.Unwrap(err); wrapped != nil {
+		return GetErrorCode(wrapped)
+	}
+
 	fmt.Printf("Error \"%v\" does not have an error code assigned to it.\n", err)
 	return fiber.StatusInternalServerError
 
@@ -171,11 +304,53 @@ func InitErrorCodeMap() {
 	ErrorCodeMap[constants.ErrorSourceDeletionBecauseInUse] = fiber.StatusBadRequest
 	ErrorCodeMap[constants.ErrorNotEnoughPermissions] = fiber.StatusUnauthorized
 	ErrorCodeMap[constants.ErrorQueryValueRequired] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidPublishingYearRange] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidSort] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidCursor] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidResumeToken] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorSlugNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorQuerySyntax] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorSavedSearchNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorInvalidTag] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTagNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorTagAlreadyApproved] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTagMerged] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTagMergeIntoItself] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCategoryNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorCategoryAlreadyExists] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCategoryInUse] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCategoryCycle] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidCategoryKey] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCommentNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorCommentBelongsToAnotherUser] = fiber.StatusUnauthorized
+	ErrorCodeMap[constants.ErrorCommentDeleted] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCommentHidden] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorModeratorNoteNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorModeratorNoteBelongsToAnotherUser] = fiber.StatusUnauthorized
+	ErrorCodeMap[constants.ErrorRatingNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorRatingOfOwnDefinition] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCollectionNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorCollectionItemNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorCollectionFull] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorDefinitionAlreadyInCollection] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorInvalidCollectionOrder] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorCollectionChanged] = fiber.StatusConflict
+	ErrorCodeMap[constants.ErrorNotificationNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorWebhookNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorWebhookInactive] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorWebhookDeliveryNotFound] = fiber.StatusNotFound
+	ErrorCodeMap[constants.ErrorInvalidWebhookUrl] = fiber.StatusBadRequest
 
 	ErrorCodeMap[constants.ErrorDefinitionNotFound] = fiber.StatusNotFound
 	ErrorCodeMap[constants.ErrorDefinitionAlreadyApproved] = fiber.StatusBadRequest
 	ErrorCodeMap[constants.ErrorDefinitionBelongsToAnotherUser] = fiber.StatusUnauthorized
 	ErrorCodeMap[constants.ErrorDefinitionRejectionNotAnsweredYet] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorDefinitionNotApproved] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorDefinitionDeletionBecauseTranslated] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTranslationSameLanguage] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTranslationAlreadyExists] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorTranslationFieldOfOriginal] = fiber.StatusBadRequest
+	ErrorCodeMap[constants.ErrorDuplicateSourceReference] = fiber.StatusBadRequest
 	ErrorCodeMap[fiber.ErrUnauthorized] = fiber.StatusUnauthorized
 
 }
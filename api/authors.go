@@ -36,6 +36,57 @@ func AddAuthorsRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Get("/by_slug/:slug", func(ctx *fiber.Ctx) error {
+
+		author, alias, err := database.GetAuthorBySlug(ctx.Params("slug"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		// old slugs redirect to the current one
+		if alias {
+			return ctx.Redirect(strings.TrimSuffix(ctx.Path(), ctx.Params("slug"))+author.SlugId, fiber.StatusMovedPermanently)
+		}
+
+		response, err := database.AuthorToResponse(author)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"author": response},
+		})
+
+	})
+
+	(*api).Get("/suggest", func(ctx *fiber.Ctx) error {
+
+		query, err := GetRequiredStringQuery(ctx.Query("q"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Query required", Error: err.Error()})
+		}
+
+		limit := GetOptionalIntParam(ctx.Query("limit"), types.DefaultSuggestionLimit)
+
+		// unapproved authors are only suggested to logged in users
+		_, err = auth.AuthenticateAndGetId(ctx)
+		onlyApproved := err != nil
+
+		suggestions, err := database.SuggestAuthors(query, limit, onlyApproved)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"suggestions": suggestions},
+		})
+
+	})
+
 	(*api).Post("/page_count", func(ctx *fiber.Ctx) error {
 
 		request := new(types.AuthorPageCountRequest)
@@ -80,6 +131,59 @@ func AddAuthorsRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Post("/cursor", func(ctx *fiber.Ctx) error {
+
+		request := new(types.AuthorCursorRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		if request.Filter == nil {
+			request.Filter = &types.AuthorFilter{}
+		}
+
+		if request.Filter.Approved == nil || *request.Filter.Approved == false {
+
+			_, err := auth.AuthenticateAndGetId(ctx)
+
+			if err != nil {
+				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+			}
+
+		}
+
+		page, err := database.GetAuthorCursorPage(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.AuthorsToResponses(&page.Items)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: types.CursorPageResponse{
+				Items:      responses,
+				NextCursor: page.NextCursor,
+				PrevCursor: page.PrevCursor,
+				TotalCount: page.TotalCount,
+			},
+		})
+
+	})
+
 	(*api).Post("/page", func(ctx *fiber.Ctx) error {
 
 		request := new(types.AuthorPageRequest)
@@ -174,13 +278,13 @@ func AddAuthorsRequests(api *fiber.Router, validate *validator.Validate) {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Author ID required", Error: err.Error()})
 		}
 
-		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+		userId, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
 
 		if err != nil {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
 		}
 
-		usedSources, err := database.DeleteAuthor(authorId)
+		usedSources, err := database.DeleteAuthor(authorId, userId)
 
 		if err != nil {
 
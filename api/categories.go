@@ -0,0 +1,128 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func AddCategoryRequests(api *fiber.Router, validate *validator.Validate) {
+
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		categories, err := database.GetCategoryTree()
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"categories": categories},
+		})
+
+	})
+
+	(*api).Post("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.CreateCategoryRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		id, err := database.CreateCategory(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created category!",
+			Data: bson.M{
+				"categoryId": id.Hex(),
+			},
+		})
+
+	})
+
+	(*api).Put("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeCategoryRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeCategory(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed category!",
+		})
+
+	})
+
+	(*api).Delete("/", func(ctx *fiber.Ctx) error {
+
+		key, err := GetRequiredStringQuery(ctx.Query("key"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Category key required", Error: err.Error()})
+		}
+
+		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.DeleteCategory(key)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully deleted category!",
+		})
+
+	})
+
+}
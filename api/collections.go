@@ -0,0 +1,390 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func AddCollectionRequests(api *fiber.Router, validate *validator.Validate) {
+
+	// the collections of the user without their items
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		collections, err := database.GetCollectionsOfUser(id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.CollectionsToResponses(collections, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"collections": responses},
+		})
+
+	})
+
+	// e.g. ?page=1&pageSize=20
+	(*api).Get("/public", func(ctx *fiber.Ctx) error {
+
+		page := GetOptionalIntParam(ctx.Query("page"), 1)
+		pageSize := GetOptionalIntParam(ctx.Query("pageSize"), 20)
+
+		if pageSize > 100 {
+			pageSize = 100
+		}
+
+		collections, totalCount, err := database.GetPublicCollections(page, pageSize)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		// the requester may be the owner of some of the collections
+		id, _, _ := auth.Authenticate(ctx)
+
+		responses, err := database.CollectionsToResponses(collections, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"collections": responses,
+				"totalCount":  totalCount,
+			},
+		})
+
+	})
+
+	// own or public collection with its definitions, ?export=true downloads it as a file
+	(*api).Get("/collection", func(ctx *fiber.Ctx) error {
+
+		collectionId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Collection ID required", Error: err.Error()})
+		}
+
+		id, _, _ := auth.Authenticate(ctx)
+
+		collection, err := database.GetCollection(collectionId, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return sendCollection(ctx, collection, id)
+
+	})
+
+	// unlisted or public collection by the token of its share link
+	(*api).Get("/shared/:token", func(ctx *fiber.Ctx) error {
+
+		collection, err := database.GetCollectionByShareToken(ctx.Params("token"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		id, _, _ := auth.Authenticate(ctx)
+
+		return sendCollection(ctx, collection, id)
+
+	})
+
+	(*api).Post("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.CreateCollectionRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		collectionId, err := database.CreateCollection(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created collection!",
+			Data: bson.M{
+				"collectionId": collectionId.Hex(),
+			},
+		})
+
+	})
+
+	(*api).Put("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeCollectionRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeCollection(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed collection!",
+		})
+
+	})
+
+	(*api).Get("/reset_token", func(ctx *fiber.Ctx) error {
+
+		collectionId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Collection ID required", Error: err.Error()})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		token, err := database.ResetCollectionShareToken(collectionId, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully reset share link!",
+			Data:    bson.M{"shareToken": token},
+		})
+
+	})
+
+	(*api).Delete("/", func(ctx *fiber.Ctx) error {
+
+		collectionId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Collection ID required", Error: err.Error()})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.DeleteCollection(collectionId, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully deleted collection!",
+		})
+
+	})
+
+	(*api).Post("/items", func(ctx *fiber.Ctx) error {
+
+		request := new(types.AddCollectionItemRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.AddCollectionItem(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully added definition to collection!",
+		})
+
+	})
+
+	(*api).Put("/items", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeCollectionItemRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeCollectionItem(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed note!",
+		})
+
+	})
+
+	(*api).Delete("/items", func(ctx *fiber.Ctx) error {
+
+		collectionId, err := GetRequiredStringQuery(ctx.Query("collectionId"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Collection ID required", Error: err.Error()})
+		}
+
+		definitionId, err := GetRequiredStringQuery(ctx.Query("definitionId"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Definition ID required", Error: err.Error()})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.RemoveCollectionItem(collectionId, definitionId, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully removed definition from collection!",
+		})
+
+	})
+
+	(*api).Put("/order", func(ctx *fiber.Ctx) error {
+
+		request := new(types.OrderCollectionRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.OrderCollection(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully ordered collection!",
+		})
+
+	})
+
+}
+
+/* Sends the collection with its definitions, with ?export=true as a file download */
+func sendCollection(ctx *fiber.Ctx, collection *types.Collection, userId string) error {
+
+	response, err := database.CollectionToResponse(collection, userId, true)
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+	}
+
+	if ctx.Query("export") == "true" {
+		ctx.Attachment("collection-" + collection.ID.Hex() + ".json")
+	}
+
+	return ctx.JSON(Response{
+		Data: bson.M{"collection": response},
+	})
+
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func AddCommentRequests(api *fiber.Router, validate *validator.Validate) {
+
+	// e.g. ?definitionId=...&parentId=...&page=1&pageSize=20, hidden comments only for moderators and admins (?hidden=true)
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		request := &types.CommentPageRequest{
+			DefinitionId:  ctx.Query("definitionId"),
+			Page:          GetOptionalIntParam(ctx.Query("page"), 1),
+			PageSize:      GetOptionalIntParam(ctx.Query("pageSize"), 20),
+			IncludeHidden: ctx.Query("hidden") == "true",
+		}
+
+		if parentId := ctx.Query("parentId"); len(parentId) > 0 {
+			request.ParentId = &parentId
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		if request.IncludeHidden {
+
+			_, _, err := auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+			if err != nil {
+				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+			}
+
+		}
+
+		comments, totalCount, err := database.GetComments(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.CommentsToResponses(comments, request.IncludeHidden)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"comments":   responses,
+				"totalCount": totalCount,
+			},
+		})
+
+	})
+
+	(*api).Post("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.CreateCommentRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		commentId, err := database.CreateComment(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created comment!",
+			Data: bson.M{
+				"commentId": commentId.Hex(),
+			},
+		})
+
+	})
+
+	(*api).Put("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeCommentRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeComment(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed comment!",
+		})
+
+	})
+
+	(*api).Get("/hide", func(ctx *fiber.Ctx) error {
+		return setCommentHidden(ctx, true)
+	})
+
+	(*api).Get("/unhide", func(ctx *fiber.Ctx) error {
+		return setCommentHidden(ctx, false)
+	})
+
+	(*api).Delete("/", func(ctx *fiber.Ctx) error {
+
+		commentId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Comment ID required", Error: err.Error()})
+		}
+
+		id, roles, err := auth.Authenticate(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		isModerator := common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+		err = database.DeleteComment(commentId, id, isModerator)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully deleted comment!",
+		})
+
+	})
+
+}
+
+func setCommentHidden(ctx *fiber.Ctx, hidden bool) error {
+
+	commentId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Comment ID required", Error: err.Error()})
+	}
+
+	id, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+	}
+
+	err = database.SetCommentHidden(commentId, hidden, id)
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+	}
+
+	if hidden {
+		return ctx.JSON(Response{Message: "Successfully hid comment!"})
+	}
+
+	return ctx.JSON(Response{Message: "Successfully unhid comment!"})
+
+}
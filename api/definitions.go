@@ -6,6 +6,7 @@ import (
 	"yacoid_server/common"
 	"yacoid_server/constants"
 	"yacoid_server/database"
+	"yacoid_server/query"
 	"yacoid_server/types"
 
 	"github.com/go-playground/validator/v10"
@@ -37,6 +38,31 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Get("/by_slug/:slug", func(ctx *fiber.Ctx) error {
+
+		definition, alias, err := database.GetDefinitionBySlug(ctx.Params("slug"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		// old slugs redirect to the current one
+		if alias {
+			return ctx.Redirect(strings.TrimSuffix(ctx.Path(), ctx.Params("slug"))+definition.SlugId, fiber.StatusMovedPermanently)
+		}
+
+		response, err := database.DefinitionToResponse(definition)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"definition": response},
+		})
+
+	})
+
 	(*api).Post("/submit", func(ctx *fiber.Ctx) error {
 
 		request := new(types.SubmitDefinitionRequest)
@@ -72,6 +98,43 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 		})
 	})
 
+	(*api).Post("/submit_translation", func(ctx *fiber.Ctx) error {
+
+		request := new(types.SubmitTranslationRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		definition, err := database.SubmitTranslation(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created translation!",
+			Data: bson.M{
+				"definitionId": definition.Hex(),
+			},
+		})
+
+	})
+
 	(*api).Get("/approve", func(ctx *fiber.Ctx) error {
 
 		definitionId := ctx.Query("id")
@@ -182,6 +245,30 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Get("/top_rated_definitions", func(ctx *fiber.Ctx) error {
+
+		limit := GetOptionalIntParam(ctx.Query("limit"), 4)
+
+		definitions, err := database.GetTopRatedDefinitions(limit)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.DefinitionsToResponses(&definitions)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"definitions": responses,
+			},
+		})
+
+	})
+
 	(*api).Post("/page_count", func(ctx *fiber.Ctx) error {
 
 		request := new(types.DefinitionPageCountRequest)
@@ -202,22 +289,10 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 			request.Filter = &types.DefinitionFilter{}
 		}
 
-		// user wants to see (only) submitted definitions
-		if request.Filter.Approved == nil || *request.Filter.Approved == false {
-
-			id, roles, err := auth.Authenticate(ctx)
-
-			if err != nil {
-				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
-			}
-
-			// if the user is not an moderator or admin, he can only view unapproved definitions of himself
-			if !common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin) {
-				if request.Filter.UserId == nil || *request.Filter.UserId != id {
-					return ctx.Status(GetErrorCode(constants.ErrorNotEnoughPermissions)).JSON(Response{Message: "Authentication failed", Error: constants.ErrorNotEnoughPermissions.Error()})
-				}
-			}
+		err := authorizeDefinitionFilter(ctx, request.Filter)
 
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
 		}
 
 		count, err := database.GetDefinitionPageCount(request)
@@ -254,64 +329,210 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 			request.Filter = &types.DefinitionFilter{}
 		}
 
-		// user wants to see (only) submitted definitions
-		if request.Filter.Approved == nil || *request.Filter.Approved == false {
+		err := authorizeDefinitionFilter(ctx, request.Filter)
 
-			id, roles, err := auth.Authenticate(ctx)
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
 
-			if err != nil {
-				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
-			}
+		definitions, err := database.GetDefinitions(request)
 
-			// if the user is not an moderator or admin, he can only view unapproved definitions of himself
-			if !common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin) {
-				if request.Filter.UserId == nil || *request.Filter.UserId != id {
-					return ctx.Status(GetErrorCode(constants.ErrorNotEnoughPermissions)).JSON(Response{Message: "Authentication failed", Error: constants.ErrorNotEnoughPermissions.Error()})
-				}
-			}
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := definitionsToPageResponses(ctx, request.Filter, request.AdminInformation, definitions)
 
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
 		}
 
-		definitions, err := database.GetDefinitions(request)
+		highlights, err := database.CreateDefinitionHighlights(definitions, request.Filter)
 
 		if err != nil {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
 		}
 
-		var responses interface{}
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"definitions": responses,
+				"highlights":  highlights,
+			},
+		})
 
-		// if the user wants to see his own definitions, they will have more information in it
-		if request.Filter.UserId != nil {
+	})
 
-			id, roles, err := auth.Authenticate(ctx)
+	(*api).Post("/cursor", func(ctx *fiber.Ctx) error {
 
-			if err == nil && (id == *request.Filter.UserId || common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin)) {
-				responses, err = database.DefinitionsToUserResponses(&definitions)
-			}
+		request := new(types.DefinitionCursorRequest)
 
-		} else if request.AdminInformation != nil && *request.AdminInformation == true {
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
 
-			_, _, err := auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+		validateErrors := request.Validate(validate)
 
-			if err == nil {
-				responses, err = database.DefinitionsToUserResponses(&definitions)
-			}
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
 
+		if request.Filter == nil {
+			request.Filter = &types.DefinitionFilter{}
 		}
 
-		if responses == nil {
+		err := authorizeDefinitionFilter(ctx, request.Filter)
 
-			responses, err = database.DefinitionsToResponses(&definitions)
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
 
-			if err != nil {
-				return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
-			}
+		page, err := database.GetDefinitionCursorPage(request)
 
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := definitionsToPageResponses(ctx, request.Filter, request.AdminInformation, page.Items)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		highlights, err := database.CreateDefinitionHighlights(page.Items, request.Filter)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: types.CursorPageResponse{
+				Items:      responses,
+				NextCursor: page.NextCursor,
+				PrevCursor: page.PrevCursor,
+				TotalCount: page.TotalCount,
+				Highlights: highlights,
+			},
+		})
+
+	})
+
+	(*api).Post("/search", func(ctx *fiber.Ctx) error {
+
+		request := new(types.DefinitionPageRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		if request.Filter == nil {
+			request.Filter = &types.DefinitionFilter{}
+		}
+
+		err := authorizeDefinitionFilter(ctx, request.Filter)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		definitions, totalCount, facets, err := database.SearchDefinitions(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := definitionsToPageResponses(ctx, request.Filter, request.AdminInformation, definitions)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		highlights, err := database.CreateDefinitionHighlights(definitions, request.Filter)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"definitions": responses,
+				"totalCount":  totalCount,
+				"facets":      facets,
+				"highlights":  highlights,
+			},
+		})
+
+	})
+
+	// e.g. ?q=category:artificial_intelligence author:"Alan Turing" year:1950..1960 "learning"
+	(*api).Get("/search", func(ctx *fiber.Ctx) error {
+
+		queryText, err := GetRequiredStringQuery(ctx.Query("q"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Query required", Error: err.Error()})
+		}
+
+		approved := true
+		request := &types.DefinitionPageRequest{
+			Page:     GetOptionalIntParam(ctx.Query("page"), 1),
+			PageSize: GetOptionalIntParam(ctx.Query("pageSize"), 20),
+			Filter:   &types.DefinitionFilter{Approved: &approved, Query: &queryText},
+		}
+
+		if sortField := ctx.Query("sort"); len(sortField) > 0 {
+			request.Sort = &types.SortRequest{Field: types.SortField(sortField), Order: types.SortOrder(ctx.Query("order"))}
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		definitions, totalCount, facets, err := database.SearchDefinitions(request)
+
+		if syntaxError, ok := query.AsSyntaxError(err); ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Message: syntaxError.Message,
+				Error:   err.Error(),
+				Data:    bson.M{"position": syntaxError.Position},
+			})
+		}
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.DefinitionsToResponses(&definitions)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		highlights, err := database.CreateDefinitionHighlights(definitions, request.Filter)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
 		}
 
 		return ctx.JSON(Response{
 			Data: bson.M{
 				"definitions": responses,
+				"totalCount":  totalCount,
+				"facets":      facets,
+				"highlights":  highlights,
 			},
 		})
 
@@ -325,16 +546,29 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Definition ID required", Error: err.Error()})
 		}
 
-		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+		userId, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
 
 		if err != nil {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
 		}
 
-		err = database.DeleteDefinition(definitionId)
+		translations, err := database.DeleteDefinition(definitionId, userId)
 
 		if err != nil {
-			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+
+			if err == constants.ErrorDefinitionDeletionBecauseTranslated {
+
+				return ctx.Status(GetErrorCode(err)).JSON(Response{
+					Error: err.Error(),
+					Data: bson.M{
+						"translations": translations,
+					},
+				})
+
+			} else {
+				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+			}
+
 		}
 
 		return ctx.JSON(Response{
@@ -343,3 +577,62 @@ func AddDefinitionRequests(api *fiber.Router, validate *validator.Validate) {
 	})
 
 }
+
+/* Unapproved definitions can only be seen by moderators, admins and the user who submitted them. */
+func authorizeDefinitionFilter(ctx *fiber.Ctx, filter *types.DefinitionFilter) error {
+
+	// user wants to see (only) submitted definitions
+	if filter.Approved == nil || *filter.Approved == false {
+
+		id, roles, err := auth.Authenticate(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		// if the user is not an moderator or admin, he can only view unapproved definitions of himself
+		if !common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin) {
+			if filter.UserId == nil || *filter.UserId != id {
+				return constants.ErrorNotEnoughPermissions
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
+func definitionsToPageResponses(ctx *fiber.Ctx, filter *types.DefinitionFilter, adminInformation *bool, definitions []*types.Definition) (interface{}, error) {
+
+	wantsAdminInformation := adminInformation != nil && *adminInformation == true
+	id, roles, err := auth.Authenticate(ctx)
+	isStaff := err == nil && common.ArrayContainsOr(roles, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+	// if the user wants to see his own definitions, they will have more information in it
+	isOwner := err == nil && filter != nil && filter.UserId != nil && id == *filter.UserId
+
+	if !isOwner && !(isStaff && (wantsAdminInformation || (filter != nil && filter.UserId != nil))) {
+		return database.DefinitionsToResponses(&definitions)
+	}
+
+	responses, err := database.DefinitionsToUserResponses(&definitions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal notes of the staff are only shown to moderators and admins
+	if isStaff && wantsAdminInformation {
+
+		err = database.AddModeratorNotesToDefinitions(responses)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return responses, nil
+
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// comments sent to keep idle connections (and proxies) open
+	moderationHeartbeatInterval = 20 * time.Second
+	// the delay before the browser reconnects after a lost connection
+	moderationReconnectDelay = 5 * time.Second
+)
+
+/* The id of an event is the token of the change stream, so reconnecting clients resume right after it */
+func writeModerationEvent(writer *bufio.Writer, resumeToken string, event *types.ModerationEvent) error {
+
+	data, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "id: %s\nevent: %s\ndata: %s\n\n", resumeToken, event.Type.String(), data)
+	return err
+
+}
+
+type moderationStreamItem struct {
+	event       *types.ModerationEvent
+	resumeToken string
+}
+
+/* All moderation requests are restricted to moderators and admins */
+func AddModerationRequests(api *fiber.Router, validate *validator.Validate) {
+
+	/*
+		Server-Sent Events about submitted, changed, approved, rejected and deleted definitions, sources and authors.
+		Reconnecting clients send the ID of the last received event as Last-Event-ID header (or ?lastEventId=)
+		and receive the events they missed first. A resync event tells clients, that their events could not be resumed.
+	*/
+	(*api).Get("/events", func(ctx *fiber.Ctx) error {
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		lastEventId := ctx.Get("Last-Event-ID", ctx.Query("lastEventId"))
+
+		// the change stream is opened before the response starts, so no event gets lost in between
+		stream, err := database.WatchModerationEvents(context.Background(), lastEventId)
+
+		// events, which can not be resumed anymore, are skipped and the client has to reload the queue
+		resync := errors.Is(err, constants.ErrorInvalidResumeToken)
+
+		if resync {
+			stream, err = database.WatchModerationEvents(context.Background(), "")
+		}
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Failed to watch moderation events", Error: err.Error()})
+		}
+
+		ctx.Set(fiber.HeaderContentType, "text/event-stream")
+		ctx.Set(fiber.HeaderCacheControl, "no-cache")
+		ctx.Set(fiber.HeaderConnection, "keep-alive")
+		ctx.Set("X-Accel-Buffering", "no")
+
+		ctx.Context().SetBodyStreamWriter(func(writer *bufio.Writer) {
+
+			streamContext, cancel := context.WithCancel(context.Background())
+			items := make(chan moderationStreamItem)
+
+			// the change stream blocks until the next event, it is only read here and closed, when the client left
+			go func() {
+
+				defer close(items)
+				defer stream.Close()
+
+				for {
+
+					event, resumeToken, err := stream.Next(streamContext)
+
+					if err != nil {
+						return
+					}
+
+					select {
+					case items <- moderationStreamItem{event: event, resumeToken: resumeToken}:
+					case <-streamContext.Done():
+						return
+					}
+
+				}
+
+			}()
+
+			defer cancel()
+
+			_, err := fmt.Fprintf(writer, "retry: %d\n\n", moderationReconnectDelay.Milliseconds())
+
+			if err == nil && resync {
+				_, err = writer.WriteString("event: resync\ndata: {}\n\n")
+			}
+
+			if err != nil || writer.Flush() != nil {
+				return
+			}
+
+			heartbeat := time.NewTicker(moderationHeartbeatInterval)
+			defer heartbeat.Stop()
+
+			for {
+
+				select {
+				case item, ok := <-items:
+
+					// the change stream failed, the client resumes after reconnecting
+					if !ok {
+						return
+					}
+
+					err = writeModerationEvent(writer, item.resumeToken, item.event)
+
+				case <-heartbeat.C:
+					_, err = writer.WriteString(": heartbeat\n\n")
+				}
+
+				// a failed flush means, that the client disconnected
+				if err != nil || writer.Flush() != nil {
+					return
+				}
+
+			}
+
+		})
+
+		return nil
+
+	})
+
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/* All note requests are restricted to moderators and admins */
+func AddModeratorNoteRequests(api *fiber.Router, validate *validator.Validate) {
+
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		entityId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Entity ID required", Error: err.Error()})
+		}
+
+		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		notes, err := database.GetModeratorNotesOfEntity(entityId)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"notes": notes},
+		})
+
+	})
+
+	(*api).Post("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.CreateModeratorNoteRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		noteId, err := database.CreateModeratorNote(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created note!",
+			Data: bson.M{
+				"noteId": noteId.Hex(),
+			},
+		})
+
+	})
+
+	(*api).Put("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeModeratorNoteRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeModeratorNote(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed note!",
+		})
+
+	})
+
+	(*api).Delete("/", func(ctx *fiber.Ctx) error {
+
+		noteId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Note ID required", Error: err.Error()})
+		}
+
+		id, roles, err := auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.DeleteModeratorNote(noteId, id, common.ArrayContainsOr(roles, constants.EnumRole.Admin))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully deleted note!",
+		})
+
+	})
+
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func AddNotificationRequests(api *fiber.Router, validate *validator.Validate) {
+
+	// the inbox, e.g. ?page=1&pageSize=20&unread=true
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		request := &types.NotificationPageRequest{
+			Page:       GetOptionalIntParam(ctx.Query("page"), 1),
+			PageSize:   GetOptionalIntParam(ctx.Query("pageSize"), 20),
+			UnreadOnly: ctx.Query("unread") == "true",
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		notifications, totalCount, unreadCount, err := database.GetNotifications(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"notifications": notifications,
+				"totalCount":    totalCount,
+				"unreadCount":   unreadCount,
+			},
+		})
+
+	})
+
+	(*api).Get("/unread_count", func(ctx *fiber.Ctx) error {
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		unreadCount, err := database.CountUnreadNotifications(id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"unreadCount": unreadCount},
+		})
+
+	})
+
+	(*api).Get("/read", func(ctx *fiber.Ctx) error {
+		return setNotificationRead(ctx, true)
+	})
+
+	(*api).Get("/unread", func(ctx *fiber.Ctx) error {
+		return setNotificationRead(ctx, false)
+	})
+
+	(*api).Get("/read_all", func(ctx *fiber.Ctx) error {
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		count, err := database.MarkAllNotificationsRead(id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully marked notifications as read!",
+			Data:    bson.M{"count": count},
+		})
+
+	})
+
+	(*api).Get("/preferences", func(ctx *fiber.Ctx) error {
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		preferences, err := database.GetNotificationPreferences(id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"preferences": preferences},
+		})
+
+	})
+
+	(*api).Put("/preferences", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeNotificationPreferencesRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		preferences, err := database.ChangeNotificationPreferences(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed notification preferences!",
+			Data:    bson.M{"preferences": preferences},
+		})
+
+	})
+
+}
+
+func setNotificationRead(ctx *fiber.Ctx, read bool) error {
+
+	notificationId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Notification ID required", Error: err.Error()})
+	}
+
+	id, err := auth.AuthenticateAndGetId(ctx)
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+	}
+
+	err = database.SetNotificationRead(notificationId, read, id)
+
+	if err != nil {
+		return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+	}
+
+	if read {
+		return ctx.JSON(Response{Message: "Successfully marked notification as read!"})
+	}
+
+	return ctx.JSON(Response{Message: "Successfully marked notification as unread!"})
+
+}
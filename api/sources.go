@@ -36,6 +36,31 @@ func AddSourcesRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Get("/by_slug/:slug", func(ctx *fiber.Ctx) error {
+
+		source, alias, err := database.GetSourceBySlug(ctx.Params("slug"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		// old slugs redirect to the current one
+		if alias {
+			return ctx.Redirect(strings.TrimSuffix(ctx.Path(), ctx.Params("slug"))+source.SlugId, fiber.StatusMovedPermanently)
+		}
+
+		response, err := database.SourceToResponse(source)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"source": response},
+		})
+
+	})
+
 	(*api).Post("/", func(ctx *fiber.Ctx) error {
 
 		request := new(types.CreateSourceRequest)
@@ -80,13 +105,13 @@ func AddSourcesRequests(api *fiber.Router, validate *validator.Validate) {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Source ID required", Error: err.Error()})
 		}
 
-		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
+		userId, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Moderator, constants.EnumRole.Admin)
 
 		if err != nil {
 			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
 		}
 
-		usedDefinitions, err := database.DeleteSource(sourceId)
+		usedDefinitions, err := database.DeleteSource(sourceId, userId)
 
 		if err != nil {
 
@@ -142,6 +167,32 @@ func AddSourcesRequests(api *fiber.Router, validate *validator.Validate) {
 		})
 	})
 
+	(*api).Get("/suggest", func(ctx *fiber.Ctx) error {
+
+		query, err := GetRequiredStringQuery(ctx.Query("q"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Query required", Error: err.Error()})
+		}
+
+		limit := GetOptionalIntParam(ctx.Query("limit"), types.DefaultSuggestionLimit)
+
+		// unapproved sources are only suggested to logged in users
+		_, err = auth.AuthenticateAndGetId(ctx)
+		onlyApproved := err != nil
+
+		suggestions, err := database.SuggestSources(query, limit, onlyApproved)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"suggestions": suggestions},
+		})
+
+	})
+
 	(*api).Post("/page_count", func(ctx *fiber.Ctx) error {
 
 		request := new(types.SourcePageCountRequest)
@@ -186,6 +237,59 @@ func AddSourcesRequests(api *fiber.Router, validate *validator.Validate) {
 
 	})
 
+	(*api).Post("/cursor", func(ctx *fiber.Ctx) error {
+
+		request := new(types.SourceCursorRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		if request.Filter == nil {
+			request.Filter = &types.SourceFilter{}
+		}
+
+		if request.Filter.Approved == nil || *request.Filter.Approved == false {
+
+			_, err := auth.AuthenticateAndGetId(ctx)
+
+			if err != nil {
+				return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+			}
+
+		}
+
+		page, err := database.GetSourceCursorPage(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		responses, err := database.SourcesToResponses(&page.Items)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: types.CursorPageResponse{
+				Items:      responses,
+				NextCursor: page.NextCursor,
+				PrevCursor: page.PrevCursor,
+				TotalCount: page.TotalCount,
+			},
+		})
+
+	})
+
 	(*api).Post("/page", func(ctx *fiber.Ctx) error {
 
 		request := new(types.SourcePageRequest)
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"strings"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/* All webhook requests are restricted to admins */
+func AddWebhookRequests(api *fiber.Router, validate *validator.Validate) {
+
+	(*api).Get("/", func(ctx *fiber.Ctx) error {
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		webhooks, err := database.GetWebhooks()
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{"webhooks": webhooks},
+		})
+
+	})
+
+	/* The secret is only returned once, it can be replaced with /rotate_secret */
+	(*api).Post("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.CreateWebhookRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		id, err := auth.AuthenticateAndGetId(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		webhook, err := database.CreateWebhook(request, id)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully created webhook!",
+			Data: bson.M{
+				"webhook": webhook,
+				"secret":  webhook.Secret,
+			},
+		})
+
+	})
+
+	(*api).Put("/", func(ctx *fiber.Ctx) error {
+
+		request := new(types.ChangeWebhookRequest)
+
+		if err := ctx.BodyParser(request); err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.ChangeWebhook(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully changed webhook!",
+		})
+
+	})
+
+	(*api).Delete("/", func(ctx *fiber.Ctx) error {
+
+		webhookId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Webhook ID required", Error: err.Error()})
+		}
+
+		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		err = database.DeleteWebhook(webhookId)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Deletion failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully deleted webhook!",
+		})
+
+	})
+
+	(*api).Get("/rotate_secret", func(ctx *fiber.Ctx) error {
+
+		webhookId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Webhook ID required", Error: err.Error()})
+		}
+
+		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		secret, err := database.RotateWebhookSecret(webhookId)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Successfully rotated secret!",
+			Data:    bson.M{"secret": *secret},
+		})
+
+	})
+
+	// the delivery log, e.g. ?webhookId=...&status=failed&page=1&pageSize=20
+	(*api).Get("/deliveries", func(ctx *fiber.Ctx) error {
+
+		request := &types.WebhookDeliveryPageRequest{
+			WebhookId: ctx.Query("webhookId"),
+			Page:      GetOptionalIntParam(ctx.Query("page"), 1),
+			PageSize:  GetOptionalIntParam(ctx.Query("pageSize"), 20),
+		}
+
+		if status := ctx.Query("status"); len(status) > 0 {
+			deliveryStatus := types.WebhookDeliveryStatus(status)
+			request.Status = &deliveryStatus
+		}
+
+		validateErrors := request.Validate(validate)
+
+		if validateErrors != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Response{
+				Error: "Error on fields: " + strings.Join(validateErrors, ", "),
+			})
+		}
+
+		_, _, err := auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		deliveries, totalCount, err := database.GetWebhookDeliveries(request)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Data: bson.M{
+				"deliveries": deliveries,
+				"totalCount": totalCount,
+			},
+		})
+
+	})
+
+	/* Sends a delivery again, regardless of its status, and returns the attempt */
+	(*api).Post("/redeliver", func(ctx *fiber.Ctx) error {
+
+		deliveryId, err := GetRequiredStringQuery(ctx.Query("id"))
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Delivery ID required", Error: err.Error()})
+		}
+
+		_, _, err = auth.Authenticate(ctx, constants.EnumRole.Admin)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Authentication failed", Error: err.Error()})
+		}
+
+		attempt, err := database.RedeliverWebhook(deliveryId)
+
+		if err != nil {
+			return ctx.Status(GetErrorCode(err)).JSON(Response{Message: "Redelivery failed", Error: err.Error()})
+		}
+
+		return ctx.JSON(Response{
+			Message: "Redelivered webhook!",
+			Data:    bson.M{"attempt": attempt},
+		})
+
+	})
+
+}
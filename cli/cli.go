@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"yacoid_server/auth"
+	"yacoid_server/common"
+	"yacoid_server/database"
+	"yacoid_server/notify"
+)
+
+/* Exit codes of the binary. They are stable, so scripts can rely on them. */
+const (
+	ExitSuccess = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+type command struct {
+	name        string
+	usage       string
+	description string
+	needsAuth   bool
+	run         func(args []string) int
+}
+
+func getCommands() []command {
+	return []command{
+		{name: "serve", usage: "serve [-migrate]", description: "Starts the REST API (default), fails if migrations are pending", needsAuth: true, run: runServe},
+		{name: "migrate", usage: "migrate up [-to <version>] | down [-steps <n>] | status", description: "Applies, reverts or lists the database migrations", run: runMigrate},
+		{name: "seed", usage: "seed [-seed <n>] [-definitions <n>] [-users <ids>] [-drop] ...", description: "Creates deterministic demo data", run: runSeed},
+		{name: "export", usage: "export [-dir <directory>] [-status all|approved|unapproved]", description: "Exports definitions, sources and authors as JSON Lines", run: runExport},
+		{name: "import", usage: "import [-dir <directory>] [-dry-run]", description: "Validates and imports an export", run: runImport},
+		{name: "check", usage: "check", description: "Checks the references and approval states of all entities", run: runCheck},
+		{name: "user", usage: "user <id>", description: "Prints a user of the auth system", needsAuth: true, run: runUser},
+	}
+}
+
+var commandAliases = map[string]string{
+	"dump":    "export",
+	"restore": "import",
+}
+
+/* Runs the subcommand given in the arguments (without the program name) and returns the exit code. */
+func Run(args []string) int {
+
+	name := "serve"
+
+	if len(args) > 0 {
+		name = args[0]
+		args = args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return ExitSuccess
+	}
+
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+
+	for _, command := range getCommands() {
+
+		if command.name != name {
+			continue
+		}
+
+		err := setup(command.needsAuth)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return ExitFailure
+		}
+
+		defer database.Disconnect()
+
+		return command.run(args)
+
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command \"%s\"\n\n", name)
+	printUsage()
+	return ExitUsage
+
+}
+
+/* Loads the configuration and connects to the database (and auth system), shared by all commands. */
+func setup(needsAuth bool) error {
+
+	err := common.LoadEnvironmentVariables()
+
+	if err != nil {
+		fmt.Printf("Failed to load env variables from .env-file. Using OS env variables. Error: %v\n", err)
+	}
+
+	err = database.Connect()
+
+	if err != nil {
+		return fmt.Errorf("Failed to connect to database: %v", err)
+	}
+
+	if needsAuth {
+
+		err = auth.Initialize()
+
+		if err != nil {
+			return fmt.Errorf("Failed to connect to auth system: %v", err)
+		}
+
+		// email senders look up the addresses of the users in the auth system
+		err = notify.Initialize()
+
+		if err != nil {
+			return fmt.Errorf("Failed to set up notifications: %v", err)
+		}
+
+	}
+
+	return nil
+
+}
+
+func printUsage() {
+
+	fmt.Fprintln(os.Stderr, "Usage: server <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	for _, command := range getCommands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.name, command.description)
+		fmt.Fprintf(os.Stderr, "  %-10s   server %s\n", "", command.usage)
+	}
+
+}
+
+/* Parses the flags and returns the exit code to use, if parsing failed. */
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+
+	err := flags.Parse(args)
+
+	if err == flag.ErrHelp {
+		return ExitSuccess, false
+	}
+
+	if err != nil {
+		return ExitUsage, false
+	}
+
+	return ExitSuccess, true
+
+}
+
+func printJSON(value interface{}) {
+
+	bytes, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+
+	fmt.Println(string(bytes))
+
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"yacoid_server/constants"
+	"yacoid_server/database"
+	"yacoid_server/types"
+)
+
+func runSeed(args []string) int {
+
+	defaults := types.DefaultSeedOptions()
+
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	seed := flags.Int64("seed", defaults.Seed, "seed of the random generator, the same seed always creates the same data")
+	persons := flags.Int("persons", defaults.Persons, "number of person authors")
+	organizations := flags.Int("organizations", defaults.Organizations, "number of organization authors")
+	books := flags.Int("books", defaults.Books, "number of book sources")
+	journals := flags.Int("journals", defaults.Journals, "number of journal sources")
+	websites := flags.Int("websites", defaults.Websites, "number of web sources")
+	definitions := flags.Int("definitions", defaults.Definitions, "number of definitions")
+	users := flags.String("users", "", "comma separated user IDs of the auth system used as submitters (default: fake IDs)")
+	moderator := flags.String("moderator", defaults.ModeratorId, "user ID used as approver and rejecter")
+	drop := flags.Bool("drop", false, "delete ALL definitions, sources and authors with their events, notifications and webhook deliveries before seeding")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	for _, count := range []int{*persons, *organizations, *books, *journals, *websites, *definitions} {
+		if count < 0 {
+			fmt.Fprintln(os.Stderr, "Counts must not be negative")
+			return ExitUsage
+		}
+	}
+
+	seedOptions := types.SeedOptions{
+		Seed:          *seed,
+		Persons:       *persons,
+		Organizations: *organizations,
+		Books:         *books,
+		Journals:      *journals,
+		Websites:      *websites,
+		Definitions:   *definitions,
+		ModeratorId:   *moderator,
+		Drop:          *drop,
+	}
+
+	if len(*users) > 0 {
+		seedOptions.UserIds = strings.Split(*users, ",")
+	}
+
+	result, err := database.Seed(&seedOptions)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Seeding failed: %v\n", err)
+		return ExitFailure
+	}
+
+	printJSON(result)
+	return ExitSuccess
+
+}
+
+func runExport(args []string) int {
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	directory := flags.String("dir", "dump", "directory to write the export into")
+	status := flags.String("status", "all", "approval status of the exported entities (all, approved, unapproved)")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	dumpStatus, err := types.ParseStringToDumpStatus(*status)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid status \"%s\"\n", *status)
+		return ExitUsage
+	}
+
+	manifest, err := database.DumpDatabase(&types.DumpOptions{Directory: *directory, Status: dumpStatus})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return ExitFailure
+	}
+
+	for _, entry := range manifest.Files {
+		fmt.Printf("Exported %d %s into %s\n", entry.Count, entry.Collection, entry.File)
+	}
+
+	return ExitSuccess
+
+}
+
+func runImport(args []string) int {
+
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	directory := flags.String("dir", "dump", "directory containing the export")
+	dryRun := flags.Bool("dry-run", false, "only validate the export without writing anything")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	report, err := database.RestoreDatabase(&types.RestoreOptions{Directory: *directory, DryRun: *dryRun})
+
+	if report != nil {
+		printJSON(report)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		// validated imports fail while writing and may be partially written
+		if report != nil && err != constants.ErrorRestoreValidation {
+			fmt.Fprintln(os.Stderr, "The import may be partially written, run it again to complete it")
+		}
+		return ExitFailure
+	}
+
+	return ExitSuccess
+
+}
+
+func runCheck(args []string) int {
+
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	report, err := database.CheckIntegrity()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Check failed: %v\n", err)
+		return ExitFailure
+	}
+
+	for _, issue := range report.Issues {
+		fmt.Printf("%s %s %s\n", issue.Collection, issue.ID.Hex(), issue.Problem)
+	}
+
+	if len(report.Issues) > 0 {
+		fmt.Fprintf(os.Stderr, "Found %d issues\n", len(report.Issues))
+		return ExitFailure
+	}
+
+	fmt.Println("No issues found")
+	return ExitSuccess
+
+}
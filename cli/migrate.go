@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"yacoid_server/database"
+)
+
+func runMigrate(args []string) int {
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: server migrate up [-to <version>] | down [-steps <n>] | status")
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "up":
+
+		flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		targetVersion := flags.Int("to", 0, "version to migrate to (default: latest)")
+
+		if code, ok := parseFlags(flags, args[1:]); !ok {
+			return code
+		}
+
+		executed, err := database.MigrateUp(*targetVersion)
+
+		for _, migration := range executed {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return ExitFailure
+		}
+
+		if len(executed) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+
+		if code, ok := parseFlags(flags, args[1:]); !ok {
+			return code
+		}
+
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "steps must be at least 1")
+			return ExitUsage
+		}
+
+		reverted, err := database.MigrateDown(*steps)
+
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return ExitFailure
+		}
+
+	case "status":
+
+		status, err := database.GetMigrationStatus()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get migration status: %v\n", err)
+			return ExitFailure
+		}
+
+		for _, migration := range status {
+
+			state := "pending"
+			if migration.Applied {
+				state = "applied " + migration.AppliedDate.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%4d  %-40s %s\n", migration.Version, migration.Name, state)
+
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate command \"%s\"\n", args[0])
+		return ExitUsage
+	}
+
+	return ExitSuccess
+
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"yacoid_server/api"
+	"yacoid_server/database"
+	"yacoid_server/types"
+)
+
+func runServe(args []string) int {
+
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	migrate := flags.Bool("migrate", false, "apply pending migrations before starting")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	err := database.CheckTransactionSupport()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The database does not support transactions, MongoDB has to run as a replica set: %v\n", err)
+		return ExitFailure
+	}
+
+	if *migrate {
+
+		executed, err := database.MigrateUp(0)
+
+		for _, migration := range executed {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return ExitFailure
+		}
+
+	}
+
+	// the validators and the duplicate checks rely on the data and unique indexes created by the migrations
+	pending, err := getPendingMigrations()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get migration status: %v\n", err)
+		return ExitFailure
+	}
+
+	if len(pending) > 0 {
+
+		fmt.Fprintln(os.Stderr, "The database has pending migrations, run \"server migrate up\" or start with \"server serve -migrate\":")
+
+		for _, migration := range pending {
+			fmt.Fprintf(os.Stderr, "  %d_%s\n", migration.Version, migration.Name)
+		}
+
+		return ExitFailure
+
+	}
+
+	database.StartEventDispatcher()
+	database.StartWebhookWorker()
+	database.StartNotificationEmailWorker()
+
+	err = api.StartAPI()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start server: %v\n", err)
+		return ExitFailure
+	}
+
+	return ExitSuccess
+
+}
+
+func getPendingMigrations() ([]types.MigrationStatus, error) {
+
+	status, err := database.GetMigrationStatus()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []types.MigrationStatus{}
+
+	for _, migration := range status {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"yacoid_server/auth"
+)
+
+func runUser(args []string) int {
+
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: server user <id>")
+		return ExitUsage
+	}
+
+	user, err := auth.GetUser(args[0])
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get user: %v\n", err)
+		return ExitFailure
+	}
+
+	printJSON(user)
+	return ExitSuccess
+
+}
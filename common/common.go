@@ -1,6 +1,8 @@
 package common
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"time"
 	"yacoid_server/constants"
 
@@ -80,6 +82,21 @@ func GetCurrentQuarterDate() time.Time {
 
 }
 
+/* A random URL safe token, e.g. for share links */
+func CreateRandomToken(length int) (string, error) {
+
+	data := make([]byte, length)
+
+	_, err := rand.Read(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+
+}
+
 func LoadEnvironmentVariables() error {
 
 	err := godotenv.Load(".env")
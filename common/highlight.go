@@ -0,0 +1,319 @@
+package common
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* Words shorter than this must match as prefix, longer ones may differ in their last two characters (simple stemming) */
+const minStemLength = 5
+
+/* Parsed text search: "quoted phrases" and words prefixed with "-" are recognized */
+type SearchQuery struct {
+	Terms    []string
+	Phrases  []string
+	Excluded []string
+}
+
+func ParseSearchQuery(text string) SearchQuery {
+
+	query := SearchQuery{Terms: []string{}, Phrases: []string{}, Excluded: []string{}}
+	runes := []rune(text)
+
+	for index := 0; index < len(runes); {
+
+		if unicode.IsSpace(runes[index]) {
+			index++
+			continue
+		}
+
+		excluded := false
+		if runes[index] == '-' {
+			excluded = true
+			index++
+		}
+
+		if index < len(runes) && runes[index] == '"' {
+
+			// an unterminated quote reaches until the end of the text
+			end := index + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			phrase := strings.Join(strings.Fields(string(runes[index+1:end])), " ")
+			index = end + 1
+
+			if len(phrase) == 0 {
+				continue
+			}
+
+			if excluded {
+				query.Excluded = append(query.Excluded, phrase)
+			} else {
+				query.Phrases = append(query.Phrases, phrase)
+			}
+
+			continue
+
+		}
+
+		end := index
+		for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+			end++
+		}
+
+		term := string(runes[index:end])
+		index = end
+
+		if len(term) == 0 {
+			continue
+		}
+
+		if excluded {
+			query.Excluded = append(query.Excluded, term)
+		} else {
+			query.Terms = append(query.Terms, term)
+		}
+
+	}
+
+	return query
+
+}
+
+/* Creates the $search string of a MongoDB text search. If allTerms is true, every term is required. */
+func (query SearchQuery) ToTextSearch(allTerms bool) string {
+
+	parts := []string{}
+
+	for _, term := range query.Terms {
+		if allTerms {
+			parts = append(parts, "\""+term+"\"")
+		} else {
+			parts = append(parts, term)
+		}
+	}
+
+	for _, phrase := range query.Phrases {
+		parts = append(parts, "\""+phrase+"\"")
+	}
+
+	for _, excluded := range query.Excluded {
+		if strings.Contains(excluded, " ") {
+			parts = append(parts, "-\""+excluded+"\"")
+		} else {
+			parts = append(parts, "-"+excluded)
+		}
+	}
+
+	return strings.Join(parts, " ")
+
+}
+
+type textWord struct {
+	start      int
+	end        int
+	normalized string
+}
+
+type textRange struct {
+	start int
+	end   int
+}
+
+func splitTextWords(text string) []textWord {
+
+	words := []textWord{}
+	start := -1
+
+	for index, character := range text {
+
+		isWordCharacter := unicode.IsLetter(character) || unicode.IsDigit(character)
+
+		if isWordCharacter && start < 0 {
+			start = index
+		} else if !isWordCharacter && start >= 0 {
+			words = append(words, textWord{start: start, end: index, normalized: normalizeWord(text[start:index])})
+			start = -1
+		}
+
+	}
+
+	if start >= 0 {
+		words = append(words, textWord{start: start, end: len(text), normalized: normalizeWord(text[start:])})
+	}
+
+	return words
+
+}
+
+func normalizeWord(word string) string {
+	return strings.Join(NormalizeSearchText(word), "")
+}
+
+func matchesSearchTerm(word string, term string) bool {
+
+	if strings.HasPrefix(word, term) {
+		return true
+	}
+
+	if len(term) < minStemLength {
+		return false
+	}
+
+	return strings.HasPrefix(word, term[:len(term)-2])
+
+}
+
+func findHighlightRanges(words []textWord, query SearchQuery) []textRange {
+
+	ranges := []textRange{}
+
+	for _, term := range query.Terms {
+
+		normalized := normalizeWord(term)
+
+		if len(normalized) == 0 {
+			continue
+		}
+
+		for _, word := range words {
+			if matchesSearchTerm(word.normalized, normalized) {
+				ranges = append(ranges, textRange{start: word.start, end: word.end})
+			}
+		}
+
+	}
+
+	for _, phrase := range query.Phrases {
+
+		phraseWords := NormalizeSearchText(phrase)
+
+		if len(phraseWords) == 0 {
+			continue
+		}
+
+		for index := 0; index+len(phraseWords) <= len(words); index++ {
+
+			matches := true
+
+			for offset, phraseWord := range phraseWords {
+				if words[index+offset].normalized != phraseWord {
+					matches = false
+					break
+				}
+			}
+
+			if matches {
+				ranges = append(ranges, textRange{start: words[index].start, end: words[index+len(phraseWords)-1].end})
+			}
+
+		}
+
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	merged := []textRange{}
+
+	for _, current := range ranges {
+		if len(merged) > 0 && current.start <= merged[len(merged)-1].end {
+			if current.end > merged[len(merged)-1].end {
+				merged[len(merged)-1].end = current.end
+			}
+		} else {
+			merged = append(merged, current)
+		}
+	}
+
+	return merged
+
+}
+
+/*
+Returns a snippet of at most maxLength characters around the first match, with every match wrapped in
+<mark> tags and everything else HTML escaped. The bool is false, if nothing in the text matches.
+*/
+func HighlightText(text string, query SearchQuery, maxLength int) (string, bool) {
+
+	words := splitTextWords(text)
+	ranges := findHighlightRanges(words, query)
+
+	if len(ranges) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+
+	if utf8.RuneCountInString(text) > maxLength {
+
+		// the snippet starts a third of its length before the first match, at a word boundary
+		start = ranges[0].start
+		before := maxLength / 3
+
+		for index := len(words) - 1; index >= 0; index-- {
+			if words[index].start <= ranges[0].start && utf8.RuneCountInString(text[words[index].start:ranges[0].start]) <= before {
+				start = words[index].start
+			}
+		}
+
+		end = start
+		for _, word := range words {
+			if word.start >= start && utf8.RuneCountInString(text[start:word.end]) <= maxLength {
+				end = word.end
+			}
+		}
+
+		if end < ranges[0].end {
+			end = ranges[0].end
+		}
+
+	}
+
+	builder := strings.Builder{}
+
+	if start > 0 {
+		builder.WriteString("…")
+	}
+
+	position := start
+
+	for _, textRange := range ranges {
+
+		if textRange.end <= start || textRange.start >= end {
+			continue
+		}
+
+		rangeStart := textRange.start
+		if rangeStart < start {
+			rangeStart = start
+		}
+
+		builder.WriteString(html.EscapeString(text[position:rangeStart]))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(text[rangeStart:minInt(textRange.end, end)]))
+		builder.WriteString("</mark>")
+		position = minInt(textRange.end, end)
+
+	}
+
+	builder.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		builder.WriteString("…")
+	}
+
+	return builder.String(), true
+
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHighlightText(t *testing.T) {
+
+	tests := []struct {
+		text     string
+		query    string
+		expected string
+	}{
+		{"Machine learning is learning by machines", "learning", "Machine <mark>learning</mark> is <mark>learning</mark> by machines"},
+		// case and accents are ignored, the original spelling is kept
+		{"Machine Learning", "MACHINE", "<mark>Machine</mark> Learning"},
+		{"Über die Größe der Straße", "grösse", "Über die <mark>Größe</mark> der Straße"},
+		{"naïve café", "cafe", "naïve <mark>café</mark>"},
+		{"Привет, мир!", "мир", "Привет, <mark>мир</mark>!"},
+		// terms match as prefix and longer terms also without their last two characters
+		{"Übergröße", "über", "<mark>Übergröße</mark>"},
+		{"computers compute", "computer", "<mark>computers</mark> <mark>compute</mark>"},
+		{"a computation", "computer", "a <mark>computation</mark>"},
+		// overlapping matches of a phrase and a term are merged into one mark
+		{"deep machine learning", `"machine learning" learning`, "deep <mark>machine learning</mark>"},
+		{"machine learning, learning machine", `"machine learning" "learning machine"`, "<mark>machine learning</mark>, <mark>learning machine</mark>"},
+		{"learning machine", `"machine learning"`, ""},
+		// words end at punctuation, everything outside of the marks is escaped
+		{"a <b> & c", "b", "a &lt;<mark>b</mark>&gt; &amp; c"},
+		{"state-of-the-art", "art", "state-of-the-<mark>art</mark>"},
+		{"nothing here", "absent", ""},
+		{"excluded words are not highlighted", "-excluded", ""},
+	}
+
+	for _, test := range tests {
+
+		actual, ok := HighlightText(test.text, ParseSearchQuery(test.query), 200)
+
+		if ok != (len(test.expected) > 0) || actual != test.expected {
+			t.Errorf("HighlightText(%q, %q) = %q, %v, expected %q", test.text, test.query, actual, ok, test.expected)
+		}
+
+	}
+
+}
+
+func TestHighlightTextSnippet(t *testing.T) {
+
+	text := strings.Repeat("äöü ", 30) + "Ziel " + strings.Repeat("ß ", 30)
+	snippet, ok := HighlightText(text, ParseSearchQuery("ziel"), 40)
+
+	if !ok {
+		t.Fatalf("HighlightText did not find the term")
+	}
+
+	if !utf8.ValidString(snippet) {
+		t.Errorf("the snippet %q is not valid UTF-8, it was cut inside a character", snippet)
+	}
+
+	if !strings.HasPrefix(snippet, "…äöü ") || !strings.HasSuffix(snippet, " ß…") {
+		t.Errorf("the snippet %q does not start and end at word boundaries with an ellipsis", snippet)
+	}
+
+	if !strings.Contains(snippet, "<mark>Ziel</mark>") {
+		t.Errorf("the snippet %q does not contain the match", snippet)
+	}
+
+	// the marks and the ellipses are not counted
+	plain := strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(snippet)
+
+	if length := utf8.RuneCountInString(plain); length > 40 {
+		t.Errorf("the snippet %q has %d characters, expected at most 40", snippet, length)
+	}
+
+}
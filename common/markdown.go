@@ -0,0 +1,27 @@
+package common
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// raw HTML in the Markdown is omitted by goldmark, the policy removes everything else that is unsafe
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Strikethrough, extension.Linkify))
+var markdownPolicy = bluemonday.UGCPolicy()
+
+/* Renders user submitted Markdown to sanitized HTML */
+func RenderMarkdown(source string) (string, error) {
+
+	var buffer bytes.Buffer
+	err := markdown.Convert([]byte(source), &buffer)
+
+	if err != nil {
+		return "", err
+	}
+
+	return markdownPolicy.Sanitize(buffer.String()), nil
+
+}
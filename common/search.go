@@ -0,0 +1,72 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gosimple/unidecode"
+)
+
+const maxSearchPrefixLength = 32
+
+/* Transliterates the text to ASCII, lowercases it and splits it into words of letters and digits. */
+func NormalizeSearchText(text string) []string {
+
+	normalized := strings.ToLower(unidecode.Unidecode(text))
+
+	return strings.FieldsFunc(normalized, func(character rune) bool {
+		return !unicode.IsLetter(character) && !unicode.IsDigit(character)
+	})
+
+}
+
+/* Normalizes an identifier like an ISBN or a DOI to one word without separators. */
+func NormalizeSearchIdentifier(identifier string) string {
+	return strings.Join(NormalizeSearchText(identifier), "")
+}
+
+/* All prefixes of the words up to a length of 32 characters, without duplicates. */
+func CreateSearchPrefixes(words []string) []string {
+
+	prefixes := []string{}
+	seen := map[string]bool{}
+
+	for _, word := range words {
+		for length := 1; length <= len(word) && length <= maxSearchPrefixLength; length++ {
+
+			prefix := word[:length]
+
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+
+		}
+	}
+
+	return prefixes
+
+}
+
+/* All trigrams of the words, without duplicates. Words shorter than three characters have no trigrams. */
+func CreateSearchTrigrams(words []string) []string {
+
+	trigrams := []string{}
+	seen := map[string]bool{}
+
+	for _, word := range words {
+		for index := 0; index+3 <= len(word); index++ {
+
+			trigram := word[index : index+3]
+
+			if !seen[trigram] {
+				seen[trigram] = true
+				trigrams = append(trigrams, trigram)
+			}
+
+		}
+	}
+
+	return trigrams
+
+}
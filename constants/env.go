@@ -7,4 +7,10 @@ const (
 	EnvAuthAdminSecret = "AUTH_ADMIN_SECRET"
 	EnvAuthUrl         = "AUTH_URL"
 	EnvAuthRedirectUrl = "AUTH_REDIRECT_URL"
+	EnvNotifySender    = "NOTIFY_SENDER"
+	EnvSmtpHost        = "SMTP_HOST"
+	EnvSmtpPort        = "SMTP_PORT"
+	EnvSmtpUsername    = "SMTP_USERNAME"
+	EnvSmtpPassword    = "SMTP_PASSWORD"
+	EnvSmtpFrom        = "SMTP_FROM"
 )
@@ -40,7 +40,63 @@ var ErrorUserNotFound = errors.New("USER_NOT_FOUND")
 var ErrorSourceNotFound = errors.New("SOURCE_NOT_FOUND")
 var ErrorAuthorNotFound = errors.New("AUTHOR_NOT_FOUND")
 var ErrorDefinitionNotFound = errors.New("DEFINITION_NOT_FOUND")
+var ErrorDefinitionNotApproved = errors.New("DEFINITION_NOT_APPROVED")
+var ErrorDefinitionDeletionBecauseTranslated = errors.New("DEFINITION_DELETION_BECAUSE_TRANSLATED")
+var ErrorTranslationSameLanguage = errors.New("TRANSLATION_SAME_LANGUAGE")
+var ErrorTranslationAlreadyExists = errors.New("TRANSLATION_ALREADY_EXISTS")
+var ErrorTranslationFieldOfOriginal = errors.New("TRANSLATION_FIELD_OF_ORIGINAL")
+var ErrorDuplicateSourceReference = errors.New("DUPLICATE_SOURCE_REFERENCE")
 
 var ErrorAuthorDeletionBecauseInUse = errors.New("AUTHOR_COULD_NOT_BE_DELETED_BECAUSE_IN_USE")
 var ErrorAuthorChangeBecauseInUse = errors.New("AUTHOR_COULD_NOT_BE_CHANGED_BECAUSE_IN_USE")
 var ErrorSourceDeletionBecauseInUse = errors.New("SOURCE_COULD_NOT_BE_DELETED_BECAUSE_IN_USE")
+
+var ErrorDumpManifestInvalid = errors.New("DUMP_MANIFEST_INVALID")
+var ErrorDumpChecksumMismatch = errors.New("DUMP_CHECKSUM_MISMATCH")
+var ErrorDumpCountMismatch = errors.New("DUMP_COUNT_MISMATCH")
+var ErrorDumpUnsupportedVersion = errors.New("DUMP_UNSUPPORTED_VERSION")
+var ErrorRestoreValidation = errors.New("RESTORE_VALIDATION_FAILED")
+
+var ErrorMigrationUnknownVersion = errors.New("MIGRATION_UNKNOWN_VERSION")
+var ErrorInvalidPublishingYearRange = errors.New("INVALID_PUBLISHING_YEAR_RANGE")
+var ErrorInvalidSort = errors.New("INVALID_SORT")
+var ErrorInvalidCursor = errors.New("INVALID_CURSOR")
+var ErrorInvalidResumeToken = errors.New("INVALID_RESUME_TOKEN")
+var ErrorSlugNotFound = errors.New("SLUG_NOT_FOUND")
+var ErrorSlugUnavailable = errors.New("SLUG_UNAVAILABLE")
+var ErrorQuerySyntax = errors.New("QUERY_SYNTAX_ERROR")
+var ErrorSavedSearchNotFound = errors.New("SAVED_SEARCH_NOT_FOUND")
+var ErrorInvalidTag = errors.New("INVALID_TAG")
+var ErrorTagNotFound = errors.New("TAG_NOT_FOUND")
+var ErrorTagAlreadyApproved = errors.New("TAG_ALREADY_APPROVED")
+var ErrorTagMerged = errors.New("TAG_MERGED")
+var ErrorTagMergeIntoItself = errors.New("TAG_MERGE_INTO_ITSELF")
+var ErrorCategoryNotFound = errors.New("CATEGORY_NOT_FOUND")
+var ErrorCategoryAlreadyExists = errors.New("CATEGORY_ALREADY_EXISTS")
+var ErrorCategoryInUse = errors.New("CATEGORY_IN_USE")
+var ErrorCategoryCycle = errors.New("CATEGORY_CYCLE")
+var ErrorInvalidCategoryKey = errors.New("INVALID_CATEGORY_KEY")
+var ErrorCommentNotFound = errors.New("COMMENT_NOT_FOUND")
+var ErrorCommentBelongsToAnotherUser = errors.New("COMMENT_BELONGS_TO_ANOTHER_USER")
+var ErrorCommentDeleted = errors.New("COMMENT_DELETED")
+var ErrorCommentHidden = errors.New("COMMENT_HIDDEN")
+var ErrorModeratorNoteNotFound = errors.New("MODERATOR_NOTE_NOT_FOUND")
+var ErrorModeratorNoteBelongsToAnotherUser = errors.New("MODERATOR_NOTE_BELONGS_TO_ANOTHER_USER")
+var ErrorRatingNotFound = errors.New("RATING_NOT_FOUND")
+var ErrorRatingOfOwnDefinition = errors.New("RATING_OF_OWN_DEFINITION")
+var ErrorCollectionNotFound = errors.New("COLLECTION_NOT_FOUND")
+var ErrorCollectionItemNotFound = errors.New("COLLECTION_ITEM_NOT_FOUND")
+var ErrorCollectionFull = errors.New("COLLECTION_FULL")
+var ErrorDefinitionAlreadyInCollection = errors.New("DEFINITION_ALREADY_IN_COLLECTION")
+var ErrorInvalidCollectionOrder = errors.New("INVALID_COLLECTION_ORDER")
+var ErrorCollectionChanged = errors.New("COLLECTION_CHANGED")
+var ErrorNotificationNotFound = errors.New("NOTIFICATION_NOT_FOUND")
+var ErrorInvalidNotifySender = errors.New("INVALID_NOTIFY_SENDER")
+var ErrorSmtpNotConfigured = errors.New("SMTP_NOT_CONFIGURED")
+var ErrorUserHasNoEmail = errors.New("USER_HAS_NO_EMAIL")
+var ErrorWebhookNotFound = errors.New("WEBHOOK_NOT_FOUND")
+var ErrorWebhookInactive = errors.New("WEBHOOK_INACTIVE")
+var ErrorWebhookDeliveryNotFound = errors.New("WEBHOOK_DELIVERY_NOT_FOUND")
+var ErrorInvalidWebhookUrl = errors.New("INVALID_WEBHOOK_URL")
+var ErrorReplicaSetRequired = errors.New("REPLICA_SET_REQUIRED")
+var ErrorEventDispatcherRunning = errors.New("EVENT_DISPATCHER_RUNNING")
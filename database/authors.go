@@ -2,7 +2,6 @@ package database
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 	"yacoid_server/auth"
 	"yacoid_server/common"
@@ -10,7 +9,6 @@ import (
 	"yacoid_server/types"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gosimple/slug"
 	"github.com/jinzhu/copier"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,43 +16,53 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+/* The nickname of the user, which is requested only once for all responses sharing the map */
+func getNickname(nicknames map[string]string, userId string) string {
+
+	if nickname, ok := nicknames[userId]; ok {
+		return nickname
+	}
+
+	nickname, err := auth.GetNicknameOfUser(userId)
+
+	if err != nil {
+		nickname = "<deleted>"
+	}
+
+	nicknames[userId] = nickname
+	return nickname
+
+}
+
 func AuthorToResponse(author *types.Author) (*types.AuthorResponse, error) {
+	return authorToResponse(author, map[string]string{}), nil
+}
+
+func authorToResponse(author *types.Author, nicknames map[string]string) *types.AuthorResponse {
 
 	response := types.AuthorResponse{}
 	response.ID = author.ID
 	response.SlugId = author.SlugId
 
 	response.SubmittedBy = author.SubmittedBy
-	nickname, err := auth.GetNicknameOfUser(author.SubmittedBy)
-
-	if err == nil {
-		response.SubmittedByName = nickname
-	} else {
-		response.SubmittedByName = "<deleted>"
-	}
+	response.SubmittedByName = getNickname(nicknames, author.SubmittedBy)
 
 	response.SubmittedDate = author.SubmittedDate
 	response.Type = author.Type
 	response.PersonProperties = author.PersonProperties
 	response.OrganizationProperties = author.OrganizationProperties
 
-	return &response, nil
+	return &response
 
 }
 
 func AuthorsToResponses(authors *[]*types.Author) (*[]types.AuthorResponse, error) {
 
 	responses := []types.AuthorResponse{}
+	nicknames := map[string]string{}
 
 	for _, author := range *authors {
-
-		response, err := AuthorToResponse(author)
-
-		if err != nil {
-			return nil, err
-		}
-
-		responses = append(responses, *response)
+		responses = append(responses, *authorToResponse(author, nicknames))
 	}
 
 	return &responses, nil
@@ -68,12 +76,8 @@ func CreateAuthor(request *types.CreateAuthorRequest, userId string) (*primitive
 	author.ID = primitive.NewObjectID()
 
 	if request.Type == types.EnumAuthorType.Person && request.PersonProperties != nil {
-		text := fmt.Sprintf("%s-%s-%08d", request.PersonProperties.LastName, request.PersonProperties.FirstName, rand.Intn(10000000))
-		author.SlugId = slug.Make(text)
 		author.PersonProperties = request.PersonProperties
 	} else if request.Type == types.EnumAuthorType.Organization && request.OrganizationProperties != nil {
-		text := fmt.Sprintf("%s-%08d", request.OrganizationProperties.OrganizationName, rand.Intn(10000000))
-		author.SlugId = slug.Make(text)
 		author.OrganizationProperties = request.OrganizationProperties
 	} else {
 		return nil, constants.ErrorAuthorCreation
@@ -89,8 +93,28 @@ func CreateAuthor(request *types.CreateAuthorRequest, userId string) (*primitive
 	author.Approved = false
 
 	author.Type = request.Type
+	author.UpdateSuggestKeys()
 
-	_, err := authorsCollection.InsertOne(dbContext, author)
+	// the slug is registered together with the author
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+
+		slugId, err := assignSlug(ctx, types.EnumSlugEntity.Author, author.ID, getAuthorSlugText(&author))
+
+		if err != nil {
+			return err
+		}
+
+		author.SlugId = slugId
+
+		_, err = authorsCollection.InsertOne(ctx, author)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.AuthorSubmitted, author.ID, userId, nil)
+
+	})
 
 	if err != nil {
 		return nil, err
@@ -101,7 +125,7 @@ func CreateAuthor(request *types.CreateAuthorRequest, userId string) (*primitive
 }
 
 /* If the author is used in sources, then an array of the sources and an error will be returned. */
-func DeleteAuthor(authorId string) (*[]string, error) {
+func DeleteAuthor(authorId string, userId string) (*[]string, error) {
 
 	id, err := primitive.ObjectIDFromHex(authorId)
 
@@ -109,38 +133,88 @@ func DeleteAuthor(authorId string) (*[]string, error) {
 		return nil, constants.ErrorInvalidID
 	}
 
-	sources, err := GetSourcesWithAuthor(id)
-
-	if err != nil {
-		return nil, err
+	filter := bson.M{
+		"_id": id,
 	}
-	if len(*sources) > 0 {
 
-		sourceIds := []string{}
+	var sourceIds []string
+
+	/*
+		The references are checked in the transaction, new references write the author (see touchReferencedAuthors),
+		so they conflict with the deletion and either the reference or the deletion is repeated.
+	*/
+	err = withTransaction(func(ctx mongo.SessionContext) error {
 
-		for _, source := range *sources {
-			sourceIds = append(sourceIds, source.ID.Hex())
+		sourceIds = nil
+		referencingIds, err := sourcesCollection.Distinct(ctx, "_id", bson.M{"authors": id})
+
+		if err != nil {
+			return err
 		}
 
-		return &sourceIds, constants.ErrorAuthorDeletionBecauseInUse
+		if len(referencingIds) > 0 {
 
-	}
+			for _, sourceId := range referencingIds {
+				sourceIds = append(sourceIds, sourceId.(primitive.ObjectID).Hex())
+			}
 
-	filter := bson.M{
-		"_id": id,
+			return constants.ErrorAuthorDeletionBecauseInUse
+
+		}
+
+		result, err := authorsCollection.DeleteOne(ctx, filter)
+
+		if err != nil {
+			return err
+		}
+
+		if result.DeletedCount == 0 {
+			return constants.ErrorAuthorNotFound
+		}
+
+		// the notes and slugs are deleted together with the author
+		err = deleteModeratorNotes(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = deleteSlugs(ctx, types.EnumSlugEntity.Author, id)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.AuthorDeleted, id, userId, nil)
+
+	})
+
+	if err == constants.ErrorAuthorDeletionBecauseInUse {
+		return &sourceIds, err
 	}
 
-	result, err := authorsCollection.DeleteOne(dbContext, filter)
+	return nil, err
+
+}
+
+/*
+Has to be called inside of withTransaction. Writes the referenced authors, so a concurrent deletion of one of them
+conflicts with the new reference. Authors, which were deleted in the meantime, fail the transaction.
+*/
+func touchReferencedAuthors(ctx mongo.SessionContext, ids []primitive.ObjectID, now time.Time) error {
+
+	uniqueIds := uniqueObjectIDs(ids)
+	result, err := authorsCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": uniqueIds}}, bson.M{"$set": bson.M{"last_reference_date": now}})
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return nil, constants.ErrorAuthorNotFound
+	if result.MatchedCount < int64(len(uniqueIds)) {
+		return constants.ErrorAuthorNotFound
 	}
 
-	return nil, nil
+	return nil
 
 }
 
@@ -222,26 +296,40 @@ func ChangeAuthor(request *types.ChangeAuthorRequest, userId string, validate *v
 	/* Update existing author */
 
 	author.LastChangeDate = time.Now()
+	author.UpdateSuggestKeys()
 
 	filter := bson.M{
 		"_id": id,
 	}
 
-	result, err := authorsCollection.ReplaceOne(dbContext, filter, author, nil)
+	return withTransaction(func(ctx mongo.SessionContext) error {
 
-	if err != nil {
-		return err
-	}
+		slugId, err := updateSlug(ctx, types.EnumSlugEntity.Author, author.ID, author.SlugId, getAuthorSlugText(author))
 
-	if result.MatchedCount == 0 {
-		return constants.ErrorAuthorNotFound
-	}
+		if err != nil {
+			return err
+		}
 
-	return nil
+		author.SlugId = slugId
+
+		result, err := authorsCollection.ReplaceOne(ctx, filter, author, nil)
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return constants.ErrorAuthorNotFound
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.AuthorChanged, id, userId, nil)
+
+	})
 
 }
 
-func ApproveAuthors(authorIds []primitive.ObjectID, userId string) error {
+/* Has to be called inside of withTransaction, authors are only approved together with a source */
+func ApproveAuthors(ctx mongo.SessionContext, authorIds []primitive.ObjectID, userId string) error {
 
 	filter := bson.M{
 		"_id": bson.M{
@@ -258,7 +346,14 @@ func ApproveAuthors(authorIds []primitive.ObjectID, userId string) error {
 		},
 	}
 
-	_, err := authorsCollection.UpdateMany(dbContext, filter, update, nil)
+	// only the authors, which are approved now, cause events
+	approvedIds, err := authorsCollection.Distinct(ctx, "_id", filter)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = authorsCollection.UpdateMany(ctx, filter, update, nil)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -267,6 +362,22 @@ func ApproveAuthors(authorIds []primitive.ObjectID, userId string) error {
 		return err
 	}
 
+	for _, approvedId := range approvedIds {
+
+		id, ok := approvedId.(primitive.ObjectID)
+
+		if !ok {
+			continue
+		}
+
+		err = recordDomainEvent(ctx, types.EnumDomainEventType.AuthorApproved, id, userId, nil)
+
+		if err != nil {
+			return err
+		}
+
+	}
+
 	return nil
 
 }
@@ -277,13 +388,35 @@ func GetAuthors(request *types.AuthorPageRequest) ([]*types.Author, error) {
 		return nil, constants.ErrorInvalidType
 	}
 
-	options := options.FindOptions{}
+	filter := CreateAuthorFilterQuery(request.Filter)
+	textSearch := request.Filter != nil && request.Filter.Name != nil && len(*request.Filter.Name) > 0
 
-	options.SetLimit(int64(request.PageSize))
-	options.SetSkip(int64((request.Page - 1) * request.PageSize))
+	sortKeyStages, sortStage, err := createSortStages(sortAuthors, request.Sort, textSearch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$match", Value: filter}}}
+	pipeline = append(pipeline, sortKeyStages...)
+	pipeline = append(pipeline,
+		sortStage,
+		bson.D{{Key: "$skip", Value: int64((request.Page - 1) * request.PageSize)}},
+		bson.D{{Key: "$limit", Value: int64(request.PageSize)}},
+	)
+
+	return aggregateDocuments[types.Author](authorsCollection, pipeline, options.Aggregate())
+
+}
+
+func GetAuthorCursorPage(request *types.AuthorCursorRequest) (*types.CursorPage[types.Author], error) {
 
 	filter := CreateAuthorFilterQuery(request.Filter)
-	return getDocuments[types.Author](authorsCollection, filter, &options)
+	textSearch := request.Filter != nil && request.Filter.Name != nil && len(*request.Filter.Name) > 0
+
+	filterStages := bson.A{bson.D{{Key: "$match", Value: filter}}}
+
+	return getCursorPage[types.Author](authorsCollection, sortAuthors, filterStages, request.Filter, textSearch, &request.CursorRequest)
 
 }
 
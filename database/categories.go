@@ -0,0 +1,451 @@
+package database
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Other instances of the server change categories too, so the cache is reloaded after this duration */
+const categoryCacheDuration = time.Minute
+
+var categoriesCollection *mongo.Collection
+
+var categoryCache struct {
+	sync.RWMutex
+	categories []*types.Category
+	loadedDate time.Time
+}
+
+/* The categories, which existed as fixed enum before the taxonomy was stored in the database */
+var defaultCategories = []types.Category{
+	{Key: "human_intelligence", Order: 1, Names: map[types.Language]string{"en": "Human intelligence", "de": "Menschliche Intelligenz"}},
+	{Key: "artificial_intelligence", Order: 2, Names: map[types.Language]string{"en": "Artificial intelligence", "de": "Künstliche Intelligenz"}},
+	{Key: "machine_intelligence", Order: 3, Names: map[types.Language]string{"en": "Machine intelligence", "de": "Maschinelle Intelligenz"}},
+	{Key: "plant_intelligence", Order: 4, Names: map[types.Language]string{"en": "Plant intelligence", "de": "Pflanzliche Intelligenz"}},
+	{Key: "alien_intelligence", Order: 5, Names: map[types.Language]string{"en": "Alien intelligence", "de": "Außerirdische Intelligenz"}},
+}
+
+func createCategoryIndexes() error {
+
+	_, err := categoriesCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetName("key").SetUnique(true),
+	})
+
+	return err
+
+}
+
+func dropCategoryIndexes() error {
+
+	_, err := categoriesCollection.Indexes().DropOne(dbContext, "key")
+	return err
+
+}
+
+func getCachedCategories() ([]*types.Category, error) {
+
+	categoryCache.RLock()
+	categories, loadedDate := categoryCache.categories, categoryCache.loadedDate
+	categoryCache.RUnlock()
+
+	if categories != nil && time.Since(loadedDate) < categoryCacheDuration {
+		return categories, nil
+	}
+
+	categories, err := getDocuments[types.Category](categoriesCollection, bson.M{}, options.Find().SetSort(bson.D{{Key: "order", Value: 1}, {Key: "key", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	categoryCache.Lock()
+	categoryCache.categories = categories
+	categoryCache.loadedDate = time.Now()
+	categoryCache.Unlock()
+
+	return categories, nil
+
+}
+
+func invalidateCategoryCache() {
+
+	categoryCache.Lock()
+	categoryCache.categories = nil
+	categoryCache.Unlock()
+
+}
+
+func getCachedCategory(key types.DefinitionCategory) (*types.Category, error) {
+
+	categories, err := getCachedCategories()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		if category.Key == key {
+			return category, nil
+		}
+	}
+
+	return nil, nil
+
+}
+
+/* Used by the is-definition-category validator */
+func CategoryExists(key types.DefinitionCategory) (bool, error) {
+
+	category, err := getCachedCategory(key)
+	return category != nil, err
+
+}
+
+/* The categories with all their descendants, so filtering by a category also finds definitions of its child categories */
+func expandCategories(keys []types.DefinitionCategory) ([]types.DefinitionCategory, error) {
+
+	categories, err := getCachedCategories()
+
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[types.DefinitionCategory][]types.DefinitionCategory{}
+	for _, category := range categories {
+		if category.Parent != nil {
+			children[*category.Parent] = append(children[*category.Parent], category.Key)
+		}
+	}
+
+	expanded := []types.DefinitionCategory{}
+	seen := map[types.DefinitionCategory]bool{}
+	queue := append([]types.DefinitionCategory{}, keys...)
+
+	for len(queue) > 0 {
+
+		key := queue[0]
+		queue = queue[1:]
+
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		expanded = append(expanded, key)
+		queue = append(queue, children[key]...)
+
+	}
+
+	return expanded, nil
+
+}
+
+/* All categories as trees, sorted by order and key */
+func GetCategoryTree() ([]*types.CategoryNode, error) {
+
+	categories, err := getCachedCategories()
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[types.DefinitionCategory]*types.CategoryNode{}
+	for _, category := range categories {
+		nodes[category.Key] = &types.CategoryNode{Category: *category, Children: []*types.CategoryNode{}}
+	}
+
+	roots := []*types.CategoryNode{}
+
+	// the categories are sorted, so the children are appended in order
+	for _, category := range categories {
+
+		node := nodes[category.Key]
+
+		if category.Parent != nil {
+			if parent, exists := nodes[*category.Parent]; exists {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+
+		roots = append(roots, node)
+
+	}
+
+	return roots, nil
+
+}
+
+func isValidCategoryKey(key string) bool {
+	return key == strings.Join(common.NormalizeSearchText(key), "_")
+}
+
+/* Returns an error, if the parent does not exist or is the category itself or one of its descendants */
+func validateCategoryParent(key types.DefinitionCategory, parent types.DefinitionCategory) error {
+
+	descendants, err := expandCategories([]types.DefinitionCategory{key})
+
+	if err != nil {
+		return err
+	}
+
+	for _, descendant := range descendants {
+		if descendant == parent {
+			return constants.ErrorCategoryCycle
+		}
+	}
+
+	exists, err := CategoryExists(parent)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return constants.ErrorCategoryNotFound
+	}
+
+	return nil
+
+}
+
+func CreateCategory(request *types.CreateCategoryRequest) (*primitive.ObjectID, error) {
+
+	if !isValidCategoryKey(request.Key) {
+		return nil, constants.ErrorInvalidCategoryKey
+	}
+
+	now := time.Now()
+	category := types.Category{
+		ID:             primitive.NewObjectID(),
+		Key:            types.DefinitionCategory(request.Key),
+		Names:          request.Names,
+		Descriptions:   request.Descriptions,
+		CreatedDate:    now,
+		LastChangeDate: now,
+	}
+
+	if category.Descriptions == nil {
+		category.Descriptions = map[types.Language]string{}
+	}
+
+	if request.Order != nil {
+		category.Order = *request.Order
+	}
+
+	if request.Parent != nil {
+
+		parent := types.DefinitionCategory(*request.Parent)
+		err := validateCategoryParent(category.Key, parent)
+
+		if err != nil {
+			return nil, err
+		}
+
+		category.Parent = &parent
+
+	}
+
+	_, err := categoriesCollection.InsertOne(dbContext, category)
+
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, constants.ErrorCategoryAlreadyExists
+		}
+		return nil, err
+	}
+
+	invalidateCategoryCache()
+
+	return &category.ID, nil
+
+}
+
+func ChangeCategory(request *types.ChangeCategoryRequest) error {
+
+	key := types.DefinitionCategory(request.Key)
+	update := bson.D{}
+
+	if request.Parent != nil {
+
+		if len(*request.Parent) == 0 {
+			update = append(update, bson.E{Key: "parent", Value: nil})
+		} else {
+
+			parent := types.DefinitionCategory(*request.Parent)
+			err := validateCategoryParent(key, parent)
+
+			if err != nil {
+				return err
+			}
+
+			update = append(update, bson.E{Key: "parent", Value: parent})
+
+		}
+
+	}
+
+	if request.Names != nil {
+		update = append(update, bson.E{Key: "names", Value: request.Names})
+	}
+
+	if request.Descriptions != nil {
+		update = append(update, bson.E{Key: "descriptions", Value: request.Descriptions})
+	}
+
+	if request.Order != nil {
+		update = append(update, bson.E{Key: "order", Value: *request.Order})
+	}
+
+	if len(update) == 0 {
+		return nil
+	}
+
+	update = append(update, bson.E{Key: "last_change_date", Value: time.Now()})
+
+	result, err := categoriesCollection.UpdateOne(dbContext, bson.M{"key": key}, bson.M{"$set": update})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorCategoryNotFound
+	}
+
+	invalidateCategoryCache()
+
+	return nil
+
+}
+
+/* Categories with child categories or definitions can not be deleted */
+func DeleteCategory(categoryKey string) error {
+
+	key := types.DefinitionCategory(categoryKey)
+
+	childCount, err := countDocuments(categoriesCollection, bson.M{"parent": key}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	definitionCount, err := countDocuments(definitionsCollection, bson.M{"categories": key}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if childCount > 0 || definitionCount > 0 {
+		return constants.ErrorCategoryInUse
+	}
+
+	result, err := categoriesCollection.DeleteOne(dbContext, bson.M{"key": key})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return constants.ErrorCategoryNotFound
+	}
+
+	invalidateCategoryCache()
+
+	return nil
+
+}
+
+/* Inserts the default categories, which do not exist yet. Existing categories are not changed. */
+func ensureDefaultCategories() error {
+
+	now := time.Now()
+
+	for _, category := range defaultCategories {
+
+		category.ID = primitive.NewObjectID()
+		category.Descriptions = map[types.Language]string{}
+		category.CreatedDate = now
+		category.LastChangeDate = now
+
+		_, err := categoriesCollection.UpdateOne(dbContext, bson.M{"key": category.Key}, bson.M{"$setOnInsert": category}, options.Update().SetUpsert(true))
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	invalidateCategoryCache()
+
+	return nil
+
+}
+
+/* Converts the former single "category" of definitions to "categories". Also used for imported dumps of older versions. */
+func convertDefinitionCategories() error {
+
+	_, err := definitionsCollection.UpdateMany(dbContext, bson.M{"category": bson.M{"$exists": true}}, bson.A{
+		bson.D{{Key: "$set", Value: bson.D{{Key: "categories", Value: bson.A{"$category"}}}}},
+		bson.D{{Key: "$unset", Value: "category"}},
+	})
+
+	return err
+
+}
+
+func createCategories() error {
+
+	err := ensureDefaultCategories()
+
+	if err != nil {
+		return err
+	}
+
+	err = convertDefinitionCategories()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "categories", Value: 1}}, Options: options.Index().SetName("categories"),
+	})
+
+	return err
+
+}
+
+/* Keeps the first category of every definition, the managed categories are dropped */
+func dropCategories() error {
+
+	_, err := definitionsCollection.Indexes().DropOne(dbContext, "categories")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = definitionsCollection.UpdateMany(dbContext, bson.M{"categories": bson.M{"$exists": true}}, bson.A{
+		bson.D{{Key: "$set", Value: bson.D{{Key: "category", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{"$categories", 0}}}}}}},
+		bson.D{{Key: "$unset", Value: "categories"}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = categoriesCollection.DeleteMany(dbContext, bson.M{})
+	invalidateCategoryCache()
+
+	return err
+
+}
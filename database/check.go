@@ -0,0 +1,243 @@
+package database
+
+import (
+	"fmt"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type integrityRow struct {
+	ID      primitive.ObjectID   `bson:"_id"`
+	Missing []primitive.ObjectID `bson:"missing"`
+}
+
+type integrityCheck struct {
+	collection *mongo.Collection
+	name       string
+	problem    string
+	pipeline   bson.A
+}
+
+func lookupStage(from string, localField string, as string) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: "_id"},
+		{Key: "as", Value: as},
+	}}}
+}
+
+func getIntegrityChecks() []integrityCheck {
+
+	missingApprovalInformation := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "approved", Value: true},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "approved_by", Value: nil}},
+			bson.D{{Key: "approved_date", Value: nil}},
+		}},
+	}}}
+
+	return []integrityCheck{
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "references missing source",
+			pipeline: bson.A{
+				lookupStage("sources", "source", "referenced_source"),
+				bson.D{{Key: "$match", Value: bson.D{{Key: "referenced_source", Value: bson.D{{Key: "$size", Value: 0}}}}}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "missing", Value: bson.A{"$source"}}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "references missing additional sources",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "additional_sources.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+				lookupStage("sources", "additional_sources.source", "referenced_sources"),
+				bson.D{{Key: "$project", Value: bson.D{{Key: "missing", Value: bson.D{{Key: "$setDifference", Value: bson.A{"$additional_sources.source", "$referenced_sources._id"}}}}}}},
+				bson.D{{Key: "$match", Value: bson.D{{Key: "missing.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "is approved, but one of its additional sources is not",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "approved", Value: true}, {Key: "additional_sources.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+				lookupStage("sources", "additional_sources.source", "referenced_sources"),
+				bson.D{{Key: "$match", Value: bson.D{{Key: "referenced_sources.approved", Value: false}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "is a translation of a missing definition or of another translation",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "translation_of", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+				lookupStage("definitions", "translation_of", "original"),
+				bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "original", Value: bson.D{{Key: "$size", Value: 0}}}},
+					bson.D{{Key: "original.translation_of", Value: bson.D{{Key: "$exists", Value: true}}}},
+				}}}}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "missing", Value: bson.A{"$translation_of"}}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "is approved, but its source is not",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "approved", Value: true}}}},
+				lookupStage("sources", "source", "referenced_source"),
+				bson.D{{Key: "$match", Value: bson.D{{Key: "referenced_source.approved", Value: false}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "has no rejection log",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "rejection_log", Value: nil}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "is approved, but misses the approval information",
+			pipeline:   bson.A{missingApprovalInformation},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "has no categories or unknown categories",
+			pipeline: bson.A{
+				bson.D{{Key: "$lookup", Value: bson.D{
+					{Key: "from", Value: "categories"},
+					{Key: "localField", Value: "categories"},
+					{Key: "foreignField", Value: "key"},
+					{Key: "as", Value: "referenced_categories"},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "categories", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$categories", bson.A{}}}}},
+					// the categories are keys, so they are not reported as missing IDs
+					{Key: "unknown_categories", Value: bson.D{{Key: "$setDifference", Value: bson.A{
+						bson.D{{Key: "$ifNull", Value: bson.A{"$categories", bson.A{}}}},
+						"$referenced_categories.key",
+					}}}},
+				}}},
+				bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "categories.0", Value: bson.D{{Key: "$exists", Value: false}}}},
+					bson.D{{Key: "unknown_categories.0", Value: bson.D{{Key: "$exists", Value: true}}}},
+				}}}}},
+			},
+		},
+		{
+			collection: definitionsCollection,
+			name:       "definitions",
+			problem:    "references missing or merged tags",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "tags.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+				lookupStage("tags", "tags", "referenced_tags"),
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "missing", Value: bson.D{{Key: "$setDifference", Value: bson.A{
+						"$tags",
+						bson.D{{Key: "$map", Value: bson.D{
+							{Key: "input", Value: bson.D{{Key: "$filter", Value: bson.D{
+								{Key: "input", Value: "$referenced_tags"},
+								{Key: "cond", Value: bson.D{{Key: "$not", Value: bson.A{"$$this.merged_into"}}}},
+							}}}},
+							{Key: "in", Value: "$$this._id"},
+						}}},
+					}}}},
+				}}},
+				bson.D{{Key: "$match", Value: bson.D{{Key: "missing.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+			},
+		},
+		{
+			collection: sourcesCollection,
+			name:       "sources",
+			problem:    "references missing authors",
+			pipeline: bson.A{
+				lookupStage("authors", "authors", "referenced_authors"),
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "missing", Value: bson.D{{Key: "$setDifference", Value: bson.A{
+						bson.D{{Key: "$ifNull", Value: bson.A{"$authors", bson.A{}}}},
+						"$referenced_authors._id",
+					}}}},
+				}}},
+				bson.D{{Key: "$match", Value: bson.D{{Key: "missing.0", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+			},
+		},
+		{
+			collection: sourcesCollection,
+			name:       "sources",
+			problem:    "has no authors",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "authors.0", Value: bson.D{{Key: "$exists", Value: false}}}}}},
+			},
+		},
+		{
+			collection: sourcesCollection,
+			name:       "sources",
+			problem:    "is approved, but some of its authors are not",
+			pipeline: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "approved", Value: true}}}},
+				lookupStage("authors", "authors", "referenced_authors"),
+				bson.D{{Key: "$match", Value: bson.D{{Key: "referenced_authors.approved", Value: false}}}},
+			},
+		},
+		{
+			collection: sourcesCollection,
+			name:       "sources",
+			problem:    "is approved, but misses the approval information",
+			pipeline:   bson.A{missingApprovalInformation},
+		},
+		{
+			collection: authorsCollection,
+			name:       "authors",
+			problem:    "is approved, but misses the approval information",
+			pipeline:   bson.A{missingApprovalInformation},
+		},
+	}
+
+}
+
+/* Checks the references and approval states of all definitions, sources and authors. */
+func CheckIntegrity() (*types.IntegrityReport, error) {
+
+	report := types.IntegrityReport{Issues: []types.IntegrityIssue{}}
+
+	for _, check := range getIntegrityChecks() {
+
+		rows, err := aggregateDocuments[integrityRow](check.collection, check.pipeline, options.Aggregate())
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+
+			problem := check.problem
+
+			if len(row.Missing) > 0 {
+				missing := []string{}
+				for _, id := range row.Missing {
+					missing = append(missing, id.Hex())
+				}
+				problem = fmt.Sprintf("%s %v", problem, missing)
+			}
+
+			report.Add(check.name, row.ID, problem)
+
+		}
+
+	}
+
+	return &report, nil
+
+}
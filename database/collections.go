@@ -0,0 +1,604 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"yacoid_server/auth"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var collectionsCollection *mongo.Collection
+
+const maxCollectionItems = 500
+
+func createCollectionIndexes() error {
+
+	_, err := collectionsCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_date", Value: -1}}, Options: options.Index().SetName("user_id_created_date")},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "last_change_date", Value: -1}}, Options: options.Index().SetName("visibility_last_change_date")},
+		{Keys: bson.D{{Key: "share_token", Value: 1}}, Options: options.Index().SetName("share_token").SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "items.definition_id", Value: 1}}, Options: options.Index().SetName("items_definition_id")},
+	})
+
+	return err
+
+}
+
+func dropCollectionIndexes() error {
+
+	for _, name := range []string{"user_id_created_date", "visibility_last_change_date", "share_token", "items_definition_id"} {
+		_, err := collectionsCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+/* With items the definitions are hydrated like in DefinitionsToResponses. The share token is only added for the owner. */
+func CollectionToResponse(collection *types.Collection, userId string, withItems bool) (*types.CollectionResponse, error) {
+
+	response := types.CollectionResponse{
+		ID:             collection.ID,
+		UserId:         collection.UserId,
+		Name:           collection.Name,
+		Description:    collection.Description,
+		Visibility:     collection.Visibility,
+		ItemCount:      len(collection.Items),
+		CreatedDate:    collection.CreatedDate,
+		LastChangeDate: collection.LastChangeDate,
+	}
+
+	nickname, err := auth.GetNicknameOfUser(collection.UserId)
+
+	if err == nil {
+		response.UserName = nickname
+	} else {
+		response.UserName = "<deleted>"
+	}
+
+	if collection.UserId == userId {
+		response.ShareToken = collection.ShareToken
+	}
+
+	if withItems {
+
+		items, err := getCollectionItemResponses(collection.Items)
+
+		if err != nil {
+			return nil, err
+		}
+
+		response.Items = &items
+
+	}
+
+	return &response, nil
+
+}
+
+func CollectionsToResponses(collections []*types.Collection, userId string) ([]types.CollectionResponse, error) {
+
+	responses := []types.CollectionResponse{}
+
+	for _, collection := range collections {
+
+		response, err := CollectionToResponse(collection, userId, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, *response)
+	}
+
+	return responses, nil
+
+}
+
+/* The definitions of the items in the order of the collection. Items of deleted definitions are skipped. */
+func getCollectionItemResponses(items []types.CollectionItem) ([]types.CollectionItemResponse, error) {
+
+	responses := []types.CollectionItemResponse{}
+
+	if len(items) == 0 {
+		return responses, nil
+	}
+
+	definitionIds := []primitive.ObjectID{}
+	for _, item := range items {
+		definitionIds = append(definitionIds, item.DefinitionId)
+	}
+
+	definitions, err := getDocuments[types.Definition](definitionsCollection, bson.M{"_id": bson.M{"$in": definitionIds}}, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	definitionMap := map[primitive.ObjectID]*types.Definition{}
+	for _, definition := range definitions {
+		definitionMap[definition.ID] = definition
+	}
+
+	orderedDefinitions := []*types.Definition{}
+	orderedItems := []types.CollectionItem{}
+
+	for _, item := range items {
+		if definition, ok := definitionMap[item.DefinitionId]; ok {
+			orderedDefinitions = append(orderedDefinitions, definition)
+			orderedItems = append(orderedItems, item)
+		}
+	}
+
+	definitionResponses, err := DefinitionsToResponses(&orderedDefinitions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for index, definitionResponse := range *definitionResponses {
+		responses = append(responses, types.CollectionItemResponse{
+			Definition: definitionResponse,
+			Note:       orderedItems[index].Note,
+			AddedDate:  orderedItems[index].AddedDate,
+		})
+	}
+
+	return responses, nil
+
+}
+
+func getCollectionById(collectionId string) (*types.Collection, error) {
+
+	id, err := primitive.ObjectIDFromHex(collectionId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	var collection types.Collection
+	err = collectionsCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&collection)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorCollectionNotFound
+		}
+		return nil, err
+	}
+
+	return &collection, nil
+
+}
+
+/* Collections of other users are reported as not found, so private collections are not revealed */
+func getOwnCollection(collectionId string, userId string) (*types.Collection, error) {
+
+	collection, err := getCollectionById(collectionId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.UserId != userId {
+		return nil, constants.ErrorCollectionNotFound
+	}
+
+	return collection, nil
+
+}
+
+/* The collection for its owner or a public collection for everyone else */
+func GetCollection(collectionId string, userId string) (*types.Collection, error) {
+
+	collection, err := getCollectionById(collectionId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.UserId != userId && collection.Visibility != types.EnumCollectionVisibility.Public {
+		return nil, constants.ErrorCollectionNotFound
+	}
+
+	return collection, nil
+
+}
+
+/* Private collections can not be read with their share token */
+func GetCollectionByShareToken(token string) (*types.Collection, error) {
+
+	var collection types.Collection
+	err := collectionsCollection.FindOne(dbContext, bson.M{
+		"share_token": token,
+		"visibility":  bson.M{"$ne": types.EnumCollectionVisibility.Private},
+	}).Decode(&collection)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorCollectionNotFound
+		}
+		return nil, err
+	}
+
+	return &collection, nil
+
+}
+
+func GetCollectionsOfUser(userId string) ([]*types.Collection, error) {
+	return getDocuments[types.Collection](collectionsCollection, bson.M{"user_id": userId}, options.Find().SetSort(bson.D{{Key: "created_date", Value: -1}}))
+}
+
+/* Public collections, recently changed first, and the total count of public collections */
+func GetPublicCollections(page int, pageSize int) ([]*types.Collection, int, error) {
+
+	if page <= 0 || pageSize <= 0 {
+		return nil, 0, constants.ErrorInvalidType
+	}
+
+	filter := bson.M{"visibility": types.EnumCollectionVisibility.Public}
+
+	total, err := countDocuments(collectionsCollection, filter, nil)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "last_change_date", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	collections, err := getDocuments[types.Collection](collectionsCollection, filter, findOptions)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return collections, total, nil
+
+}
+
+func CreateCollection(request *types.CreateCollectionRequest, userId string) (*primitive.ObjectID, error) {
+
+	now := time.Now()
+	collection := types.Collection{
+		ID:             primitive.NewObjectID(),
+		UserId:         userId,
+		Name:           request.Name,
+		Visibility:     types.EnumCollectionVisibility.Private,
+		Items:          []types.CollectionItem{},
+		CreatedDate:    now,
+		LastChangeDate: now,
+	}
+
+	if request.Description != nil {
+		collection.Description = *request.Description
+	}
+
+	if request.Visibility != nil {
+		collection.Visibility = *request.Visibility
+	}
+
+	if collection.Visibility != types.EnumCollectionVisibility.Private {
+
+		token, err := common.CreateRandomToken(16)
+
+		if err != nil {
+			return nil, err
+		}
+
+		collection.ShareToken = &token
+
+	}
+
+	_, err := collectionsCollection.InsertOne(dbContext, collection)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &collection.ID, nil
+
+}
+
+/* A share token is created, when the collection is shared for the first time */
+func ChangeCollection(request *types.ChangeCollectionRequest, userId string) error {
+
+	collection, err := getOwnCollection(request.ID, userId)
+
+	if err != nil {
+		return err
+	}
+
+	update := bson.D{{Key: "last_change_date", Value: time.Now()}}
+
+	if request.Name != nil {
+		update = append(update, bson.E{Key: "name", Value: *request.Name})
+	}
+
+	if request.Description != nil {
+		update = append(update, bson.E{Key: "description", Value: *request.Description})
+	}
+
+	if request.Visibility != nil {
+
+		update = append(update, bson.E{Key: "visibility", Value: *request.Visibility})
+
+		if *request.Visibility != types.EnumCollectionVisibility.Private && collection.ShareToken == nil {
+
+			token, err := common.CreateRandomToken(16)
+
+			if err != nil {
+				return err
+			}
+
+			update = append(update, bson.E{Key: "share_token", Value: token})
+
+		}
+
+	}
+
+	_, err = collectionsCollection.UpdateOne(dbContext, bson.M{"_id": collection.ID}, bson.M{"$set": update})
+	return err
+
+}
+
+/* Replaces the share token, so links with the old token stop working */
+func ResetCollectionShareToken(collectionId string, userId string) (*string, error) {
+
+	collection, err := getOwnCollection(collectionId, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := common.CreateRandomToken(16)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = collectionsCollection.UpdateOne(dbContext, bson.M{"_id": collection.ID}, bson.M{"$set": bson.M{"share_token": token}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+
+}
+
+func DeleteCollection(collectionId string, userId string) error {
+
+	collection, err := getOwnCollection(collectionId, userId)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = collectionsCollection.DeleteOne(dbContext, bson.M{"_id": collection.ID})
+	return err
+
+}
+
+/* Only approved definitions can be added, every definition at most once */
+func AddCollectionItem(request *types.AddCollectionItemRequest, userId string) error {
+
+	collection, err := getOwnCollection(request.CollectionId, userId)
+
+	if err != nil {
+		return err
+	}
+
+	definition, err := GetDefinitionById(request.DefinitionId)
+
+	if err != nil {
+		return err
+	}
+
+	if !definition.Approved {
+		return constants.ErrorDefinitionNotApproved
+	}
+
+	if len(collection.Items) >= maxCollectionItems {
+		return constants.ErrorCollectionFull
+	}
+
+	item := types.CollectionItem{
+		DefinitionId: definition.ID,
+		AddedDate:    time.Now(),
+	}
+
+	if request.Note != nil {
+		item.Note = *request.Note
+	}
+
+	push := bson.M{"$each": bson.A{item}}
+
+	if request.Position != nil {
+		push["$position"] = *request.Position
+	}
+
+	// the filter prevents duplicates and too many items, if definitions are added concurrently
+	result, err := collectionsCollection.UpdateOne(dbContext, bson.M{
+		"_id":                 collection.ID,
+		"items.definition_id": bson.M{"$ne": definition.ID},
+		fmt.Sprintf("items.%d", maxCollectionItems-1): bson.M{"$exists": false},
+	}, bson.M{
+		"$push": bson.M{"items": push},
+		"$set":  bson.M{"last_change_date": item.AddedDate},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// the collection was changed since it was read
+	collection, err = getCollectionById(request.CollectionId)
+
+	if err != nil {
+		return err
+	}
+
+	if len(collection.Items) >= maxCollectionItems {
+		return constants.ErrorCollectionFull
+	}
+
+	return constants.ErrorDefinitionAlreadyInCollection
+
+}
+
+func ChangeCollectionItem(request *types.ChangeCollectionItemRequest, userId string) error {
+
+	collection, err := getOwnCollection(request.CollectionId, userId)
+
+	if err != nil {
+		return err
+	}
+
+	definitionId, err := primitive.ObjectIDFromHex(request.DefinitionId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	result, err := collectionsCollection.UpdateOne(dbContext, bson.M{
+		"_id":                 collection.ID,
+		"items.definition_id": definitionId,
+	}, bson.M{"$set": bson.M{
+		"items.$.note":     request.Note,
+		"last_change_date": time.Now(),
+	}})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorCollectionItemNotFound
+	}
+
+	return nil
+
+}
+
+func RemoveCollectionItem(collectionId string, definitionId string, userId string) error {
+
+	collection, err := getOwnCollection(collectionId, userId)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(definitionId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	result, err := collectionsCollection.UpdateOne(dbContext, bson.M{
+		"_id":                 collection.ID,
+		"items.definition_id": id,
+	}, bson.M{
+		"$pull": bson.M{"items": bson.M{"definition_id": id}},
+		"$set":  bson.M{"last_change_date": time.Now()},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorCollectionItemNotFound
+	}
+
+	return nil
+
+}
+
+/* Orders the items like the given definition IDs, which must contain every item exactly once */
+func OrderCollection(request *types.OrderCollectionRequest, userId string) error {
+
+	collection, err := getOwnCollection(request.CollectionId, userId)
+
+	if err != nil {
+		return err
+	}
+
+	if len(request.DefinitionIds) != len(collection.Items) {
+		return constants.ErrorInvalidCollectionOrder
+	}
+
+	itemMap := map[primitive.ObjectID]types.CollectionItem{}
+	for _, item := range collection.Items {
+		itemMap[item.DefinitionId] = item
+	}
+
+	items := []types.CollectionItem{}
+
+	for _, definitionId := range request.DefinitionIds {
+
+		id, err := primitive.ObjectIDFromHex(definitionId)
+
+		if err != nil {
+			return constants.ErrorInvalidID
+		}
+
+		item, ok := itemMap[id]
+
+		if !ok {
+			return constants.ErrorInvalidCollectionOrder
+		}
+
+		// each item only once
+		delete(itemMap, id)
+		items = append(items, item)
+
+	}
+
+	// the items are replaced, so the update fails, if they were changed since they were read
+	result, err := collectionsCollection.UpdateOne(dbContext, bson.M{
+		"_id":              collection.ID,
+		"last_change_date": collection.LastChangeDate,
+		"items":            bson.M{"$size": len(collection.Items)},
+	}, bson.M{"$set": bson.M{
+		"items":            items,
+		"last_change_date": time.Now(),
+	}})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorCollectionChanged
+	}
+
+	return nil
+
+}
+
+func removeDefinitionFromCollections(ctx mongo.SessionContext, definitionId primitive.ObjectID) error {
+
+	_, err := collectionsCollection.UpdateMany(ctx, bson.M{"items.definition_id": definitionId}, bson.M{
+		"$pull": bson.M{"items": bson.M{"definition_id": definitionId}},
+	})
+
+	return err
+
+}
@@ -0,0 +1,416 @@
+package database
+
+import (
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var commentsCollection *mongo.Collection
+
+func createCommentIndexes() error {
+
+	_, err := commentsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "definition_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "submitted_date", Value: 1}}, Options: options.Index().SetName("definition_thread"),
+	})
+
+	return err
+
+}
+
+func dropCommentIndexes() error {
+
+	_, err := commentsCollection.Indexes().DropOne(dbContext, "definition_thread")
+	return err
+
+}
+
+func CommentToResponse(comment *types.Comment, includeHidden bool) (*types.CommentResponse, error) {
+
+	responses, err := CommentsToResponses([]*types.Comment{comment}, includeHidden)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &responses[0], nil
+
+}
+
+/* The reply counts of all comments are loaded with one query and nicknames are requested once per user */
+func CommentsToResponses(comments []*types.Comment, includeHidden bool) ([]types.CommentResponse, error) {
+
+	commentIds := []primitive.ObjectID{}
+	definitionIds := []primitive.ObjectID{}
+
+	for _, comment := range comments {
+		commentIds = append(commentIds, comment.ID)
+		definitionIds = append(definitionIds, comment.DefinitionId)
+	}
+
+	replyCounts, err := countVisibleReplies(definitionIds, commentIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nicknames := map[string]string{}
+	responses := []types.CommentResponse{}
+
+	for _, comment := range comments {
+
+		response := types.CommentResponse{
+			ID:              comment.ID,
+			DefinitionId:    comment.DefinitionId,
+			ParentId:        comment.ParentId,
+			SubmittedBy:     comment.SubmittedBy,
+			SubmittedByName: getNickname(nicknames, comment.SubmittedBy),
+			SubmittedDate:   comment.SubmittedDate,
+			Edited:          comment.LastChangeDate.After(comment.SubmittedDate),
+			Deleted:         comment.Deleted,
+			Hidden:          comment.Hidden,
+			ReplyCount:      replyCounts[comment.ID],
+		}
+
+		if !comment.Deleted && (!comment.Hidden || includeHidden) {
+
+			response.Content = comment.Content
+			response.HTML, err = common.RenderMarkdown(comment.Content)
+
+			if err != nil {
+				return nil, err
+			}
+
+		}
+
+		responses = append(responses, response)
+
+	}
+
+	return responses, nil
+
+}
+
+func getCommentByObjectId(id primitive.ObjectID) (*types.Comment, error) {
+
+	var comment types.Comment
+	err := commentsCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&comment)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorCommentNotFound
+		}
+		return nil, err
+	}
+
+	return &comment, nil
+
+}
+
+func getCommentById(commentId string) (*types.Comment, error) {
+
+	id, err := primitive.ObjectIDFromHex(commentId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	return getCommentByObjectId(id)
+
+}
+
+/* Only approved definitions can be commented. Replies belong to the definition of their parent. */
+func CreateComment(request *types.CreateCommentRequest, userId string) (*primitive.ObjectID, error) {
+
+	definition, err := GetDefinitionById(request.DefinitionId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !definition.Approved {
+		return nil, constants.ErrorDefinitionNotApproved
+	}
+
+	now := time.Now()
+	comment := types.Comment{
+		ID:             primitive.NewObjectID(),
+		DefinitionId:   definition.ID,
+		SubmittedBy:    userId,
+		SubmittedDate:  now,
+		LastChangeDate: now,
+		Content:        request.Content,
+	}
+
+	var parent *types.Comment
+
+	if request.ParentId != nil {
+
+		parent, err = getCommentById(*request.ParentId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.DefinitionId != definition.ID {
+			return nil, constants.ErrorCommentNotFound
+		}
+
+		if parent.Deleted {
+			return nil, constants.ErrorCommentDeleted
+		}
+
+		comment.ParentId = &parent.ID
+
+	}
+
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		_, err := commentsCollection.InsertOne(ctx, comment)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.CommentCreated, comment.ID, userId, nil)
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment.ID, nil
+
+}
+
+func ChangeComment(request *types.ChangeCommentRequest, userId string) error {
+
+	comment, err := getCommentById(request.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if comment.SubmittedBy != userId {
+		return constants.ErrorCommentBelongsToAnotherUser
+	}
+
+	if comment.Deleted {
+		return constants.ErrorCommentDeleted
+	}
+
+	if comment.Hidden {
+		return constants.ErrorCommentHidden
+	}
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+
+		_, err := commentsCollection.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$set": bson.M{
+			"content":          request.Content,
+			"last_change_date": time.Now(),
+		}})
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.CommentChanged, comment.ID, userId, nil)
+
+	})
+
+}
+
+/* Comments can be deleted by their author and by moderators. Comments with replies only lose their content. */
+func DeleteComment(commentId string, userId string, isModerator bool) error {
+
+	comment, err := getCommentById(commentId)
+
+	if err != nil {
+		return err
+	}
+
+	if comment.SubmittedBy != userId && !isModerator {
+		return constants.ErrorCommentBelongsToAnotherUser
+	}
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+
+		replyCount, err := commentsCollection.CountDocuments(ctx, bson.M{"parent_id": comment.ID})
+
+		if err != nil {
+			return err
+		}
+
+		if replyCount > 0 {
+			_, err = commentsCollection.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$set": bson.M{
+				"content": "",
+				"deleted": true,
+			}})
+		} else {
+			_, err = commentsCollection.DeleteOne(ctx, bson.M{"_id": comment.ID})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// the comment itself may be gone
+		return recordDomainEvent(ctx, types.EnumDomainEventType.CommentDeleted, comment.ID, userId, map[string]string{"definitionId": comment.DefinitionId.Hex()})
+
+	})
+
+}
+
+func SetCommentHidden(commentId string, hidden bool, userId string) error {
+
+	comment, err := getCommentById(commentId)
+
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"hidden": false, "hidden_by": nil, "hidden_date": nil}
+	eventType := types.EnumDomainEventType.CommentUnhidden
+
+	if hidden {
+		update = bson.M{"hidden": true, "hidden_by": userId, "hidden_date": time.Now()}
+		eventType = types.EnumDomainEventType.CommentHidden
+	}
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+
+		_, err := commentsCollection.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$set": update})
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, eventType, comment.ID, userId, nil)
+
+	})
+
+}
+
+/* Returns the requested page of the thread, oldest first, and the total count of comments in the thread */
+func GetComments(request *types.CommentPageRequest) ([]*types.Comment, int, error) {
+
+	definitionId, err := primitive.ObjectIDFromHex(request.DefinitionId)
+
+	if err != nil {
+		return nil, 0, constants.ErrorInvalidID
+	}
+
+	filter := bson.M{"definition_id": definitionId, "parent_id": nil}
+
+	if request.ParentId != nil {
+
+		parentId, err := primitive.ObjectIDFromHex(*request.ParentId)
+
+		if err != nil {
+			return nil, 0, constants.ErrorInvalidID
+		}
+
+		filter["parent_id"] = parentId
+
+	}
+
+	total, err := countDocuments(commentsCollection, filter, nil)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "submitted_date", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((request.Page - 1) * request.PageSize)).
+		SetLimit(int64(request.PageSize))
+
+	comments, err := getDocuments[types.Comment](commentsCollection, filter, findOptions)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+
+}
+
+/* The number of comments shown to everyone per definition, used by the definition responses. Definitions without comments are missing. */
+func countVisibleComments(definitionIds []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"definition_id": bson.M{"$in": definitionIds}, "deleted": false, "hidden": false}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$definition_id"},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+	}
+
+	results, err := aggregateDocuments[struct {
+		DefinitionId primitive.ObjectID `bson:"_id"`
+		Count        int                `bson:"count"`
+	}](commentsCollection, pipeline, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[primitive.ObjectID]int{}
+	for _, result := range results {
+		counts[result.DefinitionId] = result.Count
+	}
+
+	return counts, nil
+
+}
+
+/*
+The number of replies shown to everyone per comment, like countVisibleComments. Comments without replies are missing.
+The definitions of the comments are matched as well, so the index of the threads is used.
+*/
+func countVisibleReplies(definitionIds []primitive.ObjectID, commentIds []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{
+			"definition_id": bson.M{"$in": definitionIds},
+			"parent_id":     bson.M{"$in": commentIds},
+			"deleted":       false,
+			"hidden":        false,
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$parent_id"},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+	}
+
+	results, err := aggregateDocuments[struct {
+		CommentId primitive.ObjectID `bson:"_id"`
+		Count     int                `bson:"count"`
+	}](commentsCollection, pipeline, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[primitive.ObjectID]int{}
+	for _, result := range results {
+		counts[result.CommentId] = result.Count
+	}
+
+	return counts, nil
+
+}
+
+func deleteDefinitionComments(ctx mongo.SessionContext, definitionId primitive.ObjectID) error {
+
+	_, err := commentsCollection.DeleteMany(ctx, bson.M{"definition_id": definitionId})
+	return err
+
+}
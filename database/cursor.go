@@ -0,0 +1,295 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type cursorDirection string
+
+const (
+	cursorAfter  cursorDirection = "after"
+	cursorBefore cursorDirection = "before"
+)
+
+/*
+Content of an opaque cursor. The sort and a hash of the filter are stored, so a cursor can not be used
+with another query. The cursor points to the item before (or after) the requested page.
+*/
+type cursorPosition struct {
+	Field      string             `bson:"f"`
+	Order      string             `bson:"o"`
+	FilterHash string             `bson:"h"`
+	Direction  cursorDirection    `bson:"d"`
+	SortKey    bson.RawValue      `bson:"k"`
+	ID         primitive.ObjectID `bson:"i"`
+}
+
+type cursorPageResult struct {
+	Items []bson.Raw `bson:"items"`
+	Total []struct {
+		Total int `bson:"total"`
+	} `bson:"total"`
+}
+
+func encodeCursor(position *cursorPosition) (*string, error) {
+
+	data, err := bson.Marshal(position)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+
+	return &cursor, nil
+
+}
+
+func decodeCursor(cursor string) (*cursorPosition, error) {
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidCursor
+	}
+
+	position := cursorPosition{}
+
+	if err := bson.Unmarshal(data, &position); err != nil {
+		return nil, constants.ErrorInvalidCursor
+	}
+
+	if position.Direction != cursorAfter && position.Direction != cursorBefore {
+		return nil, constants.ErrorInvalidCursor
+	}
+
+	return &position, nil
+
+}
+
+func hashCursorFilter(filter interface{}) (string, error) {
+
+	data, err := json.Marshal(filter)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:8]), nil
+
+}
+
+/* Without a sort the documents are sorted ascending by their ID */
+func getCursorSort(sort *types.SortRequest) (string, string) {
+
+	if sort == nil {
+		return "_id", types.EnumSortOrder.Ascending.String()
+	}
+
+	if sort.IsDescending() {
+		return sort.Field.String(), types.EnumSortOrder.Descending.String()
+	}
+
+	return sort.Field.String(), types.EnumSortOrder.Ascending.String()
+
+}
+
+func createCursorFromDocument(document bson.Raw, position cursorPosition) (*string, error) {
+
+	sortKey, err := document.LookupErr(sortKeyField)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := document.Lookup("_id").ObjectIDOK()
+
+	if !ok {
+		return nil, constants.ErrorInvalidID
+	}
+
+	position.SortKey = sortKey
+	position.ID = id
+
+	return encodeCursor(&position)
+
+}
+
+/*
+Returns one page after or before the given cursor using keyset pagination on the sort key and the ID.
+The items and the optional total count are fetched in a single aggregation. The filter is only used
+to bind the cursor to the query, the filter stages must already contain it.
+*/
+func getCursorPage[T interface{}](collection *mongo.Collection, entity sortEntity, filterStages bson.A, filter interface{}, textSearch bool, request *types.CursorRequest) (*types.CursorPage[T], error) {
+
+	if request.Limit <= 0 {
+		return nil, constants.ErrorInvalidType
+	}
+
+	filterHash, err := hashCursorFilter(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	field, order := getCursorSort(request.Sort)
+
+	var position *cursorPosition
+
+	if request.Cursor != nil {
+
+		position, err = decodeCursor(*request.Cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if position.Field != field || position.Order != order || position.FilterHash != filterHash {
+			return nil, constants.ErrorInvalidCursor
+		}
+
+	}
+
+	sortKeyStages, _, err := createSortStages(entity, request.Sort, textSearch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Sort == nil {
+		sortKeyStages = append(sortKeyStages, bson.D{{Key: "$addFields", Value: bson.D{{Key: sortKeyField, Value: "$_id"}}}})
+	}
+
+	// pages before the cursor are read in reverse order and turned around afterwards
+	backwards := position != nil && position.Direction == cursorBefore
+
+	direction := 1
+	if (order == types.EnumSortOrder.Descending.String()) != backwards {
+		direction = -1
+	}
+
+	itemStages := bson.A{}
+
+	if position != nil {
+
+		comparison := "$gt"
+		if direction == -1 {
+			comparison = "$lt"
+		}
+
+		itemStages = append(itemStages, bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: sortKeyField, Value: bson.D{{Key: comparison, Value: position.SortKey}}}},
+			bson.D{{Key: sortKeyField, Value: position.SortKey}, {Key: "_id", Value: bson.D{{Key: comparison, Value: position.ID}}}},
+		}}}}})
+
+	}
+
+	// one more item than requested tells if there is another page
+	itemStages = append(itemStages,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: sortKeyField, Value: direction}, {Key: "_id", Value: direction}}}},
+		bson.D{{Key: "$limit", Value: int64(request.Limit + 1)}},
+	)
+
+	facet := bson.D{{Key: "items", Value: itemStages}}
+
+	if request.IncludeTotalCount != nil && *request.IncludeTotalCount {
+		facet = append(facet, bson.E{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "total"}}}})
+	}
+
+	pipeline := bson.A{}
+	pipeline = append(pipeline, filterStages...)
+	pipeline = append(pipeline, sortKeyStages...)
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: facet}})
+
+	results, err := aggregateDocuments[cursorPageResult](collection, pipeline, options.Aggregate())
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := types.CursorPage[T]{Items: []*T{}}
+
+	if request.IncludeTotalCount != nil && *request.IncludeTotalCount {
+		total := 0
+		page.TotalCount = &total
+	}
+
+	if len(results) == 0 {
+		return &page, nil
+	}
+
+	result := results[0]
+
+	if page.TotalCount != nil && len(result.Total) > 0 {
+		*page.TotalCount = result.Total[0].Total
+	}
+
+	documents := result.Items
+	hasMore := len(documents) > request.Limit
+
+	if hasMore {
+		documents = documents[:request.Limit]
+	}
+
+	if backwards {
+		for i, j := 0, len(documents)-1; i < j; i, j = i+1, j-1 {
+			documents[i], documents[j] = documents[j], documents[i]
+		}
+	}
+
+	for _, document := range documents {
+
+		item := new(T)
+
+		if err := bson.Unmarshal(document, item); err != nil {
+			return nil, err
+		}
+
+		page.Items = append(page.Items, item)
+
+	}
+
+	if len(documents) == 0 {
+		return &page, nil
+	}
+
+	cursor := cursorPosition{Field: field, Order: order, FilterHash: filterHash}
+
+	// a page before the cursor always has a following page and a page after the cursor a previous one
+	if hasMore || backwards {
+
+		cursor.Direction = cursorAfter
+		page.NextCursor, err = createCursorFromDocument(documents[len(documents)-1], cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	if (backwards && hasMore) || (!backwards && position != nil) {
+
+		cursor.Direction = cursorBefore
+		page.PrevCursor, err = createCursorFromDocument(documents[0], cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return &page, nil
+
+}
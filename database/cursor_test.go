@@ -0,0 +1,83 @@
+package database
+
+import (
+	"encoding/base64"
+	"testing"
+	"yacoid_server/constants"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+
+	sortKeyType, sortKeyData, err := bson.MarshalValue("alan turing")
+
+	if err != nil {
+		t.Fatalf("MarshalValue failed: %v", err)
+	}
+
+	position := cursorPosition{
+		Field:      "title",
+		Order:      "asc",
+		FilterHash: "0123456789abcdef",
+		Direction:  cursorBefore,
+		SortKey:    bson.RawValue{Type: sortKeyType, Value: sortKeyData},
+		ID:         primitive.NewObjectID(),
+	}
+
+	cursor, err := encodeCursor(&position)
+
+	if err != nil {
+		t.Fatalf("encodeCursor failed: %v", err)
+	}
+
+	decoded, err := decodeCursor(*cursor)
+
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+
+	if decoded.Field != position.Field || decoded.Order != position.Order || decoded.FilterHash != position.FilterHash ||
+		decoded.Direction != position.Direction || decoded.ID != position.ID || decoded.SortKey.StringValue() != "alan turing" {
+		t.Errorf("decodeCursor = %+v, expected %+v", decoded, position)
+	}
+
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+
+	valid, err := encodeCursor(&cursorPosition{Field: "_id", Order: "asc", Direction: cursorAfter, ID: primitive.NewObjectID()})
+
+	if err != nil {
+		t.Fatalf("encodeCursor failed: %v", err)
+	}
+
+	// a cursor changed by the client, which still is a valid document
+	otherDirection, err := bson.Marshal(bson.M{"f": "_id", "o": "asc", "d": "sideways"})
+
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"garbage", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("abc"))},
+		{"no document", base64.RawURLEncoding.EncodeToString([]byte("abcdefgh"))},
+		{"truncated", (*valid)[:len(*valid)/2]},
+		{"appended", *valid + "AAAA"},
+		{"unknown direction", base64.RawURLEncoding.EncodeToString(otherDirection)},
+		{"no direction", base64.RawURLEncoding.EncodeToString([]byte{5, 0, 0, 0, 0})},
+	}
+
+	for _, test := range tests {
+		if _, err := decodeCursor(test.cursor); err != constants.ErrorInvalidCursor {
+			t.Errorf("decodeCursor of the %s cursor returned %v, expected %v", test.name, err, constants.ErrorInvalidCursor)
+		}
+	}
+
+}
@@ -52,13 +52,6 @@ func Connect() error {
 	database.CreateCollection(dbContext, "definitions")
 
 	definitionsCollection = database.Collection("definitions")
-	_, err = definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
-		Keys: bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
-	})
-
-	if err != nil {
-		return err
-	}
 
 	authorsCollection = database.Collection("authors")
 	_, err = authorsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
@@ -70,17 +63,19 @@ func Connect() error {
 	}
 
 	sourcesCollection = database.Collection("sources")
-	_, err = sourcesCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "book_properties.title", Value: "text"}, {Key: "book_properties.edition", Value: "text"}, {Key: "book_properties.publisher", Value: "text"},
-			{Key: "journal_properties.journal_name", Value: "text"}, {Key: "journal_properties.title", Value: "text"}, {Key: "journal_properties.edition", Value: "text"}, {Key: "journal_properties.publisher", Value: "text"},
-			{Key: "web_properties.article_name", Value: "text"}, {Key: "web_properties.url", Value: "text"}, {Key: "web_properties.website_name", Value: "text"},
-		},
-	})
-
-	if err != nil {
-		return err
-	}
+	savedSearchesCollection = database.Collection("saved_searches")
+	notificationsCollection = database.Collection("notifications")
+	notificationPreferencesCollection = database.Collection("notification_preferences")
+	slugsCollection = database.Collection("slugs")
+	tagsCollection = database.Collection("tags")
+	categoriesCollection = database.Collection("categories")
+	commentsCollection = database.Collection("comments")
+	notesCollection = database.Collection("notes")
+	ratingsCollection = database.Collection("ratings")
+	collectionsCollection = database.Collection("collections")
+	webhooksCollection = database.Collection("webhooks")
+	webhookDeliveriesCollection = database.Collection("webhook_deliveries")
+	outboxCollection = database.Collection("outbox")
 
 	return nil
 }
@@ -217,6 +212,23 @@ func stringsToObjectIDs(stringIds *[]string) ([]primitive.ObjectID, error) {
 
 }
 
+/* The IDs without duplicates, in the order of their first occurrence */
+func uniqueObjectIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+
+	seen := map[primitive.ObjectID]bool{}
+	uniqueIds := []primitive.ObjectID{}
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			uniqueIds = append(uniqueIds, id)
+		}
+	}
+
+	return uniqueIds
+
+}
+
 func appendUpdate(key string, currentValue interface{}, newValue interface{}) (entries bson.D) {
 
 	if newValue != currentValue && newValue != nil {
@@ -233,3 +245,13 @@ func forceUpdate(key string, newValue interface{}) (entries bson.D) {
 	return entries
 
 }
+
+func Disconnect() error {
+
+	if client == nil {
+		return nil
+	}
+
+	return client.Disconnect(dbContext)
+
+}
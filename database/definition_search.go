@@ -0,0 +1,550 @@
+package database
+
+import (
+	"strings"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const authorFacetLimit = 50
+const highlightSnippetLength = 200
+
+// a filter on a faceted field, facets ignore the filter of their own field
+type definitionFacetFilter struct {
+	facet string
+	match bson.E
+	// the filter needs the fields added by createDefinitionSourceJoinStages
+	needsSource bool
+}
+
+type definitionSearchResult struct {
+	Definitions []*types.Definition `bson:"definitions"`
+	Total       []struct {
+		Total int `bson:"total"`
+	} `bson:"total"`
+	types.DefinitionFacets `bson:",inline"`
+}
+
+func isDefinitionTextSearch(filter *types.DefinitionFilter) bool {
+	return filter != nil && filter.Content != nil && len(*filter.Content) > 0
+}
+
+/* In the phrase mode the whole content is one phrase, otherwise quotes and "-" are interpreted */
+func createDefinitionSearchQuery(filter *types.DefinitionFilter) common.SearchQuery {
+
+	if filter.SearchMode != nil && *filter.SearchMode == types.EnumSearchMode.Phrase {
+		phrase := strings.Join(strings.Fields(strings.ReplaceAll(*filter.Content, "\"", " ")), " ")
+		return common.SearchQuery{Terms: []string{}, Phrases: []string{phrase}, Excluded: []string{}}
+	}
+
+	return common.ParseSearchQuery(*filter.Content)
+
+}
+
+/* Match stage for all filters, that are not faceted. Must be the first stage, because it can contain $text. */
+func createDefinitionBaseMatch(filter *types.DefinitionFilter) bson.D {
+
+	matchStage := bson.D{}
+
+	if isDefinitionTextSearch(filter) {
+
+		allTerms := filter.SearchMode != nil && *filter.SearchMode == types.EnumSearchMode.All
+		textSearch := bson.D{{Key: "$search", Value: createDefinitionSearchQuery(filter).ToTextSearch(allTerms)}}
+
+		if filter.Language != nil {
+			textSearch = append(textSearch, bson.E{Key: "$language", Value: filter.Language.String()})
+		}
+
+		matchStage = append(matchStage, bson.E{Key: "$text", Value: textSearch})
+
+	}
+
+	if filter.Language != nil {
+		matchStage = append(matchStage, bson.E{Key: "language", Value: *filter.Language})
+	}
+
+	if filter.Approved != nil {
+		matchStage = append(matchStage, bson.E{Key: "approved", Value: *filter.Approved})
+	}
+
+	if filter.OriginalsOnly != nil && *filter.OriginalsOnly {
+		matchStage = append(matchStage, bson.E{Key: "translation_of", Value: bson.M{"$exists": false}})
+	}
+
+	if filter.UserId != nil && len(*filter.UserId) > 0 {
+		matchStage = append(matchStage, bson.E{Key: "submitted_by", Value: *filter.UserId})
+	}
+
+	if filter.MinScore != nil {
+		matchStage = append(matchStage, bson.E{Key: "rating.score", Value: bson.M{"$gte": *filter.MinScore}})
+	}
+
+	return matchStage
+
+}
+
+/*
+Adds the source of the definition as "source_document" and its publication year as "publication_year".
+The publication year is null, if the source has no publication date. The additional sources are added as
+"additional_source_documents" and the authors of all sources as "referenced_authors".
+*/
+func createDefinitionSourceJoinStages() bson.A {
+
+	return bson.A{
+		lookupStage("sources", "source", "source_document"),
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$source_document"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "publication_year", Value: bson.D{{Key: "$year", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+				"$source_document.book_properties.publication_date",
+				"$source_document.journal_properties.publication_date",
+				"$source_document.web_properties.publication_date",
+				nil,
+			}}}}}},
+		}}},
+		lookupStage("sources", "additional_sources.source", "additional_source_documents"),
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "referenced_authors", Value: bson.D{{Key: "$setUnion", Value: bson.A{
+				bson.D{{Key: "$ifNull", Value: bson.A{"$source_document.authors", bson.A{}}}},
+				bson.D{{Key: "$reduce", Value: bson.D{
+					{Key: "input", Value: "$additional_source_documents.authors"},
+					{Key: "initialValue", Value: bson.A{}},
+					{Key: "in", Value: bson.D{{Key: "$setUnion", Value: bson.A{"$$value", "$$this"}}}},
+				}}},
+			}}}},
+		}}},
+	}
+
+}
+
+func createDefinitionFacetFilters(filter *types.DefinitionFilter) ([]definitionFacetFilter, error) {
+
+	facetFilters := []definitionFacetFilter{}
+
+	if filter.Categories != nil && len(*filter.Categories) > 0 {
+
+		categories, err := expandCategories(*filter.Categories)
+
+		if err != nil {
+			return nil, err
+		}
+
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet: "categories",
+			match: bson.E{Key: "categories", Value: bson.M{"$in": categories}},
+		})
+
+	}
+
+	if filter.Languages != nil && len(*filter.Languages) > 0 {
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet: "languages",
+			match: bson.E{Key: "language", Value: bson.M{"$in": *filter.Languages}},
+		})
+	}
+
+	if filter.AuthorIds != nil && len(*filter.AuthorIds) > 0 {
+
+		authors, err := stringsToObjectIDs(filter.AuthorIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet:       "authors",
+			match:       bson.E{Key: "referenced_authors", Value: bson.M{"$in": authors}},
+			needsSource: true,
+		})
+
+	}
+
+	if filter.Query != nil && len(*filter.Query) > 0 {
+
+		queryMatch, err := compileDefinitionQuery(*filter.Query)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the query applies to every facet
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet:       "query",
+			match:       bson.E{Key: "$and", Value: bson.A{queryMatch}},
+			needsSource: true,
+		})
+
+	}
+
+	if filter.TagIds != nil && len(*filter.TagIds) > 0 {
+
+		tags, err := stringsToObjectIDs(filter.TagIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet: "tags",
+			match: bson.E{Key: "tags", Value: bson.M{"$in": tags}},
+		})
+
+	}
+
+	yearMatch, err := createPublishingYearMatch(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if yearMatch != nil {
+		facetFilters = append(facetFilters, definitionFacetFilter{
+			facet:       "publishingYears",
+			match:       *yearMatch,
+			needsSource: true,
+		})
+	}
+
+	return facetFilters, nil
+
+}
+
+/* Returns nil, if no year filter is active. The publication year is read from the joined source. */
+func createPublishingYearMatch(filter *types.DefinitionFilter) (*bson.E, error) {
+
+	conditions := bson.A{}
+
+	if filter.PublishingYears != nil && len(*filter.PublishingYears) > 0 {
+		conditions = append(conditions, bson.D{{Key: "publication_year", Value: bson.D{{Key: "$in", Value: *filter.PublishingYears}}}})
+	}
+
+	if filter.PublishingYearFrom != nil || filter.PublishingYearTo != nil {
+
+		if filter.PublishingYearFrom != nil && filter.PublishingYearTo != nil && *filter.PublishingYearFrom > *filter.PublishingYearTo {
+			return nil, constants.ErrorInvalidPublishingYearRange
+		}
+
+		yearRange := bson.D{}
+
+		if filter.PublishingYearFrom != nil {
+			yearRange = append(yearRange, bson.E{Key: "$gte", Value: *filter.PublishingYearFrom})
+		}
+
+		if filter.PublishingYearTo != nil {
+			yearRange = append(yearRange, bson.E{Key: "$lte", Value: *filter.PublishingYearTo})
+		}
+
+		conditions = append(conditions, bson.D{{Key: "publication_year", Value: yearRange}})
+
+	}
+
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	if filter.IncludeUnknownPublishingYear != nil && *filter.IncludeUnknownPublishingYear {
+		conditions = append(conditions, bson.D{{Key: "publication_year", Value: nil}})
+	}
+
+	return &bson.E{Key: "$or", Value: conditions}, nil
+
+}
+
+/*
+The stages before the facet filters, shared by the filter stages and the faceted search: the match of all filters,
+that are not faceted, and the join of the source, if a facet filter needs it.
+*/
+func createDefinitionBaseStages(filter *types.DefinitionFilter) (bson.A, []definitionFacetFilter, bool, error) {
+
+	facetFilters, err := createDefinitionFacetFilters(filter)
+
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$match", Value: createDefinitionBaseMatch(filter)}}}
+
+	needsSource := false
+	for _, facetFilter := range facetFilters {
+		needsSource = needsSource || facetFilter.needsSource
+	}
+
+	if needsSource {
+		pipeline = append(pipeline, createDefinitionSourceJoinStages()...)
+	}
+
+	return pipeline, facetFilters, needsSource, nil
+
+}
+
+/* Removes the fields added by createDefinitionSourceJoinStages, so the documents keep the shape of types.Definition */
+func createDefinitionJoinProjection() bson.D {
+
+	return bson.D{{Key: "$project", Value: bson.D{
+		{Key: "source_document", Value: 0},
+		{Key: "publication_year", Value: 0},
+		{Key: "additional_source_documents", Value: 0},
+		{Key: "referenced_authors", Value: 0},
+	}}}
+
+}
+
+/* Shared by the page, cursor and count queries. Only joins the source, if a filter needs it. */
+func createDefinitionFilterStages(filter *types.DefinitionFilter) (bson.A, error) {
+
+	if filter == nil {
+		filter = &types.DefinitionFilter{}
+	}
+
+	pipeline, facetFilters, needsSource, err := createDefinitionBaseStages(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(facetFilters) > 0 {
+		pipeline = append(pipeline, combineDefinitionFacetFilters(facetFilters, ""))
+	}
+
+	if needsSource {
+		pipeline = append(pipeline, createDefinitionJoinProjection())
+	}
+
+	return pipeline, nil
+
+}
+
+/* Combines all facet filters except the one of the given facet */
+func combineDefinitionFacetFilters(facetFilters []definitionFacetFilter, exceptFacet string) bson.D {
+
+	match := bson.D{}
+
+	for _, facetFilter := range facetFilters {
+		if facetFilter.facet != exceptFacet {
+			match = append(match, facetFilter.match)
+		}
+	}
+
+	return bson.D{{Key: "$match", Value: match}}
+
+}
+
+/*
+Creates one aggregation returning the requested page, the total count and the facets. The count of
+each facet respects all active filters except the filter on the facet itself. The sources are only
+joined before $facet, if a filter needs them, otherwise only the facets grouping by them join them.
+*/
+func CreateDefinitionSearchQuery(page int64, pageSize int64, filter *types.DefinitionFilter, sort *types.SortRequest) (*bson.A, error) {
+
+	if filter == nil {
+		filter = &types.DefinitionFilter{}
+	}
+
+	pipeline, facetFilters, needsSource, err := createDefinitionBaseStages(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// joined inside the facets grouping by the source, after their filters reduced the documents
+	sourceJoinStages := createDefinitionSourceJoinStages()
+
+	if needsSource {
+		sourceJoinStages = bson.A{}
+	}
+
+	sortKeyStages, sortStage, err := createSortStages(sortDefinitions, sort, isDefinitionTextSearch(filter))
+
+	if err != nil {
+		return nil, err
+	}
+
+	allFilters := combineDefinitionFacetFilters(facetFilters, "")
+
+	definitionsStages := bson.A{allFilters}
+
+	// the text score is not available inside $facet, other sort keys are only computed for the filtered definitions
+	if sort != nil && sort.Field == types.EnumSortField.Relevance {
+		pipeline = append(pipeline, sortKeyStages...)
+	} else {
+		definitionsStages = append(definitionsStages, sortKeyStages...)
+	}
+
+	definitionsStages = append(definitionsStages, sortStage)
+
+	if page > 0 && pageSize > 0 {
+		definitionsStages = append(definitionsStages, bson.D{{Key: "$skip", Value: (page - 1) * pageSize}})
+	}
+
+	if pageSize > 0 {
+		definitionsStages = append(definitionsStages, bson.D{{Key: "$limit", Value: pageSize}})
+	}
+
+	definitionsStages = append(definitionsStages, createDefinitionJoinProjection())
+
+	countBuckets := func(field string) bson.A {
+		return bson.A{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: field},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		}
+	}
+
+	categoriesStages := bson.A{
+		combineDefinitionFacetFilters(facetFilters, "categories"),
+		bson.D{{Key: "$unwind", Value: "$categories"}},
+	}
+	categoriesStages = append(categoriesStages, countBuckets("$categories")...)
+	sourceTypesStages := append(append(bson.A{allFilters}, sourceJoinStages...), countBuckets("$source_document.type")...)
+	languagesStages := append(bson.A{combineDefinitionFacetFilters(facetFilters, "languages")}, countBuckets("$language")...)
+
+	publishingYearsStages := append(bson.A{combineDefinitionFacetFilters(facetFilters, "publishingYears")}, sourceJoinStages...)
+	publishingYearsStages = append(publishingYearsStages,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$publication_year"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+	)
+
+	authorsStages := append(bson.A{combineDefinitionFacetFilters(facetFilters, "authors")}, sourceJoinStages...)
+	authorsStages = append(authorsStages, bson.D{{Key: "$unwind", Value: "$referenced_authors"}})
+	authorsStages = append(authorsStages, countBuckets("$referenced_authors")...)
+	authorsStages = append(authorsStages,
+		bson.D{{Key: "$limit", Value: authorFacetLimit}},
+		lookupStage("authors", "_id", "author"),
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "count", Value: 1},
+			{Key: "label", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+				bson.D{{Key: "$concat", Value: bson.A{
+					bson.D{{Key: "$arrayElemAt", Value: bson.A{"$author.person_properties.first_name", 0}}},
+					" ",
+					bson.D{{Key: "$arrayElemAt", Value: bson.A{"$author.person_properties.last_name", 0}}},
+				}}},
+				bson.D{{Key: "$arrayElemAt", Value: bson.A{"$author.organization_properties.organization_name", 0}}},
+			}}}},
+		}}},
+	)
+
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "definitions", Value: definitionsStages},
+		{Key: "total", Value: bson.A{allFilters, bson.D{{Key: "$count", Value: "total"}}}},
+		{Key: "categories", Value: categoriesStages},
+		{Key: "source_types", Value: sourceTypesStages},
+		{Key: "authors", Value: authorsStages},
+		{Key: "publishing_years", Value: publishingYearsStages},
+		{Key: "languages", Value: languagesStages},
+	}}})
+
+	return &pipeline, nil
+
+}
+
+/* Returns the definitions of the requested page, the total count of matching definitions and the facets. */
+func SearchDefinitions(request *types.DefinitionPageRequest) ([]*types.Definition, int, *types.DefinitionFacets, error) {
+
+	if request.PageSize <= 0 || request.Page <= 0 {
+		return nil, 0, nil, constants.ErrorInvalidType
+	}
+
+	pipeline, err := CreateDefinitionSearchQuery(int64(request.Page), int64(request.PageSize), request.Filter, request.Sort)
+
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	results, err := aggregateDocuments[definitionSearchResult](definitionsCollection, *pipeline, options.Aggregate())
+
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if len(results) == 0 {
+		return []*types.Definition{}, 0, &types.DefinitionFacets{}, nil
+	}
+
+	result := results[0]
+	total := 0
+
+	if len(result.Total) > 0 {
+		total = result.Total[0].Total
+	}
+
+	return result.Definitions, total, &result.DefinitionFacets, nil
+
+}
+
+/*
+Highlighted snippets of the content and the source title of the definitions, keyed by the definition ID.
+Only definitions with a match are contained. The matching is an approximation of the stemming of MongoDB.
+*/
+func CreateDefinitionHighlights(definitions []*types.Definition, filter *types.DefinitionFilter) (map[string][]types.Highlight, error) {
+
+	highlights := map[string][]types.Highlight{}
+
+	hasQuery := filter != nil && filter.Query != nil && len(*filter.Query) > 0
+
+	if (!isDefinitionTextSearch(filter) && !hasQuery) || len(definitions) == 0 {
+		return highlights, nil
+	}
+
+	query := common.SearchQuery{Terms: []string{}, Phrases: []string{}, Excluded: []string{}}
+
+	if isDefinitionTextSearch(filter) {
+		query = createDefinitionSearchQuery(filter)
+	}
+
+	if hasQuery {
+		queryTerms := createQueryHighlightTerms(*filter.Query)
+		query.Terms = append(query.Terms, queryTerms.Terms...)
+		query.Phrases = append(query.Phrases, queryTerms.Phrases...)
+	}
+
+	sourceIds := []primitive.ObjectID{}
+	for _, definition := range definitions {
+		sourceIds = append(sourceIds, definition.Source)
+	}
+
+	sources, err := getDocuments[types.Source](sourcesCollection, bson.M{"_id": bson.M{"$in": sourceIds}}, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	sourceTitles := map[primitive.ObjectID]string{}
+	for _, source := range sources {
+		sourceTitles[source.ID] = source.GetDisplayTitle()
+	}
+
+	for _, definition := range definitions {
+
+		definitionHighlights := []types.Highlight{}
+
+		if snippet, ok := common.HighlightText(definition.Content, query, highlightSnippetLength); ok {
+			definitionHighlights = append(definitionHighlights, types.Highlight{Field: "content", Snippet: snippet})
+		}
+
+		if title, exists := sourceTitles[definition.Source]; exists {
+			if snippet, ok := common.HighlightText(title, query, highlightSnippetLength); ok {
+				definitionHighlights = append(definitionHighlights, types.Highlight{Field: "sourceTitle", Snippet: snippet})
+			}
+		}
+
+		if len(definitionHighlights) > 0 {
+			highlights[definition.ID.Hex()] = definitionHighlights
+		}
+
+	}
+
+	return highlights, nil
+
+}
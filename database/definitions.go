@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"math"
 	"time"
 
@@ -15,20 +16,105 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+/*
+The documents referenced by the definitions of a response, which are loaded with one query per collection
+instead of several queries per definition. Nicknames are requested once per user.
+*/
+type definitionResponseData struct {
+	nicknames map[string]string
+	sources   map[primitive.ObjectID]*types.SourceResponse
+	tags      map[primitive.ObjectID]*types.Tag
+	// the approved versions of every text, keyed by the ID of the original
+	versions      map[primitive.ObjectID][]*types.Definition
+	commentCounts map[primitive.ObjectID]int
+}
+
+func loadDefinitionResponseData(definitions []*types.Definition) (*definitionResponseData, error) {
+
+	data := definitionResponseData{nicknames: map[string]string{}}
+
+	sourceIds := []primitive.ObjectID{}
+	tagIds := []primitive.ObjectID{}
+	originalIds := []primitive.ObjectID{}
+	definitionIds := []primitive.ObjectID{}
+
+	for _, definition := range definitions {
+
+		definitionIds = append(definitionIds, definition.ID)
+		sourceIds = append(sourceIds, definition.Source)
+		originalIds = append(originalIds, definition.GetOriginalId())
+
+		for _, reference := range definition.AdditionalSources {
+			sourceIds = append(sourceIds, reference.Source)
+		}
+
+		tagIds = append(tagIds, definition.Tags...)
+
+	}
+
+	var err error
+	data.sources, err = getSourceResponsesByIds(sourceIds, data.nicknames)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.tags, err = getTagsByIds(tagIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.versions, err = getApprovedVersions(originalIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.commentCounts, err = countVisibleComments(definitionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+
+}
+
+func (data *definitionResponseData) getSource(sourceId primitive.ObjectID) (*types.SourceResponse, error) {
+
+	source, ok := data.sources[sourceId]
+
+	if !ok {
+		return nil, constants.ErrorSourceNotFound
+	}
+
+	return source, nil
+
+}
+
 func DefinitionToUserResponse(definition *types.Definition) (*types.DefinitionsOfUserResponse, error) {
 
+	responses, err := DefinitionsToUserResponses(&[]*types.Definition{definition})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &(*responses)[0], nil
+
+}
+
+func definitionToUserResponse(definition *types.Definition, data *definitionResponseData) (*types.DefinitionsOfUserResponse, error) {
+
 	response := types.DefinitionsOfUserResponse{}
 
 	response.ID = definition.ID
+	response.SlugId = definition.SlugId
+	response.Language = definition.Language
 
 	response.SubmittedBy = definition.SubmittedBy
-	nickname, err := auth.GetNicknameOfUser(definition.SubmittedBy)
-
-	if err == nil {
-		response.SubmittedByName = nickname
-	} else {
-		response.SubmittedByName = "<deleted>"
-	}
+	response.SubmittedByName = getNickname(data.nicknames, definition.SubmittedBy)
 
 	response.SubmittedDate = definition.SubmittedDate
 
@@ -39,20 +125,33 @@ func DefinitionToUserResponse(definition *types.Definition) (*types.DefinitionsO
 	response.RejectionLog = RejectionsToResponses(definition.RejectionLog)
 	response.Content = definition.Content
 
-	source, err := GetSource(definition.Source)
+	sourceResponse, err := data.getSource(definition.Source)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sourceResponse, err := SourceToResponse(source)
+	response.Source = *sourceResponse
+	response.Locator = definition.Locator
+	response.Paraphrase = definition.Paraphrase
+	response.AdditionalSources, err = getSourceReferenceResponses(definition.AdditionalSources, data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	response.Source = *sourceResponse
-	response.Category = definition.Category
+	response.Citation = types.AppendSecondaryCitations(types.FormatCitation(sourceResponse, definition.Locator, definition.Paraphrase), response.AdditionalSources)
+	response.Categories = definition.Categories
+
+	// the submitter also sees the tags, which are not approved yet
+	response.Tags = getDefinitionTags(definition.Tags, data.tags, true)
+
+	response.TranslationOf = definition.TranslationOf
+	response.Translations = getDefinitionTranslations(definition, data)
+
+	response.CommentCount = data.commentCounts[definition.ID]
+
+	response.Rating = definition.Rating
 
 	response.Status = definition.GetStatus()
 
@@ -62,11 +161,17 @@ func DefinitionToUserResponse(definition *types.Definition) (*types.DefinitionsO
 
 func DefinitionsToUserResponses(definitions *[]*types.Definition) (*[]types.DefinitionsOfUserResponse, error) {
 
+	data, err := loadDefinitionResponseData(*definitions)
+
+	if err != nil {
+		return nil, err
+	}
+
 	responses := []types.DefinitionsOfUserResponse{}
 
 	for _, definition := range *definitions {
 
-		response, err := DefinitionToUserResponse(definition)
+		response, err := definitionToUserResponse(definition, data)
 
 		if err != nil {
 			return nil, err
@@ -81,36 +186,56 @@ func DefinitionsToUserResponses(definitions *[]*types.Definition) (*[]types.Defi
 
 func DefinitionToResponse(definition *types.Definition) (*types.DefinitionResponse, error) {
 
+	responses, err := DefinitionsToResponses(&[]*types.Definition{definition})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &(*responses)[0], nil
+
+}
+
+func definitionToResponse(definition *types.Definition, data *definitionResponseData) (*types.DefinitionResponse, error) {
+
 	response := types.DefinitionResponse{}
 
 	response.ID = definition.ID
+	response.SlugId = definition.SlugId
+	response.Language = definition.Language
 
 	response.SubmittedBy = definition.SubmittedBy
-	nickname, err := auth.GetNicknameOfUser(definition.SubmittedBy)
-
-	if err == nil {
-		response.SubmittedByName = nickname
-	} else {
-		response.SubmittedByName = "<deleted>"
-	}
+	response.SubmittedByName = getNickname(data.nicknames, definition.SubmittedBy)
 
 	response.SubmittedDate = definition.SubmittedDate
 	response.Content = definition.Content
 
-	source, err := GetSource(definition.Source)
+	sourceResponse, err := data.getSource(definition.Source)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sourceResponse, err := SourceToResponse(source)
+	response.Source = *sourceResponse
+	response.Locator = definition.Locator
+	response.Paraphrase = definition.Paraphrase
+	response.AdditionalSources, err = getSourceReferenceResponses(definition.AdditionalSources, data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	response.Source = *sourceResponse
-	response.Category = definition.Category
+	response.Citation = types.AppendSecondaryCitations(types.FormatCitation(sourceResponse, definition.Locator, definition.Paraphrase), response.AdditionalSources)
+	response.Categories = definition.Categories
+
+	response.Tags = getDefinitionTags(definition.Tags, data.tags, false)
+
+	response.TranslationOf = definition.TranslationOf
+	response.Translations = getDefinitionTranslations(definition, data)
+
+	response.CommentCount = data.commentCounts[definition.ID]
+
+	response.Rating = definition.Rating
 
 	return &response, nil
 
@@ -118,11 +243,17 @@ func DefinitionToResponse(definition *types.Definition) (*types.DefinitionRespon
 
 func DefinitionsToResponses(definitions *[]*types.Definition) (*[]types.DefinitionResponse, error) {
 
+	data, err := loadDefinitionResponseData(*definitions)
+
+	if err != nil {
+		return nil, err
+	}
+
 	responses := []types.DefinitionResponse{}
 
 	for _, definition := range *definitions {
 
-		response, err := DefinitionToResponse(definition)
+		response, err := definitionToResponse(definition, data)
 
 		if err != nil {
 			return nil, err
@@ -186,7 +317,17 @@ func SubmitDefinition(request *types.SubmitDefinitionRequest, userId string) (*p
 	definition.Approved = false
 
 	definition.Content = request.Content
-	definition.Category = request.Category
+	definition.Locator = request.Locator
+	definition.Categories = request.Categories
+	definition.Language = types.DefaultLanguage
+
+	if request.Paraphrase != nil {
+		definition.Paraphrase = *request.Paraphrase
+	}
+
+	if request.Language != nil {
+		definition.Language = *request.Language
+	}
 
 	sourceId, err := primitive.ObjectIDFromHex(request.SourceId)
 
@@ -202,11 +343,152 @@ func SubmitDefinition(request *types.SubmitDefinitionRequest, userId string) (*p
 
 	definition.Source = sourceId
 
+	if request.AdditionalSources != nil {
+
+		definition.AdditionalSources, err = createSourceReferences(*request.AdditionalSources, sourceId)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	rejectionLog := []*types.Rejection{}
+	definition.RejectionLog = &rejectionLog
+
+	// new tags, the slug and the references of the sources are registered together with the definition
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		if request.Tags != nil {
+
+			tagIds, err := proposeTags(ctx, *request.Tags, userId)
+
+			if err != nil {
+				return err
+			}
+
+			definition.Tags = tagIds
+
+		}
+
+		slugId, err := assignSlug(ctx, types.EnumSlugEntity.Definition, definition.ID, getDefinitionSlugText(&definition))
+
+		if err != nil {
+			return err
+		}
+
+		definition.SlugId = slugId
+
+		err = touchReferencedSources(ctx, definition.GetSourceIds(), now)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = definitionsCollection.InsertOne(ctx, definition)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionSubmitted, definition.ID, userId, nil)
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &definition.ID, nil
+
+}
+
+/*
+Submits a translation of an approved definition, which has to be approved like every other definition.
+The translation takes the source, categories and tags of the original. Translations of translations
+are linked to the original text.
+*/
+func SubmitTranslation(request *types.SubmitTranslationRequest, userId string) (*primitive.ObjectID, error) {
+
+	original, err := GetDefinitionById(request.DefinitionId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !original.Approved {
+		return nil, constants.ErrorDefinitionNotApproved
+	}
+
+	if original.IsTranslation() {
+
+		original, err = GetDefinitionByObjectId(*original.TranslationOf)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	err = validateTranslationLanguage(dbContext, original.ID, primitive.NilObjectID, request.Language)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var definition types.Definition
+
+	now := time.Now()
+	definition.ID = primitive.NewObjectID()
+	definition.SubmittedBy = userId
+	definition.SubmittedDate = now
+	definition.LastChangeDate = now
+	definition.Approved = false
+
+	definition.Content = request.Content
+	definition.Language = request.Language
+	definition.Source = original.Source
+	definition.Locator = original.Locator
+	definition.Paraphrase = original.Paraphrase
+	definition.AdditionalSources = original.AdditionalSources
+	definition.Categories = original.Categories
+	definition.Tags = original.Tags
+	definition.TranslationOf = &original.ID
+
 	rejectionLog := []*types.Rejection{}
 	definition.RejectionLog = &rejectionLog
 
-	_, err = definitionsCollection.InsertOne(dbContext, definition)
-	// TODO: send email to user??
+	// the slug is registered together with the definition
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		// the original is checked again and written, so a concurrent deletion of it conflicts with the submit
+		result, err := definitionsCollection.UpdateOne(ctx, bson.M{"_id": original.ID, "approved": true}, bson.M{"$set": bson.M{"last_translation_date": now}})
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return constants.ErrorDefinitionNotFound
+		}
+
+		slugId, err := assignSlug(ctx, types.EnumSlugEntity.Definition, definition.ID, getDefinitionSlugText(&definition))
+
+		if err != nil {
+			return err
+		}
+
+		definition.SlugId = slugId
+
+		_, err = definitionsCollection.InsertOne(ctx, definition)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionSubmitted, definition.ID, userId, nil)
+
+	})
 
 	if err != nil {
 		return nil, err
@@ -216,6 +498,155 @@ func SubmitDefinition(request *types.SubmitDefinitionRequest, userId string) (*p
 
 }
 
+/*
+A text has at most one approved version per language, the definition with the excluded ID is ignored. The context
+can be the context of a transaction, which has to write the original, so concurrent approvals conflict.
+*/
+func validateTranslationLanguage(ctx context.Context, originalId primitive.ObjectID, excludedId primitive.ObjectID, language types.Language) error {
+
+	var original types.Definition
+	err := definitionsCollection.FindOne(ctx, bson.M{"_id": originalId}).Decode(&original)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return constants.ErrorDefinitionNotFound
+		}
+		return err
+	}
+
+	if original.Language == language {
+		return constants.ErrorTranslationSameLanguage
+	}
+
+	count, err := definitionsCollection.CountDocuments(ctx, bson.M{
+		"translation_of": originalId,
+		"_id":            bson.M{"$ne": excludedId},
+		"language":       language,
+		"approved":       true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return constants.ErrorTranslationAlreadyExists
+	}
+
+	return nil
+
+}
+
+/* The approved originals and translations of the texts, keyed by the ID of the original and sorted by language */
+func getApprovedVersions(originalIds []primitive.ObjectID) (map[primitive.ObjectID][]*types.Definition, error) {
+
+	filter := bson.M{
+		"$or":      bson.A{bson.M{"_id": bson.M{"$in": originalIds}}, bson.M{"translation_of": bson.M{"$in": originalIds}}},
+		"approved": true,
+	}
+
+	definitions, err := getDocuments[types.Definition](definitionsCollection, filter, options.Find().SetSort(bson.D{{Key: "language", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[primitive.ObjectID][]*types.Definition{}
+	for _, definition := range definitions {
+		versions[definition.GetOriginalId()] = append(versions[definition.GetOriginalId()], definition)
+	}
+
+	return versions, nil
+
+}
+
+/* The approved versions of the text in other languages, including the original, sorted by language */
+func getDefinitionTranslations(definition *types.Definition, data *definitionResponseData) []types.TranslationReference {
+
+	references := []types.TranslationReference{}
+
+	for _, translation := range data.versions[definition.GetOriginalId()] {
+
+		if translation.ID == definition.ID {
+			continue
+		}
+
+		references = append(references, types.TranslationReference{
+			ID:       translation.ID,
+			SlugId:   translation.SlugId,
+			Language: translation.Language,
+			Original: !translation.IsTranslation(),
+		})
+
+	}
+
+	return references
+
+}
+
+/* Every source can only be referenced once by a definition, including the primary source */
+func createSourceReferences(requests []types.SourceReferenceRequest, primarySourceId primitive.ObjectID) ([]types.SourceReference, error) {
+
+	references := []types.SourceReference{}
+	seen := map[primitive.ObjectID]bool{primarySourceId: true}
+
+	for _, request := range requests {
+
+		sourceId, err := primitive.ObjectIDFromHex(request.SourceId)
+
+		if err != nil {
+			return nil, constants.ErrorInvalidID
+		}
+
+		if seen[sourceId] {
+			return nil, constants.ErrorDuplicateSourceReference
+		}
+
+		seen[sourceId] = true
+
+		err = validateSourceExists(sourceId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		references = append(references, types.SourceReference{
+			Source:   sourceId,
+			Relation: request.Relation,
+			Locator:  request.Locator,
+		})
+
+	}
+
+	return references, nil
+
+}
+
+func getSourceReferenceResponses(references []types.SourceReference, data *definitionResponseData) ([]types.SourceReferenceResponse, error) {
+
+	responses := []types.SourceReferenceResponse{}
+
+	for _, reference := range references {
+
+		sourceResponse, err := data.getSource(reference.Source)
+
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, types.SourceReferenceResponse{
+			Source:   *sourceResponse,
+			Relation: reference.Relation,
+			Locator:  reference.Locator,
+			Citation: types.FormatCitation(sourceResponse, reference.Locator, false),
+		})
+
+	}
+
+	return responses, nil
+
+}
+
 func ApproveDefinition(definitionId string, userId string) error {
 
 	id, err := primitive.ObjectIDFromHex(definitionId)
@@ -234,33 +665,66 @@ func ApproveDefinition(definitionId string, userId string) error {
 		return constants.ErrorDefinitionAlreadyApproved
 	}
 
-	err = ApproveSource(definition.Source, userId)
-
-	if err != nil && err != constants.ErrorSourceAlreadyApproved {
-		return err
-	}
+	now := time.Now()
 
-	filter := bson.M{"_id": id}
+	// concurrent approvals of the definition conflict on it, the repeated one finds it approved
+	filter := bson.M{"_id": id, "approved": false}
 	update := bson.M{
 		"$set": bson.M{
 			"approved_by":   userId,
-			"approved_date": time.Now(),
+			"approved_date": now,
 			"approved":      true,
 		},
 	}
 
-	var result bson.M
-	updateError := definitionsCollection.FindOneAndUpdate(dbContext, filter, update, nil).Decode(&result)
-	// TODO: send email to user
+	// the sources and their authors are approved in the same transaction as the definition
+	return withTransaction(func(ctx mongo.SessionContext) error {
+
+		// another translation into the same language could have been approved after this one was submitted
+		if definition.IsTranslation() {
+
+			// written, so approvals of other translations of the original conflict with this one
+			result, err := definitionsCollection.UpdateOne(ctx, bson.M{"_id": *definition.TranslationOf}, bson.M{"$set": bson.M{"last_translation_date": now}})
+
+			if err != nil {
+				return err
+			}
+
+			if result.MatchedCount == 0 {
+				return constants.ErrorDefinitionNotFound
+			}
+
+			err = validateTranslationLanguage(ctx, *definition.TranslationOf, definition.ID, definition.Language)
+
+			if err != nil {
+				return err
+			}
 
-	if updateError != nil {
-		if updateError == mongo.ErrNoDocuments {
-			return constants.ErrorDefinitionNotFound
 		}
-		return updateError
-	}
 
-	return nil
+		for _, sourceId := range definition.GetSourceIds() {
+
+			err := ApproveSource(ctx, sourceId, userId)
+
+			if err != nil && err != constants.ErrorSourceAlreadyApproved {
+				return err
+			}
+
+		}
+
+		var result bson.M
+		updateError := definitionsCollection.FindOneAndUpdate(ctx, filter, update, nil).Decode(&result)
+
+		if updateError != nil {
+			if updateError == mongo.ErrNoDocuments {
+				return constants.ErrorDefinitionAlreadyApproved
+			}
+			return updateError
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionApproved, definition.ID, userId, nil)
+
+	})
 
 }
 
@@ -303,17 +767,20 @@ func RejectDefinition(definitionId string, content string, userId string) error
 		},
 	}
 
-	result := definitionsCollection.FindOneAndUpdate(dbContext, filter, update, nil)
-	// TODO: send email to user
+	return withTransaction(func(ctx mongo.SessionContext) error {
 
-	if result.Err() != nil {
-		if result.Err() == mongo.ErrNoDocuments {
-			return constants.ErrorDefinitionNotFound
+		result := definitionsCollection.FindOneAndUpdate(ctx, filter, update, nil)
+
+		if result.Err() != nil {
+			if result.Err() == mongo.ErrNoDocuments {
+				return constants.ErrorDefinitionNotFound
+			}
+			return result.Err()
 		}
-		return result.Err()
-	}
 
-	return nil
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionRejected, definition.ID, userId, map[string]string{"rejectionId": rejection.ID.Hex()})
+
+	})
 
 }
 
@@ -339,12 +806,34 @@ func ChangeDefinition(request *types.ChangeDefinitionRequest, userId string) err
 		return constants.ErrorDefinitionBelongsToAnotherUser
 	}
 
+	if definition.IsTranslation() {
+
+		if request.SourceId != nil || request.AdditionalSources != nil || request.Locator != nil || request.RemoveLocator != nil || request.Categories != nil || request.Tags != nil {
+			return constants.ErrorTranslationFieldOfOriginal
+		}
+
+		if request.Language != nil {
+
+			err = validateTranslationLanguage(dbContext, *definition.TranslationOf, definition.ID, *request.Language)
+
+			if err != nil {
+				return err
+			}
+
+		}
+
+	}
+
 	filter := bson.M{"_id": id}
 
 	var updateEntries bson.D
 
 	if request.Content != nil {
+
+		// the slug is updated in the transaction below
+		definition.Content = *request.Content
 		updateEntries = append(updateEntries, bson.E{Key: "content", Value: request.Content})
+
 	}
 	if request.SourceId != nil {
 
@@ -360,27 +849,110 @@ func ChangeDefinition(request *types.ChangeDefinitionRequest, userId string) err
 			return sourceExistsError
 		}
 
+		// the additional sources are checked against the new primary source
+		if request.AdditionalSources == nil {
+			for _, reference := range definition.AdditionalSources {
+				if reference.Source == sourceId {
+					return constants.ErrorDuplicateSourceReference
+				}
+			}
+		}
+
+		definition.Source = sourceId
 		updateEntries = append(updateEntries, bson.E{Key: "source", Value: sourceId})
 
 	}
 
-	if request.Category != nil {
-		updateEntries = append(updateEntries, bson.E{Key: "category", Value: request.Category})
+	if request.AdditionalSources != nil {
+
+		references, err := createSourceReferences(*request.AdditionalSources, definition.Source)
+
+		if err != nil {
+			return err
+		}
+
+		definition.AdditionalSources = references
+		updateEntries = append(updateEntries, bson.E{Key: "additional_sources", Value: references})
+
+	}
+
+	if request.Locator != nil {
+		updateEntries = append(updateEntries, bson.E{Key: "locator", Value: request.Locator})
+	} else if request.RemoveLocator != nil && *request.RemoveLocator {
+		updateEntries = append(updateEntries, bson.E{Key: "locator", Value: nil})
 	}
 
-	if len(updateEntries) > 0 {
+	if request.Paraphrase != nil {
+		updateEntries = append(updateEntries, bson.E{Key: "paraphrase", Value: *request.Paraphrase})
+	}
 
-		updateEntries = append(updateEntries, bson.E{Key: "last_change_date", Value: time.Now()})
-		update := bson.M{"$set": updateEntries}
+	if request.Categories != nil {
+		updateEntries = append(updateEntries, bson.E{Key: "categories", Value: request.Categories})
+	}
 
-		result := definitionsCollection.FindOneAndUpdate(dbContext, filter, update, nil)
+	if request.Language != nil {
+		updateEntries = append(updateEntries, bson.E{Key: "language", Value: request.Language})
+	}
+
+	if len(updateEntries) > 0 || request.Tags != nil {
+
+		now := time.Now()
+		updateEntries = append(updateEntries, bson.E{Key: "last_change_date", Value: now})
+
+		return withTransaction(func(ctx mongo.SessionContext) error {
+
+			// the transaction can be repeated, so the entries of this attempt are collected in a copy
+			setEntries := append(bson.D{}, updateEntries...)
+
+			if request.Tags != nil {
+
+				tagIds, err := proposeTags(ctx, *request.Tags, userId)
+
+				if err != nil {
+					return err
+				}
+
+				setEntries = append(setEntries, bson.E{Key: "tags", Value: tagIds})
 
-		if result.Err() != nil {
-			if result.Err() == mongo.ErrNoDocuments {
-				return constants.ErrorDefinitionNotFound
 			}
-			return result.Err()
-		}
+
+			if request.Content != nil {
+
+				slugId, err := updateSlug(ctx, types.EnumSlugEntity.Definition, definition.ID, definition.SlugId, getDefinitionSlugText(definition))
+
+				if err != nil {
+					return err
+				}
+
+				setEntries = append(setEntries, bson.E{Key: "slug_id", Value: slugId})
+
+			}
+
+			if request.SourceId != nil || request.AdditionalSources != nil {
+
+				err := touchReferencedSources(ctx, definition.GetSourceIds(), now)
+
+				if err != nil {
+					return err
+				}
+
+			}
+
+			update := bson.M{"$set": setEntries}
+
+			result := definitionsCollection.FindOneAndUpdate(ctx, filter, update, nil)
+
+			if result.Err() != nil {
+				if result.Err() == mongo.ErrNoDocuments {
+					return constants.ErrorDefinitionNotFound
+				}
+				return result.Err()
+			}
+
+			return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionChanged, id, userId, nil)
+
+		})
+
 	}
 
 	return nil
@@ -409,7 +981,7 @@ func GetDefinitionByObjectId(id primitive.ObjectID) (*types.Definition, error) {
 
 func GetNewestDefinitions(limit int) ([]*types.Definition, error) {
 
-	options := options.Find().SetSort(bson.M{"creation_date": -1}).SetLimit(int64(limit))
+	options := options.Find().SetSort(bson.D{{Key: "approved_date", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
 	return getDocuments[types.Definition](definitionsCollection, bson.M{"approved": true}, options)
 
 }
@@ -422,7 +994,7 @@ func GetDefinitions(request *types.DefinitionPageRequest) ([]*types.Definition,
 
 	options := options.AggregateOptions{}
 
-	filter, err := CreateDefinitonFilterQuery(int64(request.Page), int64(request.PageSize), request.Filter)
+	filter, err := CreateDefinitonFilterQuery(int64(request.Page), int64(request.PageSize), request.Filter, request.Sort)
 
 	if err != nil {
 		return nil, err
@@ -432,83 +1004,36 @@ func GetDefinitions(request *types.DefinitionPageRequest) ([]*types.Definition,
 
 }
 
-func CreateDefinitonFilterQuery(page int64, pageSize int64, filter *types.DefinitionFilter) (*bson.A, error) {
+/* Returns the page after or before the cursor of the request, see getCursorPage */
+func GetDefinitionCursorPage(request *types.DefinitionCursorRequest) (*types.CursorPage[types.Definition], error) {
 
-	pipeline := bson.A{}
+	filterStages, err := createDefinitionFilterStages(request.Filter)
 
-	if filter == nil {
-		return &pipeline, nil
+	if err != nil {
+		return nil, err
 	}
 
-	matchStage := bson.D{}
-
-	textSearch := ""
-	if filter.Content != nil && len(*filter.Content) > 0 {
-		textSearch = *filter.Content
-	}
+	return getCursorPage[types.Definition](definitionsCollection, sortDefinitions, filterStages, request.Filter, isDefinitionTextSearch(request.Filter), &request.CursorRequest)
 
-	if len(textSearch) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "$text", Value: bson.M{"$search": textSearch}})
-	}
+}
 
-	if filter.Categories != nil && len(*filter.Categories) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "category", Value: bson.M{"$in": *filter.Categories}})
-	}
+func CreateDefinitonFilterQuery(page int64, pageSize int64, filter *types.DefinitionFilter, sort *types.SortRequest) (*bson.A, error) {
 
-	if filter.Approved != nil {
-		matchStage = append(matchStage, bson.E{Key: "approved", Value: *filter.Approved})
-	}
+	pipeline, err := createDefinitionFilterStages(filter)
 
-	if filter.UserId != nil && len(*filter.UserId) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "submitted_by", Value: *filter.UserId})
+	if err != nil {
+		return nil, err
 	}
 
-	pipeline = append(pipeline, bson.D{
-		{Key: "$match", Value: matchStage},
-	})
-
-	authors, err := stringsToObjectIDs(filter.AuthorIds)
-	if filter.AuthorIds != nil && len(*filter.AuthorIds) > 0 {
-
-		if err != nil {
-			return nil, err
-		}
-
-		// If we lookup on the source, the orignal source (which is just the ID) will be replaced
-		// with the entire document. To avoid this we will save the original value here and
-		// replace with after lookup.
-		pipeline = append(pipeline, bson.D{
-			{Key: "$addFields", Value: bson.D{
-				{Key: "original_source", Value: "$source"},
-			}},
-		})
-
-		lookup := bson.D{
-			{Key: "from", Value: "sources"},
-			{Key: "localField", Value: "source"},
-			{Key: "foreignField", Value: "_id"},
-			{Key: "as", Value: "source"},
-		}
-
-		pipeline = append(pipeline, bson.D{{Key: "$lookup", Value: lookup}})
-		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "source.authors", Value: bson.D{{Key: "$in", Value: authors}}}}}})
-
-		// Replace modified source with original source
-		pipeline = append(pipeline, bson.D{
-			{Key: "$addFields", Value: bson.D{
-				{Key: "source", Value: "$original_source"},
-			}},
-		})
-
-		// Remove original source
-		pipeline = append(pipeline, bson.D{
-			{Key: "$project", Value: bson.D{
-				{Key: "original_source", Value: 0},
-			}},
-		})
+	sortKeyStages, sortStage, err := createSortStages(sortDefinitions, sort, isDefinitionTextSearch(filter))
 
+	if err != nil {
+		return nil, err
 	}
 
+	pipeline = append(pipeline, sortKeyStages...)
+	pipeline = append(pipeline, sortStage)
+
 	if page > 0 && pageSize > 0 {
 		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: int64((page - 1) * pageSize)}})
 	}
@@ -548,21 +1073,18 @@ func GetDefinitionCountInCurrentQuarter() (int64, error) {
 
 }
 
-func CountDefinitionsWithSource(id primitive.ObjectID) (int, error) {
-
-	filter := bson.M{
-		"source": id,
-	}
-
-	return countDocuments(definitionsCollection, filter, nil)
+/* Matches definitions using the source as primary or additional source */
+func createSourceReferenceFilter(id primitive.ObjectID) bson.M {
+	return bson.M{"$or": bson.A{bson.M{"source": id}, bson.M{"additional_sources.source": id}}}
+}
 
+func CountDefinitionsWithSource(id primitive.ObjectID) (int, error) {
+	return countDocuments(definitionsCollection, createSourceReferenceFilter(id), nil)
 }
 
 func GetDefinitionsWithSource(id primitive.ObjectID) (*[]*types.Definition, error) {
 
-	filter := bson.M{
-		"source": id,
-	}
+	filter := createSourceReferenceFilter(id)
 
 	options := options.FindOptions{}
 	definitions, err := getDocuments[types.Definition](definitionsCollection, filter, &options)
@@ -575,29 +1097,99 @@ func GetDefinitionsWithSource(id primitive.ObjectID) (*[]*types.Definition, erro
 
 }
 
-func DeleteDefinition(definitionId string) error {
+/* Originals with translations can not be deleted, the IDs of the translations are returned in this case */
+func DeleteDefinition(definitionId string, userId string) (*[]string, error) {
 
 	id, err := primitive.ObjectIDFromHex(definitionId)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	filter := bson.M{
 		"_id": id,
 	}
 
-	result, err := definitionsCollection.DeleteOne(dbContext, filter)
+	var translationIds []string
 
-	if err != nil {
-		return err
-	}
+	err = withTransaction(func(ctx mongo.SessionContext) error {
 
-	if result.DeletedCount == 0 {
-		return constants.ErrorDefinitionNotFound
+		// checked in the transaction, a translation submitted concurrently conflicts on the original
+		cursor, err := definitionsCollection.Find(ctx, bson.M{"translation_of": id}, options.Find().SetProjection(bson.M{"_id": 1}))
+
+		if err != nil {
+			return err
+		}
+
+		translations := []types.Definition{}
+		err = cursor.All(ctx, &translations)
+
+		if err != nil {
+			return err
+		}
+
+		if len(translations) > 0 {
+
+			translationIds = []string{}
+
+			for _, translation := range translations {
+				translationIds = append(translationIds, translation.ID.Hex())
+			}
+
+			return constants.ErrorDefinitionDeletionBecauseTranslated
+
+		}
+
+		result, err := definitionsCollection.DeleteOne(ctx, filter)
+
+		if err != nil {
+			return err
+		}
+
+		if result.DeletedCount == 0 {
+			return constants.ErrorDefinitionNotFound
+		}
+
+		// everything belonging to the definition is deleted in the same transaction
+		err = deleteDefinitionComments(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = deleteDefinitionRatings(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = removeDefinitionFromCollections(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = deleteModeratorNotes(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = deleteSlugs(ctx, types.EnumSlugEntity.Definition, id)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionDeleted, id, userId, nil)
+
+	})
+
+	if err == constants.ErrorDefinitionDeletionBecauseTranslated {
+		return &translationIds, err
 	}
 
-	return nil
+	return nil, err
 
 }
 
@@ -647,56 +1239,10 @@ func GetDefinitionPageCount(request *types.DefinitionPageCountRequest) (int64, e
 
 func CreateDefinitionCountFilter(filter *types.DefinitionFilter) (*bson.A, error) {
 
-	pipeline := bson.A{}
-
-	if filter == nil {
-		return &pipeline, nil
-	}
-
-	matchStage := bson.D{}
-
-	textSearch := ""
-	if filter.Content != nil && len(*filter.Content) > 0 {
-		textSearch = *filter.Content
-	}
-
-	if len(textSearch) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "$text", Value: bson.M{"$search": textSearch}})
-	}
-
-	if filter.Categories != nil && len(*filter.Categories) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "category", Value: bson.M{"$in": *filter.Categories}})
-	}
-
-	if filter.Approved != nil {
-		matchStage = append(matchStage, bson.E{Key: "approved", Value: *filter.Approved})
-	}
-
-	if filter.UserId != nil && len(*filter.UserId) > 0 {
-		matchStage = append(matchStage, bson.E{Key: "submitted_by", Value: *filter.UserId})
-	}
-
-	pipeline = append(pipeline, bson.D{
-		{Key: "$match", Value: matchStage},
-	})
-
-	authors, err := stringsToObjectIDs(filter.AuthorIds)
-	if filter.AuthorIds != nil && len(*filter.AuthorIds) > 0 {
-
-		if err != nil {
-			return nil, err
-		}
-
-		lookup := bson.D{
-			{Key: "from", Value: "sources"},
-			{Key: "localField", Value: "source"},
-			{Key: "foreignField", Value: "_id"},
-			{Key: "as", Value: "source"},
-		}
-
-		pipeline = append(pipeline, bson.D{{Key: "$lookup", Value: lookup}})
-		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "source.authors", Value: bson.D{{Key: "$in", Value: authors}}}}}})
+	pipeline, err := createDefinitionFilterStages(filter)
 
+	if err != nil {
+		return nil, err
 	}
 
 	pipeline = append(pipeline, bson.D{
@@ -714,3 +1260,38 @@ func CreateDefinitionCountFilter(filter *types.DefinitionFilter) (*bson.A, error
 	return &pipeline, nil
 
 }
+
+func createTranslationIndex() error {
+
+	_, err := definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "translation_of", Value: 1}}, Options: options.Index().SetName("translation_of").SetSparse(true),
+	})
+
+	return err
+
+}
+
+func dropTranslationIndex() error {
+
+	_, err := definitionsCollection.Indexes().DropOne(dbContext, "translation_of")
+	return err
+
+}
+
+/* Used by the in-use check of sources */
+func createAdditionalSourceIndex() error {
+
+	_, err := definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "additional_sources.source", Value: 1}}, Options: options.Index().SetName("additional_sources"),
+	})
+
+	return err
+
+}
+
+func dropAdditionalSourceIndex() error {
+
+	_, err := definitionsCollection.Indexes().DropOne(dbContext, "additional_sources")
+	return err
+
+}
@@ -0,0 +1,842 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dumpManifestFile = "manifest.json"
+const dumpMaxLineSize = 16 * 1024 * 1024 // max BSON document size
+const restoreBatchSize = 500
+
+// Only the fields needed to check the references between the collections
+type dumpReference struct {
+	ID                primitive.ObjectID   `bson:"_id"`
+	Source            primitive.ObjectID   `bson:"source"`
+	Authors           []primitive.ObjectID `bson:"authors"`
+	Tags              []primitive.ObjectID `bson:"tags"`
+	TranslationOf     *primitive.ObjectID  `bson:"translation_of"`
+	AdditionalSources []struct {
+		Source primitive.ObjectID `bson:"source"`
+	} `bson:"additional_sources"`
+	RejectionLog []bson.Raw `bson:"rejection_log"`
+	// categories are referenced by their key
+	Key        string   `bson:"key"`
+	Parent     *string  `bson:"parent"`
+	Categories []string `bson:"categories"`
+	Category   *string  `bson:"category"`
+}
+
+type dumpCollectionInfo struct {
+	name       string
+	collection *mongo.Collection
+	// dumps created before the collection existed do not contain it
+	optional bool
+	// restored documents replace the document with the same value of this field instead of the same ID
+	matchField string
+}
+
+func getDumpCollections() []dumpCollectionInfo {
+	// order matters for restoring: referenced documents are written first
+	return []dumpCollectionInfo{
+		{name: "categories", collection: categoriesCollection, optional: true, matchField: "key"},
+		{name: "tags", collection: tagsCollection, optional: true},
+		{name: "authors", collection: authorsCollection},
+		{name: "sources", collection: sourcesCollection},
+		{name: "definitions", collection: definitionsCollection},
+	}
+}
+
+func createDumpStatusFilter(status types.DumpStatus) bson.D {
+
+	switch status {
+	case types.EnumDumpStatus.Approved:
+		return bson.D{{Key: "approved", Value: true}}
+	case types.EnumDumpStatus.Unapproved:
+		return bson.D{{Key: "approved", Value: false}}
+	}
+
+	return bson.D{}
+
+}
+
+/* Adds the referenced documents to the filter, so a filtered dump never contains dangling references. */
+func createDumpReferenceFilter(status types.DumpStatus, references map[primitive.ObjectID]bool) bson.D {
+
+	if status == types.EnumDumpStatus.All {
+		return bson.D{}
+	}
+
+	ids := []primitive.ObjectID{}
+	for id := range references {
+		ids = append(ids, id)
+	}
+
+	return bson.D{{Key: "$or", Value: bson.A{
+		createDumpStatusFilter(status),
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+	}}}
+
+}
+
+/*
+Starts a snapshot session, so all collections of a dump are read at the same point in time and their references
+match. Snapshots require a replica set, a standalone MongoDB (without concurrent writes) is read without one.
+*/
+func startDumpSnapshot() (context.Context, func(), error) {
+
+	err := CheckTransactionSupport()
+
+	if err == constants.ErrorReplicaSetRequired {
+		return dbContext, func() {}, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := client.StartSession(options.Session().SetSnapshot(true))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mongo.NewSessionContext(dbContext, session), func() { session.EndSession(dbContext) }, nil
+
+}
+
+/* Writes the definitions, sources, authors, tags and categories as JSON Lines (canonical extended JSON) into the directory. */
+func DumpDatabase(dumpOptions *types.DumpOptions) (*types.DumpManifest, error) {
+
+	if dumpOptions.Status == "" {
+		dumpOptions.Status = types.EnumDumpStatus.All
+	}
+
+	err := os.MkdirAll(dumpOptions.Directory, 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, endSnapshot, err := startDumpSnapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer endSnapshot()
+
+	manifest := types.DumpManifest{
+		Version:   types.DumpFormatVersion,
+		CreatedAt: time.Now(),
+		Status:    dumpOptions.Status,
+	}
+
+	// the originals of the translations are dumped with them, originals are never translations themselves
+	originalIds, err := definitionsCollection.Distinct(ctx, "translation_of", bson.D{
+		{Key: "$and", Value: bson.A{createDumpStatusFilter(dumpOptions.Status), bson.D{{Key: "translation_of", Value: bson.D{{Key: "$ne", Value: nil}}}}}},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	referencedOriginals := map[primitive.ObjectID]bool{}
+
+	for _, originalId := range originalIds {
+		if id, ok := originalId.(primitive.ObjectID); ok {
+			referencedOriginals[id] = true
+		}
+	}
+
+	// definitions are dumped first, because they decide which sources, authors and tags are needed
+	referencedSources := map[primitive.ObjectID]bool{}
+	referencedTags := map[primitive.ObjectID]bool{}
+	rejectionCount := 0
+
+	definitionsEntry, err := dumpCollection(ctx, definitionsCollection, "definitions", dumpOptions.Directory, createDumpReferenceFilter(dumpOptions.Status, referencedOriginals), func(raw bson.Raw) error {
+
+		var reference dumpReference
+		err := bson.Unmarshal(raw, &reference)
+
+		if err != nil {
+			return err
+		}
+
+		referencedSources[reference.Source] = true
+
+		for _, additionalSource := range reference.AdditionalSources {
+			referencedSources[additionalSource.Source] = true
+		}
+		rejectionCount += len(reference.RejectionLog)
+
+		for _, tagId := range reference.Tags {
+			referencedTags[tagId] = true
+		}
+
+		return nil
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	definitionsEntry.RejectionCount = &rejectionCount
+
+	referencedAuthors := map[primitive.ObjectID]bool{}
+
+	sourcesEntry, err := dumpCollection(ctx, sourcesCollection, "sources", dumpOptions.Directory, createDumpReferenceFilter(dumpOptions.Status, referencedSources), func(raw bson.Raw) error {
+
+		var reference dumpReference
+		err := bson.Unmarshal(raw, &reference)
+
+		if err != nil {
+			return err
+		}
+
+		for _, authorId := range reference.Authors {
+			referencedAuthors[authorId] = true
+		}
+
+		return nil
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	authorsEntry, err := dumpCollection(ctx, authorsCollection, "authors", dumpOptions.Directory, createDumpReferenceFilter(dumpOptions.Status, referencedAuthors), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tagsEntry, err := dumpCollection(ctx, tagsCollection, "tags", dumpOptions.Directory, createDumpReferenceFilter(dumpOptions.Status, referencedTags), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the taxonomy is small and needed by every definition, so it is always dumped completely
+	categoriesEntry, err := dumpCollection(ctx, categoriesCollection, "categories", dumpOptions.Directory, bson.D{}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.Files = []types.DumpManifestEntry{*categoriesEntry, *tagsEntry, *authorsEntry, *sourcesEntry, *definitionsEntry}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(filepath.Join(dumpOptions.Directory, dumpManifestFile), manifestBytes, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+
+}
+
+func dumpCollection(ctx context.Context, collection *mongo.Collection, name string, directory string, filter bson.D, onDocument func(raw bson.Raw) error) (*types.DumpManifestEntry, error) {
+
+	fileName := name + ".jsonl"
+
+	file, err := os.Create(filepath.Join(directory, fileName))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(file, hash))
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, findOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	count := 0
+
+	for cursor.Next(ctx) {
+
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = writer.Write(append(line, '\n'))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if onDocument != nil {
+			err = onDocument(cursor.Current)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		count++
+
+	}
+
+	if cursor.Err() != nil {
+		return nil, cursor.Err()
+	}
+
+	err = writer.Flush()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.DumpManifestEntry{
+		Collection: name,
+		File:       fileName,
+		Count:      count,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+
+}
+
+func readDumpManifest(directory string) (*types.DumpManifest, error) {
+
+	manifestBytes, err := os.ReadFile(filepath.Join(directory, dumpManifestFile))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest types.DumpManifest
+	err = json.Unmarshal(manifestBytes, &manifest)
+
+	if err != nil {
+		return nil, constants.ErrorDumpManifestInvalid
+	}
+
+	if manifest.Version != types.DumpFormatVersion {
+		return nil, constants.ErrorDumpUnsupportedVersion
+	}
+
+	for _, info := range getDumpCollections() {
+		if manifest.GetEntry(info.name) == nil && !info.optional {
+			return nil, constants.ErrorDumpManifestInvalid
+		}
+	}
+
+	return &manifest, nil
+
+}
+
+/* Streams the documents of a dump file and verifies the count and checksum from the manifest afterwards. */
+func readDumpFile(directory string, entry *types.DumpManifestEntry, onDocument func(raw bson.Raw) error) error {
+
+	file, err := os.Open(filepath.Join(directory, filepath.Base(entry.File)))
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(file, hash))
+	scanner.Buffer(make([]byte, 0, 64*1024), dumpMaxLineSize)
+
+	count := 0
+
+	for scanner.Scan() {
+
+		line := scanner.Bytes()
+
+		if len(line) == 0 {
+			continue
+		}
+
+		var document bson.D
+		err := bson.UnmarshalExtJSON(line, true, &document)
+
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", entry.File, count+1, err)
+		}
+
+		raw, err := bson.Marshal(document)
+
+		if err != nil {
+			return err
+		}
+
+		err = onDocument(raw)
+
+		if err != nil {
+			return err
+		}
+
+		count++
+
+	}
+
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+
+	if count != entry.Count {
+		return constants.ErrorDumpCountMismatch
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != entry.SHA256 {
+		return constants.ErrorDumpChecksumMismatch
+	}
+
+	return nil
+
+}
+
+/*
+Validates all references of the dump before anything is written. References may point to
+documents inside the dump or to documents already existing in the database.
+*/
+func validateDump(directory string, manifest *types.DumpManifest, report *types.RestoreReport) error {
+
+	categoryKeys := map[string]bool{}
+	tagIds := map[primitive.ObjectID]bool{}
+	authorIds := map[primitive.ObjectID]bool{}
+	sourceIds := map[primitive.ObjectID]bool{}
+	definitionIds := map[primitive.ObjectID]bool{}
+
+	// referenced id or key -> documents referencing it
+	missingCategories := map[string][]string{}
+	missingTags := map[primitive.ObjectID][]string{}
+	missingOriginals := map[primitive.ObjectID][]string{}
+	missingAuthors := map[primitive.ObjectID][]string{}
+	missingSources := map[primitive.ObjectID][]string{}
+
+	if categoriesEntry := manifest.GetEntry("categories"); categoriesEntry != nil {
+
+		err := readDumpFile(directory, categoriesEntry, func(raw bson.Raw) error {
+
+			var reference dumpReference
+			err := bson.Unmarshal(raw, &reference)
+
+			if err != nil {
+				return err
+			}
+
+			if categoryKeys[reference.Key] {
+				report.Issues = append(report.Issues, fmt.Sprintf("category %s exists more than once", reference.Key))
+			}
+
+			categoryKeys[reference.Key] = true
+
+			// the parent can follow its children in the file, so it is checked after reading all categories
+			if reference.Parent != nil {
+				missingCategories[*reference.Parent] = append(missingCategories[*reference.Parent], "category "+reference.Key)
+			}
+
+			report.Categories++
+			return nil
+
+		})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	if tagsEntry := manifest.GetEntry("tags"); tagsEntry != nil {
+
+		err := readDumpFile(directory, tagsEntry, func(raw bson.Raw) error {
+
+			var reference dumpReference
+			err := bson.Unmarshal(raw, &reference)
+
+			if err != nil {
+				return err
+			}
+
+			if tagIds[reference.ID] {
+				report.Issues = append(report.Issues, fmt.Sprintf("tag %s exists more than once", reference.ID.Hex()))
+			}
+
+			tagIds[reference.ID] = true
+			report.Tags++
+			return nil
+
+		})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	err := readDumpFile(directory, manifest.GetEntry("authors"), func(raw bson.Raw) error {
+
+		var reference dumpReference
+		err := bson.Unmarshal(raw, &reference)
+
+		if err != nil {
+			return err
+		}
+
+		if authorIds[reference.ID] {
+			report.Issues = append(report.Issues, fmt.Sprintf("author %s exists more than once", reference.ID.Hex()))
+		}
+
+		authorIds[reference.ID] = true
+		report.Authors++
+		return nil
+
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = readDumpFile(directory, manifest.GetEntry("sources"), func(raw bson.Raw) error {
+
+		var reference dumpReference
+		err := bson.Unmarshal(raw, &reference)
+
+		if err != nil {
+			return err
+		}
+
+		if sourceIds[reference.ID] {
+			report.Issues = append(report.Issues, fmt.Sprintf("source %s exists more than once", reference.ID.Hex()))
+		}
+
+		sourceIds[reference.ID] = true
+
+		for _, authorId := range reference.Authors {
+			if !authorIds[authorId] {
+				missingAuthors[authorId] = append(missingAuthors[authorId], "source "+reference.ID.Hex())
+			}
+		}
+
+		report.Sources++
+		return nil
+
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = readDumpFile(directory, manifest.GetEntry("definitions"), func(raw bson.Raw) error {
+
+		var reference dumpReference
+		err := bson.Unmarshal(raw, &reference)
+
+		if err != nil {
+			return err
+		}
+
+		if definitionIds[reference.ID] {
+			report.Issues = append(report.Issues, fmt.Sprintf("definition %s exists more than once", reference.ID.Hex()))
+		}
+
+		definitionIds[reference.ID] = true
+
+		if !sourceIds[reference.Source] {
+			missingSources[reference.Source] = append(missingSources[reference.Source], "definition "+reference.ID.Hex())
+		}
+
+		for _, additionalSource := range reference.AdditionalSources {
+			if !sourceIds[additionalSource.Source] {
+				missingSources[additionalSource.Source] = append(missingSources[additionalSource.Source], "definition "+reference.ID.Hex())
+			}
+		}
+
+		for _, tagId := range reference.Tags {
+			if !tagIds[tagId] {
+				missingTags[tagId] = append(missingTags[tagId], "definition "+reference.ID.Hex())
+			}
+		}
+
+		// dumps of older versions contain the single "category", which is converted after restoring
+		categories := reference.Categories
+		if reference.Category != nil {
+			categories = append(categories, *reference.Category)
+		}
+
+		for _, category := range categories {
+			missingCategories[category] = append(missingCategories[category], "definition "+reference.ID.Hex())
+		}
+
+		// the original can follow its translations in the file, so it is checked after reading all definitions
+		if reference.TranslationOf != nil {
+			missingOriginals[*reference.TranslationOf] = append(missingOriginals[*reference.TranslationOf], "definition "+reference.ID.Hex())
+		}
+
+		report.Definitions++
+		report.Rejections += len(reference.RejectionLog)
+		return nil
+
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for id := range definitionIds {
+		delete(missingOriginals, id)
+	}
+
+	for key := range categoryKeys {
+		delete(missingCategories, key)
+	}
+
+	err = reportMissingCategories(missingCategories, report)
+
+	if err != nil {
+		return err
+	}
+
+	err = reportMissingReferences(definitionsCollection, "definition", missingOriginals, report)
+
+	if err != nil {
+		return err
+	}
+
+	err = reportMissingReferences(tagsCollection, "tag", missingTags, report)
+
+	if err != nil {
+		return err
+	}
+
+	err = reportMissingReferences(authorsCollection, "author", missingAuthors, report)
+
+	if err != nil {
+		return err
+	}
+
+	return reportMissingReferences(sourcesCollection, "source", missingSources, report)
+
+}
+
+func reportMissingReferences(collection *mongo.Collection, name string, missing map[primitive.ObjectID][]string, report *types.RestoreReport) error {
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	ids := []primitive.ObjectID{}
+	for id := range missing {
+		ids = append(ids, id)
+	}
+
+	existing, err := getDocuments[dumpReference](collection, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+
+	if err != nil {
+		return err
+	}
+
+	for _, document := range existing {
+		delete(missing, document.ID)
+	}
+
+	for id, referencedBy := range missing {
+		for _, document := range referencedBy {
+			report.Issues = append(report.Issues, fmt.Sprintf("%s references missing %s %s", document, name, id.Hex()))
+		}
+	}
+
+	return nil
+
+}
+
+/* Like reportMissingReferences, but categories are referenced by their key */
+func reportMissingCategories(missing map[string][]string, report *types.RestoreReport) error {
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	keys := []string{}
+	for key := range missing {
+		keys = append(keys, key)
+	}
+
+	existing, err := getDocuments[dumpReference](categoriesCollection, bson.M{"key": bson.M{"$in": keys}}, options.Find().SetProjection(bson.M{"key": 1}))
+
+	if err != nil {
+		return err
+	}
+
+	for _, document := range existing {
+		delete(missing, document.Key)
+	}
+
+	for key, referencedBy := range missing {
+		for _, document := range referencedBy {
+			report.Issues = append(report.Issues, fmt.Sprintf("%s references missing category %s", document, key))
+		}
+	}
+
+	return nil
+
+}
+
+/*
+Restores a dump created by DumpDatabase. Existing documents with the same ID are replaced. The documents are written
+in batches without a transaction, which could not hold large dumps. Categories replace the category with the same key,
+because every database created its default categories with its own IDs. If writing fails, the documents written so far
+stay in the database. Writing is idempotent, so running the restore again completes it.
+*/
+func RestoreDatabase(restoreOptions *types.RestoreOptions) (*types.RestoreReport, error) {
+
+	manifest, err := readDumpManifest(restoreOptions.Directory)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := types.RestoreReport{Issues: []string{}}
+
+	err = validateDump(restoreOptions.Directory, manifest, &report)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Issues) > 0 {
+		return &report, constants.ErrorRestoreValidation
+	}
+
+	if restoreOptions.DryRun {
+		return &report, nil
+	}
+
+	for _, info := range getDumpCollections() {
+
+		if manifest.GetEntry(info.name) == nil {
+			continue
+		}
+
+		err = restoreCollection(restoreOptions.Directory, manifest.GetEntry(info.name), info.collection, info.matchField)
+
+		if err != nil {
+			return &report, err
+		}
+
+	}
+
+	invalidateCategoryCache()
+
+	// dumps of older versions contain the single "category" of definitions
+	err = convertDefinitionCategories()
+
+	if err != nil {
+		return &report, err
+	}
+
+	// imported slugs are registered, missing or colliding ones are replaced
+	err = syncSlugs()
+
+	if err != nil {
+		return &report, err
+	}
+
+	report.Written = true
+	return &report, nil
+
+}
+
+func restoreCollection(directory string, entry *types.DumpManifestEntry, collection *mongo.Collection, matchField string) error {
+
+	models := []mongo.WriteModel{}
+
+	flush := func() error {
+
+		if len(models) == 0 {
+			return nil
+		}
+
+		_, err := collection.BulkWrite(dbContext, models, options.BulkWrite().SetOrdered(false))
+		models = []mongo.WriteModel{}
+		return err
+
+	}
+
+	err := readDumpFile(directory, entry, func(raw bson.Raw) error {
+
+		id := raw.Lookup("_id")
+
+		if len(matchField) == 0 {
+
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.D{{Key: "_id", Value: id}}).
+				SetReplacement(raw).
+				SetUpsert(true))
+
+		} else {
+
+			// the ID of an existing document can not be replaced, so it is only set for new documents
+			elements, err := raw.Elements()
+
+			if err != nil {
+				return err
+			}
+
+			fields := bson.D{}
+			for _, element := range elements {
+				if element.Key() != "_id" {
+					fields = append(fields, bson.E{Key: element.Key(), Value: element.Value()})
+				}
+			}
+
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: matchField, Value: raw.Lookup(matchField)}}).
+				SetUpdate(bson.D{{Key: "$set", Value: fields}, {Key: "$setOnInsert", Value: bson.D{{Key: "_id", Value: id}}}}).
+				SetUpsert(true))
+
+		}
+
+		if len(models) >= restoreBatchSize {
+			return flush()
+		}
+
+		return nil
+
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return flush()
+
+}
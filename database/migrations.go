@@ -0,0 +1,545 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type migration struct {
+	version int
+	name    string
+	up      func() error
+	down    func() error
+}
+
+/* Every migration needs a unique and increasing version. Applied migrations must never be changed. */
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_filter_indexes",
+		up:      createFilterIndexes,
+		down:    dropFilterIndexes,
+	},
+	{
+		version: 2,
+		name:    "create_suggest_keys",
+		up:      createSuggestKeys,
+		down:    dropSuggestKeys,
+	},
+	{
+		version: 3,
+		name:    "assign_slugs",
+		up:      syncSlugs,
+		down:    dropSlugs,
+	},
+	{
+		version: 4,
+		name:    "set_default_language",
+		up:      setDefaultLanguage,
+		down:    unsetLanguage,
+	},
+	{
+		version: 5,
+		name:    "create_saved_search_indexes",
+		up:      createSavedSearchIndexes,
+		down:    dropSavedSearchIndexes,
+	},
+	{
+		version: 6,
+		name:    "create_definition_tag_index",
+		up:      createDefinitionTagIndex,
+		down:    dropDefinitionTagIndex,
+	},
+	{
+		version: 7,
+		name:    "create_categories",
+		up:      createCategories,
+		down:    dropCategories,
+	},
+	{
+		version: 8,
+		name:    "create_translation_index",
+		up:      createTranslationIndex,
+		down:    dropTranslationIndex,
+	},
+	{
+		version: 9,
+		name:    "create_additional_source_index",
+		up:      createAdditionalSourceIndex,
+		down:    dropAdditionalSourceIndex,
+	},
+	{
+		version: 10,
+		name:    "create_rating_fields",
+		up:      createRatingFields,
+		down:    dropRatingFields,
+	},
+	{
+		version: 11,
+		name:    "create_slug_indexes",
+		up:      createSlugIndexes,
+		down:    dropSlugIndexes,
+	},
+	{
+		version: 12,
+		name:    "create_tag_indexes",
+		up:      createTagIndexes,
+		down:    dropTagIndexes,
+	},
+	{
+		version: 13,
+		name:    "create_category_indexes",
+		up:      createCategoryIndexes,
+		down:    dropCategoryIndexes,
+	},
+	{
+		version: 14,
+		name:    "create_comment_indexes",
+		up:      createCommentIndexes,
+		down:    dropCommentIndexes,
+	},
+	{
+		version: 15,
+		name:    "create_moderator_note_indexes",
+		up:      createModeratorNoteIndexes,
+		down:    dropModeratorNoteIndexes,
+	},
+	{
+		version: 16,
+		name:    "create_rating_indexes",
+		up:      createRatingIndexes,
+		down:    dropRatingIndexes,
+	},
+	{
+		version: 17,
+		name:    "create_collection_indexes",
+		up:      createCollectionIndexes,
+		down:    dropCollectionIndexes,
+	},
+	{
+		version: 18,
+		name:    "create_webhook_indexes",
+		up:      createWebhookIndexes,
+		down:    dropWebhookIndexes,
+	},
+	{
+		version: 19,
+		name:    "create_outbox_indexes",
+		up:      createOutboxIndexes,
+		down:    dropOutboxIndexes,
+	},
+	{
+		version: 20,
+		name:    "create_notification_email_fields",
+		up:      createNotificationEmailFields,
+		down:    dropNotificationEmailFields,
+	},
+	{
+		version: 21,
+		name:    "create_language_text_indexes",
+		up:      createLanguageTextIndexes,
+		down:    dropLanguageTextIndexes,
+	},
+}
+
+func getMigrationsCollection() *mongo.Collection {
+	return database.Collection("migrations")
+}
+
+func getAppliedMigrations() (map[int]*types.AppliedMigration, error) {
+
+	applied, err := getDocuments[types.AppliedMigration](getMigrationsCollection(), bson.M{}, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	appliedMap := map[int]*types.AppliedMigration{}
+
+	for _, migration := range applied {
+		appliedMap[migration.Version] = migration
+	}
+
+	return appliedMap, nil
+
+}
+
+func GetMigrationStatus() ([]types.MigrationStatus, error) {
+
+	applied, err := getAppliedMigrations()
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := []types.MigrationStatus{}
+
+	for _, migration := range migrations {
+
+		entry := types.MigrationStatus{
+			Version: migration.version,
+			Name:    migration.name,
+		}
+
+		if appliedMigration, ok := applied[migration.version]; ok {
+			entry.Applied = true
+			entry.AppliedDate = &appliedMigration.AppliedDate
+		}
+
+		status = append(status, entry)
+
+	}
+
+	return status, nil
+
+}
+
+/* Applies all pending migrations up to (and including) the target version. A target of 0 applies all. */
+func MigrateUp(targetVersion int) ([]types.MigrationStatus, error) {
+
+	if targetVersion != 0 && !isKnownMigrationVersion(targetVersion) {
+		return nil, constants.ErrorMigrationUnknownVersion
+	}
+
+	applied, err := getAppliedMigrations()
+
+	if err != nil {
+		return nil, err
+	}
+
+	executed := []types.MigrationStatus{}
+
+	for _, migration := range migrations {
+
+		if targetVersion != 0 && migration.version > targetVersion {
+			break
+		}
+
+		if _, ok := applied[migration.version]; ok {
+			continue
+		}
+
+		fmt.Printf("Applying migration %d_%s...\n", migration.version, migration.name)
+
+		err = migration.up()
+
+		if err != nil {
+			return executed, fmt.Errorf("migration %d_%s failed: %w", migration.version, migration.name, err)
+		}
+
+		now := time.Now()
+		_, err = getMigrationsCollection().InsertOne(dbContext, types.AppliedMigration{
+			Version:     migration.version,
+			Name:        migration.name,
+			AppliedDate: now,
+		})
+
+		if err != nil {
+			return executed, err
+		}
+
+		executed = append(executed, types.MigrationStatus{Version: migration.version, Name: migration.name, Applied: true, AppliedDate: &now})
+
+	}
+
+	return executed, nil
+
+}
+
+/* Reverts the given number of applied migrations, starting with the newest one. */
+func MigrateDown(steps int) ([]types.MigrationStatus, error) {
+
+	applied, err := getAppliedMigrations()
+
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []types.MigrationStatus{}
+
+	for index := len(migrations) - 1; index >= 0 && len(reverted) < steps; index-- {
+
+		migration := migrations[index]
+
+		if _, ok := applied[migration.version]; !ok {
+			continue
+		}
+
+		fmt.Printf("Reverting migration %d_%s...\n", migration.version, migration.name)
+
+		err = migration.down()
+
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", migration.version, migration.name, err)
+		}
+
+		_, err = getMigrationsCollection().DeleteOne(dbContext, bson.M{"_id": migration.version})
+
+		if err != nil {
+			return reverted, err
+		}
+
+		reverted = append(reverted, types.MigrationStatus{Version: migration.version, Name: migration.name, Applied: false})
+
+	}
+
+	return reverted, nil
+
+}
+
+func isKnownMigrationVersion(version int) bool {
+
+	for _, migration := range migrations {
+		if migration.version == version {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+/* Migrations */
+
+func createFilterIndexes() error {
+
+	_, err := definitionsCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "approved", Value: 1}, {Key: "approved_date", Value: -1}}, Options: options.Index().SetName("approved_approved_date")},
+		{Keys: bson.D{{Key: "source", Value: 1}}, Options: options.Index().SetName("source")},
+		{Keys: bson.D{{Key: "submitted_by", Value: 1}}, Options: options.Index().SetName("submitted_by")},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = sourcesCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "authors", Value: 1}}, Options: options.Index().SetName("authors")},
+		{Keys: bson.D{{Key: "approved", Value: 1}}, Options: options.Index().SetName("approved")},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = authorsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "approved", Value: 1}}, Options: options.Index().SetName("approved"),
+	})
+
+	return err
+
+}
+
+func dropFilterIndexes() error {
+
+	for _, name := range []string{"approved_approved_date", "source", "submitted_by"} {
+		_, err := definitionsCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{"authors", "approved"} {
+		_, err := sourcesCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := authorsCollection.Indexes().DropOne(dbContext, "approved")
+	return err
+
+}
+
+func createSuggestKeys() error {
+
+	for _, collection := range []*mongo.Collection{authorsCollection, sourcesCollection} {
+
+		_, err := collection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "suggest.prefixes", Value: 1}}, Options: options.Index().SetName("suggest_prefixes")},
+			{Keys: bson.D{{Key: "suggest.trigrams", Value: 1}}, Options: options.Index().SetName("suggest_trigrams")},
+		})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	authors, err := getDocuments[types.Author](authorsCollection, bson.M{}, options.Find())
+
+	if err != nil {
+		return err
+	}
+
+	for _, author := range authors {
+
+		author.UpdateSuggestKeys()
+		_, err = authorsCollection.UpdateByID(dbContext, author.ID, bson.M{"$set": bson.M{"suggest": author.Suggest}})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	sources, err := getDocuments[types.Source](sourcesCollection, bson.M{}, options.Find())
+
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+
+		source.UpdateSuggestKeys()
+		_, err = sourcesCollection.UpdateByID(dbContext, source.ID, bson.M{"$set": bson.M{"suggest": source.Suggest}})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+func dropSuggestKeys() error {
+
+	for _, collection := range []*mongo.Collection{authorsCollection, sourcesCollection} {
+
+		for _, name := range []string{"suggest_prefixes", "suggest_trigrams"} {
+			_, err := collection.Indexes().DropOne(dbContext, name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := collection.UpdateMany(dbContext, bson.M{}, bson.M{"$unset": bson.M{"suggest": ""}})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+/* The slugs of the entities are kept, because the random slugs of authors were replaced by readable ones */
+func dropSlugs() error {
+
+	_, err := slugsCollection.DeleteMany(dbContext, bson.M{})
+	return err
+
+}
+
+/* Documents without a language were indexed with the default language, so they get it explicitly */
+func setDefaultLanguage() error {
+
+	for _, collection := range []*mongo.Collection{definitionsCollection, sourcesCollection} {
+
+		_, err := collection.UpdateMany(dbContext,
+			bson.M{"language": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"language": types.DefaultLanguage}},
+		)
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+/* Nothing to revert, a document with the default language is indexed like one without a language */
+func unsetLanguage() error {
+	return nil
+}
+
+var definitionTextIndexKeys = bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}}
+
+var sourceTextIndexKeys = bson.D{
+	{Key: "book_properties.title", Value: "text"}, {Key: "book_properties.edition", Value: "text"}, {Key: "book_properties.publisher", Value: "text"},
+	{Key: "journal_properties.journal_name", Value: "text"}, {Key: "journal_properties.title", Value: "text"}, {Key: "journal_properties.edition", Value: "text"}, {Key: "journal_properties.publisher", Value: "text"},
+	{Key: "web_properties.article_name", Value: "text"}, {Key: "web_properties.url", Value: "text"}, {Key: "web_properties.website_name", Value: "text"},
+}
+
+/* The text indexes of definitions and sources use the "language" field of every document for stemming and stop words */
+func createLanguageTextIndexes() error {
+
+	err := replaceTextIndex(definitionsCollection, mongo.IndexModel{
+		Keys: definitionTextIndexKeys,
+		Options: options.Index().
+			SetName("definitions_text").
+			SetDefaultLanguage(types.DefaultLanguage.String()).
+			SetLanguageOverride("language"),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return replaceTextIndex(sourcesCollection, mongo.IndexModel{
+		Keys: sourceTextIndexKeys,
+		Options: options.Index().
+			SetName("sources_text").
+			SetDefaultLanguage(types.DefaultLanguage.String()).
+			SetLanguageOverride("language"),
+	})
+
+}
+
+/* Restores the former text indexes without language support */
+func dropLanguageTextIndexes() error {
+
+	err := replaceTextIndex(definitionsCollection, mongo.IndexModel{Keys: definitionTextIndexKeys})
+
+	if err != nil {
+		return err
+	}
+
+	return replaceTextIndex(sourcesCollection, mongo.IndexModel{Keys: sourceTextIndexKeys})
+
+}
+
+/* A collection can only have one text index, so the existing text indexes are dropped before the new one is created */
+func replaceTextIndex(collection *mongo.Collection, model mongo.IndexModel) error {
+
+	specifications, err := collection.Indexes().ListSpecifications(dbContext)
+
+	if err != nil {
+		return err
+	}
+
+	for _, specification := range specifications {
+
+		// the keys of a text index are stored as "_fts"
+		if _, err := specification.KeysDocument.LookupErr("_fts"); err == nil {
+
+			_, err = collection.Indexes().DropOne(dbContext, specification.Name)
+
+			if err != nil {
+				return err
+			}
+
+		}
+
+	}
+
+	_, err = collection.Indexes().CreateOne(dbContext, model)
+	return err
+
+}
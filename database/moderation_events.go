@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+The moderation events recorded in the outbox, read from a change stream. Events appear after their transaction
+was committed, in the order of the commits, no matter which instance recorded them.
+*/
+type ModerationEventStream struct {
+	stream *mongo.ChangeStream
+}
+
+type outboxChange struct {
+	FullDocument types.DomainEvent `bson:"fullDocument"`
+}
+
+func DomainEventToModerationEvent(event *types.DomainEvent) *types.ModerationEvent {
+
+	return &types.ModerationEvent{
+		ID:          event.ID.Hex(),
+		Type:        event.Type,
+		Entity:      event.Type.GetEntity(),
+		EntityId:    event.EntityId.Hex(),
+		UserId:      event.UserId,
+		CreatedDate: event.CreatedDate,
+	}
+
+}
+
+/*
+Watches the moderation events recorded from now on or, with the token of a received event, after that event.
+Tokens can only be resumed as long as the oplog of the replica set still contains the event.
+*/
+func WatchModerationEvents(ctx context.Context, resumeToken string) (*ModerationEventStream, error) {
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType":     "insert",
+			"fullDocument.type": bson.M{"$in": types.ModerationEventTypes()},
+		}}},
+	}
+
+	streamOptions := options.ChangeStream()
+
+	if len(resumeToken) > 0 {
+		streamOptions.SetStartAfter(bson.M{"_data": resumeToken})
+	}
+
+	stream, err := outboxCollection.Watch(ctx, pipeline, streamOptions)
+
+	if err != nil {
+
+		if len(resumeToken) > 0 {
+			return nil, constants.ErrorInvalidResumeToken
+		}
+
+		return nil, err
+
+	}
+
+	return &ModerationEventStream{stream: stream}, nil
+
+}
+
+/* Blocks until the next event was committed and returns it with the token to resume after it */
+func (moderationStream *ModerationEventStream) Next(ctx context.Context) (*types.ModerationEvent, string, error) {
+
+	if !moderationStream.stream.Next(ctx) {
+
+		if err := moderationStream.stream.Err(); err != nil {
+			return nil, "", err
+		}
+
+		return nil, "", ctx.Err()
+
+	}
+
+	change := outboxChange{}
+
+	if err := moderationStream.stream.Decode(&change); err != nil {
+		return nil, "", err
+	}
+
+	resumeToken := moderationStream.stream.ResumeToken().Lookup("_data").StringValue()
+
+	return DomainEventToModerationEvent(&change.FullDocument), resumeToken, nil
+
+}
+
+func (moderationStream *ModerationEventStream) Close() error {
+	return moderationStream.stream.Close(context.Background())
+}
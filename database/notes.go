@@ -0,0 +1,264 @@
+package database
+
+import (
+	"time"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var notesCollection *mongo.Collection
+
+func createModeratorNoteIndexes() error {
+
+	_, err := notesCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "entity_id", Value: 1}, {Key: "created_date", Value: 1}}, Options: options.Index().SetName("entity_id_created_date"),
+	})
+
+	return err
+
+}
+
+func dropModeratorNoteIndexes() error {
+
+	_, err := notesCollection.Indexes().DropOne(dbContext, "entity_id_created_date")
+	return err
+
+}
+
+func ModeratorNoteToResponse(note *types.ModeratorNote) types.ModeratorNoteResponse {
+
+	response := types.ModeratorNoteResponse{
+		ID:             note.ID,
+		Entity:         note.Entity,
+		EntityId:       note.EntityId,
+		CreatedBy:      note.CreatedBy,
+		CreatedDate:    note.CreatedDate,
+		LastChangeDate: note.LastChangeDate,
+		Content:        note.Content,
+	}
+
+	nickname, err := auth.GetNicknameOfUser(note.CreatedBy)
+
+	if err == nil {
+		response.CreatedByName = nickname
+	} else {
+		response.CreatedByName = "<deleted>"
+	}
+
+	return response
+
+}
+
+func validateNoteEntityExists(entity types.NoteEntity, id primitive.ObjectID) error {
+
+	var err error
+
+	switch entity {
+	case types.EnumNoteEntity.Definition:
+		_, err = GetDefinitionByObjectId(id)
+	case types.EnumNoteEntity.Source:
+		_, err = GetSource(id)
+	case types.EnumNoteEntity.Author:
+		_, err = GetAuthor(id)
+	default:
+		err = constants.ErrorInvalidEnum
+	}
+
+	return err
+
+}
+
+func CreateModeratorNote(request *types.CreateModeratorNoteRequest, userId string) (*primitive.ObjectID, error) {
+
+	entityId, err := primitive.ObjectIDFromHex(request.EntityId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	err = validateNoteEntityExists(request.Entity, entityId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	note := types.ModeratorNote{
+		ID:             primitive.NewObjectID(),
+		Entity:         request.Entity,
+		EntityId:       entityId,
+		CreatedBy:      userId,
+		CreatedDate:    now,
+		LastChangeDate: now,
+		Content:        request.Content,
+	}
+
+	_, err = notesCollection.InsertOne(dbContext, note)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &note.ID, nil
+
+}
+
+func getModeratorNoteById(noteId string) (*types.ModeratorNote, error) {
+
+	id, err := primitive.ObjectIDFromHex(noteId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	var note types.ModeratorNote
+	err = notesCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&note)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorModeratorNoteNotFound
+		}
+		return nil, err
+	}
+
+	return &note, nil
+
+}
+
+/* Notes can only be changed by the moderator who wrote them */
+func ChangeModeratorNote(request *types.ChangeModeratorNoteRequest, userId string) error {
+
+	note, err := getModeratorNoteById(request.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if note.CreatedBy != userId {
+		return constants.ErrorModeratorNoteBelongsToAnotherUser
+	}
+
+	_, err = notesCollection.UpdateOne(dbContext, bson.M{"_id": note.ID}, bson.M{"$set": bson.M{
+		"content":          request.Content,
+		"last_change_date": time.Now(),
+	}})
+
+	return err
+
+}
+
+/* Notes can be deleted by the moderator who wrote them and by admins */
+func DeleteModeratorNote(noteId string, userId string, isAdmin bool) error {
+
+	note, err := getModeratorNoteById(noteId)
+
+	if err != nil {
+		return err
+	}
+
+	if note.CreatedBy != userId && !isAdmin {
+		return constants.ErrorModeratorNoteBelongsToAnotherUser
+	}
+
+	_, err = notesCollection.DeleteOne(dbContext, bson.M{"_id": note.ID})
+	return err
+
+}
+
+/* The notes of the entities, oldest first */
+func GetModeratorNotes(entityIds []primitive.ObjectID) ([]types.ModeratorNoteResponse, error) {
+
+	notes, err := getDocuments[types.ModeratorNote](notesCollection, bson.M{"entity_id": bson.M{"$in": entityIds}}, options.Find().SetSort(bson.D{{Key: "created_date", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.ModeratorNoteResponse{}
+
+	for _, note := range notes {
+		responses = append(responses, ModeratorNoteToResponse(note))
+	}
+
+	return responses, nil
+
+}
+
+func GetModeratorNotesOfEntity(entityId string) ([]types.ModeratorNoteResponse, error) {
+
+	id, err := primitive.ObjectIDFromHex(entityId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	return GetModeratorNotes([]primitive.ObjectID{id})
+
+}
+
+/* Adds the notes of the definitions, their sources and the authors of the sources to the responses */
+func AddModeratorNotesToDefinitions(responses *[]types.DefinitionsOfUserResponse) error {
+
+	entityIds := []primitive.ObjectID{}
+	responseEntityIds := [][]primitive.ObjectID{}
+
+	for _, response := range *responses {
+
+		ids := []primitive.ObjectID{response.ID, response.Source.ID}
+
+		for _, author := range response.Source.Authors {
+			ids = append(ids, author.ID)
+		}
+
+		for _, reference := range response.AdditionalSources {
+			ids = append(ids, reference.Source.ID)
+			for _, author := range reference.Source.Authors {
+				ids = append(ids, author.ID)
+			}
+		}
+
+		entityIds = append(entityIds, ids...)
+		responseEntityIds = append(responseEntityIds, ids)
+
+	}
+
+	notes, err := GetModeratorNotes(entityIds)
+
+	if err != nil {
+		return err
+	}
+
+	for index := range *responses {
+
+		ids := map[primitive.ObjectID]bool{}
+		for _, id := range responseEntityIds[index] {
+			ids[id] = true
+		}
+
+		responseNotes := []types.ModeratorNoteResponse{}
+		for _, note := range notes {
+			if ids[note.EntityId] {
+				responseNotes = append(responseNotes, note)
+			}
+		}
+
+		(*responses)[index].ModeratorNotes = &responseNotes
+
+	}
+
+	return nil
+
+}
+
+func deleteModeratorNotes(ctx mongo.SessionContext, entityId primitive.ObjectID) error {
+
+	_, err := notesCollection.DeleteMany(ctx, bson.M{"entity_id": entityId})
+	return err
+
+}
@@ -0,0 +1,542 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/notify"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var notificationPreferencesCollection *mongo.Collection
+
+/* Wakes up the email worker, when a notification with an email was created */
+var notificationEmailSignal = make(chan struct{}, 1)
+
+const (
+	// maximum number of characters of definitions and comments quoted in notifications
+	notificationExcerptLength = 200
+
+	notificationEmailPollInterval = 15 * time.Second
+	// a claimed email is sent again after this time, if the server stopped during the attempt
+	notificationEmailClaimTimeout = 2 * time.Minute
+	// emails are marked as failed after this number of failed attempts
+	maxNotificationEmailAttempts = 8
+	firstNotificationEmailDelay  = 30 * time.Second
+	maxNotificationEmailDelay    = 6 * time.Hour
+)
+
+/*
+Renders the template of the notification type and stores the notification in the inbox of the user. Muted types are
+not stored. If the user wants emails of the type, the email is sent by the email worker, so a failing or slow email
+never fails the domain event. A notification with a known idempotency key was already created and is skipped.
+*/
+func createNotification(notification *types.Notification, data notify.TemplateData, idempotencyKey string) error {
+
+	preferences, err := GetNotificationPreferences(notification.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if preferences.IsMuted(notification.Type) {
+		return nil
+	}
+
+	notification.Subject, notification.Message, err = notify.Render(notification.Type, data)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	notification.ID = primitive.NewObjectID()
+	notification.CreatedDate = now
+	notification.IdempotencyKey = &idempotencyKey
+
+	if preferences.WantsEmail(notification.Type) {
+		notification.EmailStatus = &types.EnumNotificationEmailStatus.Pending
+		notification.EmailNextAttemptDate = &now
+	}
+
+	_, err = notificationsCollection.InsertOne(dbContext, notification)
+
+	// a repeated call, e.g. after another handler of the event failed
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if notification.EmailStatus != nil {
+		select {
+		case notificationEmailSignal <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+
+}
+
+/* Sends pending notification emails in the background, until the process ends */
+func StartNotificationEmailWorker() {
+
+	go func() {
+
+		for {
+
+			sendDueNotificationEmails()
+
+			select {
+			case <-notificationEmailSignal:
+			case <-time.After(notificationEmailPollInterval):
+			}
+
+		}
+
+	}()
+
+}
+
+func sendDueNotificationEmails() {
+
+	for {
+
+		notification, err := claimDueNotificationEmail()
+
+		if err != nil {
+			fmt.Printf("Could not load notification emails: %v\n", err)
+			return
+		}
+
+		if notification == nil {
+			return
+		}
+
+		err = sendNotificationEmail(notification)
+
+		if err != nil {
+			fmt.Printf("Could not record email of notification %s: %v\n", notification.ID.Hex(), err)
+		}
+
+	}
+
+}
+
+/* Claims the oldest due email by moving its next attempt, so an email is only sent once at a time */
+func claimDueNotificationEmail() (*types.Notification, error) {
+
+	now := time.Now()
+	filter := bson.M{
+		"email_status":            types.EnumNotificationEmailStatus.Pending,
+		"email_next_attempt_date": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"email_next_attempt_date": now.Add(notificationEmailClaimTimeout)}}
+
+	var notification types.Notification
+	err := notificationsCollection.FindOneAndUpdate(dbContext, filter, update, options.FindOneAndUpdate().SetSort(bson.D{{Key: "email_next_attempt_date", Value: 1}})).Decode(&notification)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &notification, nil
+
+}
+
+/*
+Sends the email of the notification and records the attempt. Failed emails are retried with exponential backoff,
+until maxNotificationEmailAttempts is reached. Users without an email address or unknown users fail at once.
+*/
+func sendNotificationEmail(notification *types.Notification) error {
+
+	err := notify.Deliver(notification)
+
+	now := time.Now()
+	update := bson.M{}
+
+	if err == nil {
+
+		update["$set"] = bson.M{"email_status": types.EnumNotificationEmailStatus.Sent, "email_sent_date": now}
+		update["$unset"] = bson.M{"email_next_attempt_date": ""}
+
+	} else {
+
+		attempts := notification.EmailAttempts + 1
+		set := bson.M{"email_attempts": attempts, "email_last_error": err.Error()}
+		update["$set"] = set
+
+		if attempts < maxNotificationEmailAttempts && !errors.Is(err, constants.ErrorUserHasNoEmail) && !errors.Is(err, constants.ErrorUserNotFound) {
+
+			delay := firstNotificationEmailDelay * time.Duration(1<<(attempts-1))
+
+			if delay > maxNotificationEmailDelay {
+				delay = maxNotificationEmailDelay
+			}
+
+			set["email_next_attempt_date"] = now.Add(delay)
+
+		} else {
+			set["email_status"] = types.EnumNotificationEmailStatus.Failed
+			update["$unset"] = bson.M{"email_next_attempt_date": ""}
+		}
+
+	}
+
+	_, err = notificationsCollection.UpdateOne(dbContext, bson.M{"_id": notification.ID}, update)
+	return err
+
+}
+
+/*
+Handler of the domain events. Notifies about approvals and rejections of definitions, replies to comments and
+approved definitions matching saved searches. Entities deleted in the meantime are not notified about.
+*/
+func handleNotificationEvent(event *types.DomainEvent, idempotencyKey string) error {
+
+	switch event.Type {
+	case types.EnumDomainEventType.DefinitionApproved:
+
+		definition, err := GetDefinitionByObjectId(event.EntityId)
+
+		if err == constants.ErrorDefinitionNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = notifyDefinitionApproved(definition, idempotencyKey)
+
+		if err != nil {
+			return err
+		}
+
+		return notifySavedSearches(definition, idempotencyKey)
+
+	case types.EnumDomainEventType.DefinitionRejected:
+
+		definition, err := GetDefinitionByObjectId(event.EntityId)
+
+		if err == constants.ErrorDefinitionNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if definition.RejectionLog == nil {
+			return nil
+		}
+
+		for _, rejection := range *definition.RejectionLog {
+			if rejection.ID.Hex() == event.Data["rejectionId"] {
+				return notifyDefinitionRejected(definition, rejection, idempotencyKey)
+			}
+		}
+
+	case types.EnumDomainEventType.CommentCreated:
+
+		reply, err := getCommentByObjectId(event.EntityId)
+
+		if err == constants.ErrorCommentNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if reply.ParentId == nil {
+			return nil
+		}
+
+		parent, err := getCommentByObjectId(*reply.ParentId)
+
+		if err == constants.ErrorCommentNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		definition, err := GetDefinitionByObjectId(reply.DefinitionId)
+
+		if err == constants.ErrorDefinitionNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return notifyCommentReply(parent, reply, definition, idempotencyKey)
+
+	}
+
+	return nil
+
+}
+
+func notifyDefinitionApproved(definition *types.Definition, idempotencyKey string) error {
+
+	return createNotification(&types.Notification{
+		UserId:       definition.SubmittedBy,
+		Type:         types.EnumNotificationType.DefinitionApproved,
+		DefinitionId: &definition.ID,
+	}, notify.TemplateData{
+		Definition: notify.Excerpt(definition.Content, notificationExcerptLength),
+	}, idempotencyKey)
+
+}
+
+func notifyDefinitionRejected(definition *types.Definition, rejection *types.Rejection, idempotencyKey string) error {
+
+	return createNotification(&types.Notification{
+		UserId:       definition.SubmittedBy,
+		Type:         types.EnumNotificationType.DefinitionRejected,
+		DefinitionId: &definition.ID,
+	}, notify.TemplateData{
+		Definition: notify.Excerpt(definition.Content, notificationExcerptLength),
+		Reason:     rejection.Content,
+	}, idempotencyKey)
+
+}
+
+/* Notifies the author of the parent comment, unless they answered themselves */
+func notifyCommentReply(parent *types.Comment, reply *types.Comment, definition *types.Definition, idempotencyKey string) error {
+
+	if parent.SubmittedBy == reply.SubmittedBy {
+		return nil
+	}
+
+	author, err := auth.GetNicknameOfUser(reply.SubmittedBy)
+
+	if err != nil {
+		author = "Someone"
+	}
+
+	return createNotification(&types.Notification{
+		UserId:       parent.SubmittedBy,
+		Type:         types.EnumNotificationType.CommentReply,
+		DefinitionId: &definition.ID,
+		CommentId:    &reply.ID,
+	}, notify.TemplateData{
+		Definition: notify.Excerpt(definition.Content, notificationExcerptLength),
+		Comment:    notify.Excerpt(parent.Content, notificationExcerptLength),
+		Author:     author,
+	}, idempotencyKey)
+
+}
+
+/* The stored preferences of the user or the default preferences */
+func GetNotificationPreferences(userId string) (*types.NotificationPreferences, error) {
+
+	var preferences types.NotificationPreferences
+	err := notificationPreferencesCollection.FindOne(dbContext, bson.M{"_id": userId}).Decode(&preferences)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			preferences = types.DefaultNotificationPreferences(userId)
+			return &preferences, nil
+		}
+		return nil, err
+	}
+
+	return &preferences, nil
+
+}
+
+func ChangeNotificationPreferences(request *types.ChangeNotificationPreferencesRequest, userId string) (*types.NotificationPreferences, error) {
+
+	preferences, err := GetNotificationPreferences(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.EmailTypes != nil {
+		preferences.EmailTypes = *request.EmailTypes
+	}
+
+	if request.MutedTypes != nil {
+		preferences.MutedTypes = *request.MutedTypes
+	}
+
+	_, err = notificationPreferencesCollection.ReplaceOne(dbContext, bson.M{"_id": userId}, preferences, options.Replace().SetUpsert(true))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+
+}
+
+/* Returns the requested page of the inbox, the total count of the page filter and the count of unread notifications */
+func GetNotifications(request *types.NotificationPageRequest, userId string) ([]*types.Notification, int, int, error) {
+
+	filter := bson.M{"user_id": userId}
+
+	if request.UnreadOnly {
+		filter["read"] = bson.M{"$ne": true}
+	}
+
+	total, err := countDocuments(notificationsCollection, filter, nil)
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	unread, err := CountUnreadNotifications(userId)
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_date", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((request.Page - 1) * request.PageSize)).
+		SetLimit(int64(request.PageSize))
+
+	notifications, err := getDocuments[types.Notification](notificationsCollection, filter, findOptions)
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return notifications, total, unread, nil
+
+}
+
+/* Notifications recorded before the inbox existed have no read state and count as unread */
+func CountUnreadNotifications(userId string) (int, error) {
+	return countDocuments(notificationsCollection, bson.M{"user_id": userId, "read": bson.M{"$ne": true}}, nil)
+}
+
+/* Users can only change their own notifications */
+func SetNotificationRead(notificationId string, read bool, userId string) error {
+
+	id, err := primitive.ObjectIDFromHex(notificationId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	update := bson.M{"$set": bson.M{"read": false}, "$unset": bson.M{"read_date": ""}}
+
+	if read {
+		update = bson.M{"$set": bson.M{"read": true, "read_date": time.Now()}}
+	}
+
+	result, err := notificationsCollection.UpdateOne(dbContext, bson.M{"_id": id, "user_id": userId}, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorNotificationNotFound
+	}
+
+	return nil
+
+}
+
+/* Returns the number of notifications marked as read */
+func MarkAllNotificationsRead(userId string) (int64, error) {
+
+	result, err := notificationsCollection.UpdateMany(dbContext, bson.M{"user_id": userId, "read": bson.M{"$ne": true}}, bson.M{
+		"$set": bson.M{"read": true, "read_date": time.Now()},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+
+}
+
+/*
+Notifications of older versions only stored, whether an email was requested. Requested emails, which were not sent
+yet, become pending and are sent by the email worker.
+*/
+func createNotificationEmailFields() error {
+
+	_, err := notificationsCollection.UpdateMany(dbContext,
+		bson.M{"email_requested": true, "email_sent_date": bson.M{"$exists": true}},
+		bson.M{"$set": bson.M{"email_status": types.EnumNotificationEmailStatus.Sent}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.UpdateMany(dbContext,
+		bson.M{"email_requested": true, "email_sent_date": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_status": types.EnumNotificationEmailStatus.Pending, "email_next_attempt_date": time.Now()}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.UpdateMany(dbContext, bson.M{}, bson.M{"$unset": bson.M{"email_requested": ""}})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email_status", Value: 1}, {Key: "email_next_attempt_date", Value: 1}},
+		Options: options.Index().SetName("email_status_email_next_attempt_date"),
+	})
+
+	return err
+
+}
+
+func dropNotificationEmailFields() error {
+
+	_, err := notificationsCollection.Indexes().DropOne(dbContext, "email_status_email_next_attempt_date")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.UpdateMany(dbContext,
+		bson.M{"email_status": bson.M{"$exists": true}},
+		bson.M{"$set": bson.M{"email_requested": true}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.UpdateMany(dbContext, bson.M{}, bson.M{"$unset": bson.M{
+		"email_status":            "",
+		"email_attempts":          "",
+		"email_last_error":        "",
+		"email_next_attempt_date": "",
+	}})
+
+	return err
+
+}
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var outboxCollection *mongo.Collection
+
+/* Wakes up the dispatcher, when a transaction with events was committed */
+var outboxSignal = make(chan struct{}, 1)
+
+const (
+	outboxPollInterval = 10 * time.Second
+	// a claimed event is dispatched again after this time, if the server stopped during the dispatch
+	outboxClaimTimeout = 2 * time.Minute
+	// events are marked as failed after this number of failed dispatches
+	maxDispatchAttempts  = 10
+	firstDispatchDelay   = 10 * time.Second
+	maxDispatchDelay     = time.Hour
+	dispatchedEventsKept = 7 * 24 * time.Hour
+)
+
+/*
+Handles one domain event. Events are delivered at least once, so a handler may be called again for an event it
+already handled, e.g. after a crash. The idempotency key is the same for every call with the same event.
+*/
+type DomainEventHandler func(event *types.DomainEvent, idempotencyKey string) error
+
+type domainEventHandler struct {
+	name   string
+	handle DomainEventHandler
+}
+
+var domainEventHandlers = []domainEventHandler{
+	{name: "webhooks", handle: handleWebhookEvent},
+	{name: "notifications", handle: handleNotificationEvent},
+}
+
+/* Set by StartEventDispatcher, the handlers can not be changed afterwards */
+var dispatcherStarted = false
+
+/*
+Adds a handler, which receives every domain event. The name is part of the idempotency keys and must be unique.
+Handlers have to be registered before the dispatcher starts, otherwise events could be dispatched without them.
+*/
+func RegisterDomainEventHandler(name string, handler DomainEventHandler) error {
+
+	if dispatcherStarted {
+		return constants.ErrorEventDispatcherRunning
+	}
+
+	domainEventHandlers = append(domainEventHandlers, domainEventHandler{name: name, handle: handler})
+	return nil
+
+}
+
+func createOutboxIndexes() error {
+
+	_, err := outboxCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_date", Value: 1}}, Options: options.Index().SetName("status_next_attempt_date")},
+		// pending and failed events have no dispatched date and are kept
+		{Keys: bson.D{{Key: "dispatched_date", Value: 1}}, Options: options.Index().SetName("dispatched_date_ttl").SetExpireAfterSeconds(int32(dispatchedEventsKept.Seconds()))},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// the idempotency keys of the notification handler
+	_, err = notificationsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "idempotency_key", Value: 1}},
+		Options: options.Index().
+			SetName("idempotency_key").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}),
+	})
+
+	return err
+
+}
+
+func dropOutboxIndexes() error {
+
+	for _, name := range []string{"status_next_attempt_date", "dispatched_date_ttl"} {
+		_, err := outboxCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := notificationsCollection.Indexes().DropOne(dbContext, "idempotency_key")
+	return err
+
+}
+
+/*
+The domain events are written in transactions, which are only available on replica sets and sharded clusters.
+Only the server needs them, the read-only commands also work with a standalone MongoDB.
+*/
+func CheckTransactionSupport() error {
+
+	var hello bson.M
+	err := client.Database("admin").RunCommand(dbContext, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := hello["setName"]; ok || hello["msg"] == "isdbgrid" {
+		return nil
+	}
+
+	return constants.ErrorReplicaSetRequired
+
+}
+
+/*
+Runs the function in a transaction, so a change and its domain events are either written together or not at all.
+Every write inside the function has to use the given context. Transactions require a replica set.
+*/
+func withTransaction(function func(ctx mongo.SessionContext) error) error {
+
+	session, err := client.StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(dbContext)
+
+	// the function is repeated on transient errors, so it must not have side effects outside of the transaction
+	_, err = session.WithTransaction(dbContext, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, function(ctx)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case outboxSignal <- struct{}{}:
+	default:
+	}
+
+	return nil
+
+}
+
+/* Writes an event to the outbox, it has to be called inside of withTransaction */
+func recordDomainEvent(ctx mongo.SessionContext, eventType types.DomainEventType, entityId primitive.ObjectID, userId string, data map[string]string) error {
+
+	now := time.Now()
+	event := types.DomainEvent{
+		ID:              primitive.NewObjectID(),
+		Type:            eventType,
+		EntityId:        entityId,
+		UserId:          userId,
+		Data:            data,
+		Status:          types.EnumDomainEventStatus.Pending,
+		Handled:         []string{},
+		NextAttemptDate: &now,
+		CreatedDate:     now,
+	}
+
+	_, err := outboxCollection.InsertOne(ctx, event)
+	return err
+
+}
+
+/* Dispatches due events in the background, until the process ends */
+func StartEventDispatcher() {
+
+	dispatcherStarted = true
+
+	go func() {
+
+		for {
+
+			dispatchDueEvents()
+
+			select {
+			case <-outboxSignal:
+			case <-time.After(outboxPollInterval):
+			}
+
+		}
+
+	}()
+
+}
+
+func dispatchDueEvents() {
+
+	for {
+
+		event, err := claimDueEvent()
+
+		if err != nil {
+			fmt.Printf("Could not load domain events: %v\n", err)
+			return
+		}
+
+		if event == nil {
+			return
+		}
+
+		err = dispatchEvent(event)
+
+		if err != nil {
+			fmt.Printf("Could not record dispatch of domain event %s: %v\n", event.ID.Hex(), err)
+		}
+
+	}
+
+}
+
+/* Claims the oldest due event by moving its next attempt, so an event is only dispatched once at a time */
+func claimDueEvent() (*types.DomainEvent, error) {
+
+	now := time.Now()
+	filter := bson.M{
+		"status":            types.EnumDomainEventStatus.Pending,
+		"next_attempt_date": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_date": now.Add(outboxClaimTimeout)}}
+
+	var event types.DomainEvent
+	err := outboxCollection.FindOneAndUpdate(dbContext, filter, update, options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}})).Decode(&event)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &event, nil
+
+}
+
+/*
+Passes the event to every handler, which has not handled it yet. Handlers, which succeeded, are recorded at once,
+so only the failed handlers are called again, with exponential backoff.
+*/
+func dispatchEvent(event *types.DomainEvent) error {
+
+	var failure error
+
+	for _, handler := range domainEventHandlers {
+
+		if common.ArrayContainsOr(&event.Handled, handler.name) {
+			continue
+		}
+
+		err := handler.handle(event, event.IdempotencyKey(handler.name))
+
+		if err != nil {
+			failure = fmt.Errorf("%s: %w", handler.name, err)
+			continue
+		}
+
+		_, err = outboxCollection.UpdateOne(dbContext, bson.M{"_id": event.ID}, bson.M{"$addToSet": bson.M{"handled": handler.name}})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	now := time.Now()
+	set := bson.M{}
+
+	if failure == nil {
+
+		set["status"] = types.EnumDomainEventStatus.Dispatched
+		set["dispatched_date"] = now
+		set["next_attempt_date"] = nil
+
+	} else {
+
+		attempts := event.Attempts + 1
+		set["attempts"] = attempts
+		set["last_error"] = failure.Error()
+
+		if attempts < maxDispatchAttempts {
+
+			delay := firstDispatchDelay * time.Duration(1<<(attempts-1))
+
+			if delay > maxDispatchDelay {
+				delay = maxDispatchDelay
+			}
+
+			set["next_attempt_date"] = now.Add(delay)
+
+		} else {
+			set["status"] = types.EnumDomainEventStatus.Failed
+			set["next_attempt_date"] = nil
+		}
+
+		fmt.Printf("Could not dispatch domain event %s (%s): %v\n", event.ID.Hex(), event.Type.String(), failure)
+
+	}
+
+	_, err := outboxCollection.UpdateOne(dbContext, bson.M{"_id": event.ID}, bson.M{"$set": set})
+	return err
+
+}
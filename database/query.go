@@ -0,0 +1,344 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"yacoid_server/common"
+	"yacoid_server/query"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+Compiles a query of the query language (see query.Parse) to a match expression for definitions. The expression
+needs the fields added by createDefinitionSourceJoinStages. Author names are resolved to author IDs beforehand.
+Invalid values (e.g. an unknown category) are reported as query.SyntaxError at the position of the value.
+*/
+func compileDefinitionQuery(text string) (bson.D, error) {
+
+	node, err := query.Parse(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return compileDefinitionQueryNode(node)
+
+}
+
+func compileDefinitionQueryNode(node query.Node) (bson.D, error) {
+
+	switch node := node.(type) {
+	case *query.AndNode:
+		return compileDefinitionQueryNodes("$and", node.Children)
+	case *query.OrNode:
+		return compileDefinitionQueryNodes("$or", node.Children)
+	case *query.NotNode:
+		return compileDefinitionQueryNodes("$nor", []query.Node{node.Child})
+	case *query.TermNode:
+
+		regex, err := createQueryTextRegex(node.Text, node.Phrase, node.Position())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.D{{Key: "content", Value: regex}}, nil
+
+	case *query.FieldNode:
+		return compileDefinitionQueryField(node)
+	}
+
+	return nil, query.NewSyntaxError(node.Position(), "unsupported expression")
+
+}
+
+func compileDefinitionQueryNodes(operator string, nodes []query.Node) (bson.D, error) {
+
+	expressions := bson.A{}
+
+	for _, child := range nodes {
+
+		expression, err := compileDefinitionQueryNode(child)
+
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, expression)
+
+	}
+
+	return bson.D{{Key: operator, Value: expressions}}, nil
+
+}
+
+func compileDefinitionQueryField(node *query.FieldNode) (bson.D, error) {
+
+	switch node.Field {
+	case "category":
+
+		category := types.DefinitionCategory(node.Value)
+		exists, err := CategoryExists(category)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, query.NewSyntaxError(node.ValuePosition(), "unknown category \"%s\"", node.Value)
+		}
+
+		// child categories are included
+		categories, err := expandCategories([]types.DefinitionCategory{category})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.D{{Key: "categories", Value: bson.D{{Key: "$in", Value: categories}}}}, nil
+
+	case "type":
+
+		sourceType, err := types.ParseStringToSourceType(node.Value)
+
+		if err != nil {
+			return nil, query.NewSyntaxError(node.ValuePosition(), "unknown source type \"%s\"", node.Value)
+		}
+
+		return bson.D{{Key: "source_document.type", Value: sourceType}}, nil
+
+	case "language":
+
+		language, err := types.ParseStringToLanguage(node.Value)
+
+		if err != nil {
+			return nil, query.NewSyntaxError(node.ValuePosition(), "unknown language \"%s\"", node.Value)
+		}
+
+		return bson.D{{Key: "language", Value: language}}, nil
+
+	case "tag":
+
+		tagIds := []primitive.ObjectID{}
+		tagId, err := findTagIdByName(node.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// an unknown tag matches nothing
+		if tagId != nil {
+			tagIds = append(tagIds, *tagId)
+		}
+
+		return bson.D{{Key: "tags", Value: bson.D{{Key: "$in", Value: tagIds}}}}, nil
+
+	case "year":
+		return compileDefinitionQueryYear(node)
+
+	case "author":
+
+		authorIds, err := resolveQueryAuthors(node.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.D{{Key: "referenced_authors", Value: bson.D{{Key: "$in", Value: authorIds}}}}, nil
+
+	case "source":
+
+		regex, err := createQueryTextRegex(node.Value, node.Phrase, node.ValuePosition())
+
+		if err != nil {
+			return nil, err
+		}
+
+		titles := bson.A{}
+
+		for _, field := range []string{"book_properties.title", "journal_properties.title", "journal_properties.journal_name", "web_properties.article_name", "web_properties.website_name"} {
+			titles = append(titles,
+				bson.D{{Key: "source_document." + field, Value: regex}},
+				bson.D{{Key: "additional_source_documents." + field, Value: regex}},
+			)
+		}
+
+		return bson.D{{Key: "$or", Value: titles}}, nil
+
+	}
+
+	return nil, query.NewSyntaxError(node.Position(), "unknown field \"%s\"", node.Field)
+
+}
+
+func compileDefinitionQueryYear(node *query.FieldNode) (bson.D, error) {
+
+	// the value position is the start of the range, the end bound starts after the separator
+	parseYear := func(value string, position int) (int, error) {
+
+		year, err := strconv.Atoi(value)
+
+		if err != nil {
+			return 0, query.NewSyntaxError(position, "\"%s\" is not a year", value)
+		}
+
+		return year, nil
+
+	}
+
+	if !node.IsRange {
+
+		year, err := parseYear(node.Value, node.ValuePosition())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.D{{Key: "publication_year", Value: year}}, nil
+
+	}
+
+	yearRange := bson.D{}
+	from, to := 0, 0
+
+	if len(node.From) > 0 {
+
+		year, err := parseYear(node.From, node.ValuePosition())
+
+		if err != nil {
+			return nil, err
+		}
+
+		from = year
+		yearRange = append(yearRange, bson.E{Key: "$gte", Value: year})
+
+	}
+
+	if len(node.To) > 0 {
+
+		year, err := parseYear(node.To, node.ValuePosition()+len([]rune(node.From))+2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		to = year
+		yearRange = append(yearRange, bson.E{Key: "$lte", Value: year})
+
+	}
+
+	if len(node.From) > 0 && len(node.To) > 0 && from > to {
+		return nil, query.NewSyntaxError(node.ValuePosition(), "the start of the range %s is after its end", node.Value)
+	}
+
+	return bson.D{{Key: "publication_year", Value: yearRange}}, nil
+
+}
+
+/*
+Case insensitive regex matching the text anywhere. The words of a phrase must follow each other, separated by
+any whitespace. An empty phrase would match everything, so it is reported at the position of the phrase.
+*/
+func createQueryTextRegex(text string, phrase bool, position int) (primitive.Regex, error) {
+
+	if !phrase {
+		return primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}, nil
+	}
+
+	words := []string{}
+	for _, word := range strings.Fields(text) {
+		words = append(words, regexp.QuoteMeta(word))
+	}
+
+	if len(words) == 0 {
+		return primitive.Regex{}, query.NewSyntaxError(position, "the phrase is empty")
+	}
+
+	return primitive.Regex{Pattern: strings.Join(words, `\s+`), Options: "i"}, nil
+
+}
+
+/* Returns the IDs of the authors, whose names contain every word of the value, or the ID itself, if the value is an author ID */
+func resolveQueryAuthors(value string) ([]primitive.ObjectID, error) {
+
+	if id, err := primitive.ObjectIDFromHex(value); err == nil {
+		return []primitive.ObjectID{id}, nil
+	}
+
+	conditions := bson.A{}
+
+	for _, word := range strings.Fields(value) {
+
+		regex := primitive.Regex{Pattern: regexp.QuoteMeta(word), Options: "i"}
+
+		conditions = append(conditions, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "person_properties.first_name", Value: regex}},
+			bson.D{{Key: "person_properties.last_name", Value: regex}},
+			bson.D{{Key: "organization_properties.organization_name", Value: regex}},
+		}}})
+
+	}
+
+	authorIds := []primitive.ObjectID{}
+
+	if len(conditions) == 0 {
+		return authorIds, nil
+	}
+
+	authors, err := getDocuments[types.Author](authorsCollection, bson.D{{Key: "$and", Value: conditions}}, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range authors {
+		authorIds = append(authorIds, author.ID)
+	}
+
+	return authorIds, nil
+
+}
+
+/* The words and phrases of the query, which are not negated. Used to highlight the matches of a query. */
+func createQueryHighlightTerms(text string) common.SearchQuery {
+
+	searchQuery := common.SearchQuery{Terms: []string{}, Phrases: []string{}, Excluded: []string{}}
+
+	node, err := query.Parse(text)
+
+	if err != nil {
+		return searchQuery
+	}
+
+	var collect func(node query.Node)
+	collect = func(node query.Node) {
+
+		switch node := node.(type) {
+		case *query.AndNode:
+			for _, child := range node.Children {
+				collect(child)
+			}
+		case *query.OrNode:
+			for _, child := range node.Children {
+				collect(child)
+			}
+		case *query.TermNode:
+			if node.Phrase {
+				searchQuery.Phrases = append(searchQuery.Phrases, node.Text)
+			} else {
+				searchQuery.Terms = append(searchQuery.Terms, node.Text)
+			}
+		}
+
+	}
+
+	collect(node)
+
+	return searchQuery
+
+}
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"math"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ratingsCollection *mongo.Collection
+
+/* Every user has at most one rating per definition */
+func createRatingIndexes() error {
+
+	_, err := ratingsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "definition_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetName("definition_user").SetUnique(true),
+	})
+
+	return err
+
+}
+
+func dropRatingIndexes() error {
+
+	_, err := ratingsCollection.Indexes().DropOne(dbContext, "definition_user")
+	return err
+
+}
+
+/*
+Only approved definitions can be rated and users can not rate their own definitions. Rating again replaces the previous
+rating. The definition is checked in the transaction, which writes its rating, so a concurrent rejection conflicts.
+*/
+func RateDefinition(request *types.RateDefinitionRequest, userId string) (*types.DefinitionRating, error) {
+
+	id, err := primitive.ObjectIDFromHex(request.DefinitionId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	now := time.Now()
+	filter := bson.M{"definition_id": id, "user_id": userId}
+	update := bson.M{
+		"$set": bson.M{
+			"vote":             request.Vote,
+			"usefulness":       request.Usefulness,
+			"last_change_date": now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":            primitive.NewObjectID(),
+			"submitted_date": now,
+		},
+	}
+
+	var rating *types.DefinitionRating
+
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		var definition types.Definition
+		err := definitionsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&definition)
+
+		if err == mongo.ErrNoDocuments {
+			return constants.ErrorDefinitionNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !definition.Approved {
+			return constants.ErrorDefinitionNotApproved
+		}
+
+		if definition.SubmittedBy == userId {
+			return constants.ErrorRatingOfOwnDefinition
+		}
+
+		_, err = ratingsCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+
+		if err != nil {
+			return err
+		}
+
+		rating, err = updateDefinitionRating(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionRated, id, userId, nil)
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+
+}
+
+func DeleteRating(definitionId string, userId string) (*types.DefinitionRating, error) {
+
+	id, err := primitive.ObjectIDFromHex(definitionId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	var rating *types.DefinitionRating
+
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		result, err := ratingsCollection.DeleteOne(ctx, bson.M{"definition_id": id, "user_id": userId})
+
+		if err != nil {
+			return err
+		}
+
+		if result.DeletedCount == 0 {
+			return constants.ErrorRatingNotFound
+		}
+
+		rating, err = updateDefinitionRating(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.DefinitionRated, id, userId, nil)
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+
+}
+
+/* The rating of the user for the definition, nil if the user has not rated it yet */
+func GetRatingOfUser(definitionId string, userId string) (*types.Rating, error) {
+
+	id, err := primitive.ObjectIDFromHex(definitionId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	var rating types.Rating
+	err = ratingsCollection.FindOne(dbContext, bson.M{"definition_id": id, "user_id": userId}).Decode(&rating)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &rating, nil
+
+}
+
+/*
+Aggregates all ratings of the definition and stores the result on the definition. It runs in the transaction of
+the rating, concurrent ratings of the same definition conflict on the definition and the transaction is repeated.
+*/
+func updateDefinitionRating(ctx mongo.SessionContext, definitionId primitive.ObjectID) (*types.DefinitionRating, error) {
+
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"definition_id": definitionId}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "upvotes", Value: bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$vote", types.EnumVote.Up}}, 1, 0}}}},
+			{Key: "downvotes", Value: bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$vote", types.EnumVote.Down}}, 1, 0}}}},
+			{Key: "usefulness_count", Value: bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$usefulness", nil}}, 1, 0}}}},
+			// $avg ignores ratings without usefulness and is null without any usefulness
+			{Key: "usefulness_average", Value: bson.M{"$avg": "$usefulness"}},
+		}}},
+	}
+
+	cursor, err := ratingsCollection.Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := []struct {
+		Upvotes           int      `bson:"upvotes"`
+		Downvotes         int      `bson:"downvotes"`
+		UsefulnessCount   int      `bson:"usefulness_count"`
+		UsefulnessAverage *float64 `bson:"usefulness_average"`
+	}{}
+
+	err = cursor.All(ctx, &results)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rating := types.DefinitionRating{}
+
+	if len(results) > 0 {
+
+		rating.Upvotes = results[0].Upvotes
+		rating.Downvotes = results[0].Downvotes
+		rating.Score = rating.Upvotes - rating.Downvotes
+		rating.UsefulnessCount = results[0].UsefulnessCount
+
+		if results[0].UsefulnessAverage != nil {
+			rating.UsefulnessAverage = math.Round(*results[0].UsefulnessAverage*100) / 100
+		}
+
+	}
+
+	result, err := definitionsCollection.UpdateOne(ctx, bson.M{"_id": definitionId}, bson.M{"$set": bson.M{"rating": rating}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the definition was deleted, the transaction is aborted, so no rating remains without its definition
+	if result.MatchedCount == 0 {
+		return nil, constants.ErrorDefinitionNotFound
+	}
+
+	return &rating, nil
+
+}
+
+/* The approved definitions with the highest score, ties are broken by the usefulness and the approval date */
+func GetTopRatedDefinitions(limit int) ([]*types.Definition, error) {
+
+	options := options.Find().SetSort(bson.D{
+		{Key: "rating.score", Value: -1},
+		{Key: "rating.usefulness_average", Value: -1},
+		{Key: "approved_date", Value: -1},
+		{Key: "_id", Value: -1},
+	}).SetLimit(int64(limit))
+
+	return getDocuments[types.Definition](definitionsCollection, bson.M{"approved": true}, options)
+
+}
+
+func deleteDefinitionRatings(ctx mongo.SessionContext, definitionId primitive.ObjectID) error {
+
+	_, err := ratingsCollection.DeleteMany(ctx, bson.M{"definition_id": definitionId})
+	return err
+
+}
+
+/* Definitions without a rating get an empty one, so minScore and the index include them */
+func createRatingFields() error {
+
+	_, err := definitionsCollection.UpdateMany(dbContext, bson.M{"rating": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"rating": types.DefinitionRating{}}})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "approved", Value: 1}, {Key: "rating.score", Value: -1}}, Options: options.Index().SetName("approved_rating_score"),
+	})
+
+	return err
+
+}
+
+func dropRatingFields() error {
+
+	_, err := definitionsCollection.Indexes().DropOne(dbContext, "approved_rating_score")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = definitionsCollection.UpdateMany(dbContext, bson.M{}, bson.M{"$unset": bson.M{"rating": ""}})
+	return err
+
+}
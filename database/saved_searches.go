@@ -0,0 +1,233 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/notify"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var savedSearchesCollection *mongo.Collection
+var notificationsCollection *mongo.Collection
+
+func CreateSavedSearch(request *types.CreateSavedSearchRequest, userId string) (*primitive.ObjectID, error) {
+
+	// invalid filters (e.g. a query with a syntax error) are rejected before they are saved
+	_, err := createDefinitionFilterStages(request.Filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	savedSearch := types.SavedSearch{
+		ID:          primitive.NewObjectID(),
+		UserId:      userId,
+		Name:        request.Name,
+		Filter:      *request.Filter,
+		Alerts:      request.Alerts != nil && *request.Alerts,
+		CreatedDate: time.Now(),
+	}
+
+	_, err = savedSearchesCollection.InsertOne(dbContext, savedSearch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &savedSearch.ID, nil
+
+}
+
+func GetSavedSearchesOfUser(userId string) ([]*types.SavedSearch, error) {
+	return getDocuments[types.SavedSearch](savedSearchesCollection, bson.M{"user_id": userId}, options.Find().SetSort(bson.D{{Key: "created_date", Value: -1}}))
+}
+
+/* Users can only change their own saved searches */
+func ChangeSavedSearch(request *types.ChangeSavedSearchRequest, userId string) error {
+
+	id, err := primitive.ObjectIDFromHex(request.ID)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	if request.Filter != nil {
+
+		_, err = createDefinitionFilterStages(request.Filter)
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	update := bson.D{}
+
+	if request.Name != nil {
+		update = append(update, bson.E{Key: "name", Value: *request.Name})
+	}
+
+	if request.Filter != nil {
+		update = append(update, bson.E{Key: "filter", Value: *request.Filter})
+	}
+
+	if request.Alerts != nil {
+		update = append(update, bson.E{Key: "alerts", Value: *request.Alerts})
+	}
+
+	if len(update) == 0 {
+		return nil
+	}
+
+	result, err := savedSearchesCollection.UpdateOne(dbContext, bson.M{"_id": id, "user_id": userId}, bson.M{"$set": update})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorSavedSearchNotFound
+	}
+
+	return nil
+
+}
+
+/* Users can only delete their own saved searches */
+func DeleteSavedSearch(savedSearchId string, userId string) error {
+
+	id, err := primitive.ObjectIDFromHex(savedSearchId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	result, err := savedSearchesCollection.DeleteOne(dbContext, bson.M{"_id": id, "user_id": userId})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return constants.ErrorSavedSearchNotFound
+	}
+
+	return nil
+
+}
+
+func definitionMatchesFilter(definitionId primitive.ObjectID, filter *types.DefinitionFilter) (bool, error) {
+
+	pipeline, err := createDefinitionFilterStages(filter)
+
+	if err != nil {
+		return false, err
+	}
+
+	// the first stage stays first, it may contain $text
+	pipeline = append(pipeline[:1], append(bson.A{bson.D{{Key: "$match", Value: bson.M{"_id": definitionId}}}}, pipeline[1:]...)...)
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"_id": 1}}})
+
+	results, err := aggregateDocuments[bson.M](definitionsCollection, pipeline, options.Aggregate())
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(results) > 0, nil
+
+}
+
+/*
+Records a notification for every user with an alert on a saved search matching the approved definition. The
+submitter is not notified about their own definition. Saved searches, which can not be matched, are skipped.
+*/
+func notifySavedSearches(definition *types.Definition, idempotencyKey string) error {
+
+	savedSearches, err := getDocuments[types.SavedSearch](savedSearchesCollection, bson.M{"alerts": true, "user_id": bson.M{"$ne": definition.SubmittedBy}}, options.Find())
+
+	if err != nil {
+		return err
+	}
+
+	notifiedUsers := map[string]bool{}
+
+	for _, savedSearch := range savedSearches {
+
+		// a user with several matching searches is notified once
+		if notifiedUsers[savedSearch.UserId] {
+			continue
+		}
+
+		matches, err := definitionMatchesFilter(definition.ID, &savedSearch.Filter)
+
+		if err != nil {
+			fmt.Printf("Could not match saved search %s: %v\n", savedSearch.ID.Hex(), err)
+			continue
+		}
+
+		if !matches {
+			continue
+		}
+
+		notifiedUsers[savedSearch.UserId] = true
+
+		err = createNotification(&types.Notification{
+			UserId:        savedSearch.UserId,
+			Type:          types.EnumNotificationType.SavedSearchMatch,
+			DefinitionId:  &definition.ID,
+			SavedSearchId: &savedSearch.ID,
+		}, notify.TemplateData{
+			Definition:  notify.Excerpt(definition.Content, notificationExcerptLength),
+			SavedSearch: savedSearch.Name,
+		}, idempotencyKey+":"+savedSearch.UserId)
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+func createSavedSearchIndexes() error {
+
+	_, err := savedSearchesCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_date", Value: -1}}, Options: options.Index().SetName("user_id_created_date")},
+		{Keys: bson.D{{Key: "alerts", Value: 1}}, Options: options.Index().SetName("alerts")},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = notificationsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_date", Value: -1}}, Options: options.Index().SetName("user_id_created_date"),
+	})
+
+	return err
+
+}
+
+func dropSavedSearchIndexes() error {
+
+	for _, name := range []string{"user_id_created_date", "alerts"} {
+		_, err := savedSearchesCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := notificationsCollection.Indexes().DropOne(dbContext, "user_id_created_date")
+	return err
+
+}
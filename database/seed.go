@@ -0,0 +1,535 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"yacoid_server/types"
+
+	"github.com/gosimple/slug"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var seedFirstNames = []string{
+	"Alan", "Ada", "John", "Grace", "Marvin", "Margaret", "Herbert", "Barbara", "Claude", "Donna",
+	"Allen", "Fei-Fei", "Geoffrey", "Daphne", "Yann", "Cynthia", "Rodney", "Judea", "Stuart", "Melanie",
+	"Howard", "Robert", "Sarah", "Stefano", "Monica", "František", "Jürgen", "Katharina", "Émile", "Anouk",
+}
+
+var seedLastNames = []string{
+	"Turing", "Lovelace", "McCarthy", "Hopper", "Minsky", "Boden", "Simon", "Liskov", "Shannon", "Haraway",
+	"Newell", "Li", "Hinton", "Koller", "LeCun", "Breazeal", "Brooks", "Pearl", "Russell", "Mitchell",
+	"Gardner", "Sternberg", "Legg", "Mancuso", "Gagliano", "Baluška", "Schmidhuber", "Müller", "Durand", "Janssen",
+}
+
+var seedOrganizationNames = []string{
+	"Institute for Cognitive Studies", "Society for Artificial Intelligence", "Center for Plant Neurobiology",
+	"Association for Computing Machinery", "Max Planck Institute for Intelligent Systems", "SETI Research Group",
+	"European Network for Cognitive Systems", "Laboratory of Machine Learning", "Foundation for Comparative Cognition",
+	"Working Group on Collective Behaviour",
+}
+
+var seedTitleSubjects = []string{
+	"Intelligence", "Minds", "Cognition", "Learning", "Adaptation", "Reasoning", "Thinking Machines",
+	"Problem Solving", "Plant Behaviour", "Extraterrestrial Life", "Consciousness", "Knowledge",
+}
+
+var seedTitlePatterns = []string{
+	"On the Nature of %s", "%s Reconsidered", "A Theory of %s", "The Limits of %s", "Foundations of %s",
+	"%s in Context", "Measuring %s", "Rethinking %s", "%s: A Critical Introduction", "The Evolution of %s",
+}
+
+var seedJournalNames = []string{
+	"Mind", "Cognitive Science", "Artificial Intelligence", "Journal of Intelligence", "Trends in Plant Science",
+	"Behavioral and Brain Sciences", "Minds and Machines", "Astrobiology", "Intelligence", "Journal of Consciousness Studies",
+}
+
+var seedPublishers = []string{
+	"MIT Press", "Oxford University Press", "Springer", "Cambridge University Press", "Elsevier", "Suhrkamp", "Routledge",
+}
+
+var seedPlaces = []string{"Cambridge", "Oxford", "Berlin", "New York", "London", "Frankfurt am Main", "Amsterdam"}
+
+var seedWebsites = []struct {
+	name string
+	host string
+}{
+	{name: "Stanford Encyclopedia of Philosophy", host: "plato.stanford.edu"},
+	{name: "Scholarpedia", host: "www.scholarpedia.org"},
+	{name: "AI Magazine Online", host: "aimagazine.example.org"},
+	{name: "Plant Cognition Blog", host: "plantcognition.example.org"},
+	{name: "Encyclopedia of Astrobiology", host: "astrobiology.example.org"},
+}
+
+var seedDefinitionSubjects = map[types.DefinitionCategory][]string{
+	"human_intelligence":      {"Human intelligence", "Intelligence in humans", "General intelligence"},
+	"artificial_intelligence": {"Artificial intelligence", "An intelligent agent", "A rational agent"},
+	"machine_intelligence":    {"Machine intelligence", "A thinking machine", "Computational intelligence"},
+	"plant_intelligence":      {"Plant intelligence", "Intelligent plant behaviour", "Vegetal cognition"},
+	"alien_intelligence":      {"Alien intelligence", "Extraterrestrial intelligence", "Non-terrestrial cognition"},
+}
+
+var seedDefinitionAbilities = []string{
+	"the capacity to adapt to new situations",
+	"the ability to solve novel problems",
+	"the capacity to learn from experience",
+	"the ability to achieve goals in a wide range of environments",
+	"the capacity to acquire and apply knowledge",
+	"the ability to reason about the consequences of actions",
+	"the capacity to integrate information in a flexible way",
+	"the ability to anticipate changes in the environment",
+}
+
+var seedDefinitionQualifiers = []string{
+	"under limited resources",
+	"without explicit instruction",
+	"in an uncertain environment",
+	"in pursuit of its own survival",
+	"by means of abstraction",
+	"through communication with others",
+}
+
+var seedRejectionReasons = []string{
+	"The page number is missing.",
+	"Please quote the definition verbatim.",
+	"The source does not contain this definition.",
+	"The category does not fit the definition.",
+	"This definition is a duplicate of an existing one.",
+}
+
+type seeder struct {
+	random  *rand.Rand
+	options *types.SeedOptions
+	users   []string
+}
+
+func (seeder *seeder) pick(values []string) string {
+	return values[seeder.random.Intn(len(values))]
+}
+
+func (seeder *seeder) user() string {
+	return seeder.users[seeder.random.Intn(len(seeder.users))]
+}
+
+/* Returns a date between the start date and the given number of days after it. */
+func (seeder *seeder) date(start time.Time, days int) time.Time {
+	return start.Add(time.Duration(seeder.random.Int63n(int64(days) * int64(24*time.Hour))))
+}
+
+/* Creates an ObjectID based on the random generator, so the IDs are the same for every run with the same seed. */
+func (seeder *seeder) objectId(date time.Time) primitive.ObjectID {
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(date.Unix()))
+	seeder.random.Read(id[4:])
+	return id
+
+}
+
+func (seeder *seeder) isbn() string {
+
+	digits := []int{9, 7, 8}
+	for len(digits) < 12 {
+		digits = append(digits, seeder.random.Intn(10))
+	}
+
+	sum := 0
+	for index, digit := range digits {
+		if index%2 == 0 {
+			sum += digit
+		} else {
+			sum += digit * 3
+		}
+	}
+
+	digits = append(digits, (10-sum%10)%10)
+
+	var builder strings.Builder
+	for _, digit := range digits {
+		builder.WriteString(fmt.Sprint(digit))
+	}
+
+	return builder.String()
+
+}
+
+func (seeder *seeder) title() string {
+	return fmt.Sprintf(seeder.pick(seedTitlePatterns), seeder.pick(seedTitleSubjects))
+}
+
+/*
+Creates deterministic demo data. The same options (including the seed) always create the same documents.
+Definitions are spread over every category and every status (approved, pending and declined).
+*/
+func Seed(seedOptions *types.SeedOptions) (*types.SeedResult, error) {
+
+	seeder := seeder{
+		random:  rand.New(rand.NewSource(seedOptions.Seed)),
+		options: seedOptions,
+		users:   seedOptions.UserIds,
+	}
+
+	if len(seeder.users) == 0 {
+		for index := 1; index <= 5; index++ {
+			seeder.users = append(seeder.users, fmt.Sprintf("seed-user-%d", index))
+		}
+	}
+
+	// the users keep their saved searches, preferences and webhooks, but not what refers to the deleted entities
+	if seedOptions.Drop {
+		for _, collection := range []*mongo.Collection{authorsCollection, sourcesCollection, definitionsCollection, slugsCollection, tagsCollection, commentsCollection, notesCollection, ratingsCollection, collectionsCollection, outboxCollection, notificationsCollection, webhookDeliveriesCollection} {
+			_, err := collection.DeleteMany(dbContext, bson.M{})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the definitions use the default categories
+	err := ensureDefaultCategories()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := types.SeedResult{}
+
+	authors := seeder.createAuthors()
+	sources := seeder.createSources(authors)
+
+	if len(sources) == 0 && seedOptions.Definitions > 0 {
+		return nil, fmt.Errorf("at least one author and one source are needed to seed definitions")
+	}
+
+	definitions := seeder.createDefinitions(sources, &result)
+
+	/* Approve everything referenced by approved definitions */
+
+	for _, definition := range definitions {
+
+		if !definition.Approved {
+			continue
+		}
+
+		source := findSeedSource(sources, definition.Source)
+		seeder.approve(&source.Approved, &source.ApprovedBy, &source.ApprovedDate, source.SubmittedDate, *definition.ApprovedDate)
+
+		for _, authorId := range source.Authors {
+			author := findSeedAuthor(authors, authorId)
+			seeder.approve(&author.Approved, &author.ApprovedBy, &author.ApprovedDate, author.SubmittedDate, *definition.ApprovedDate)
+		}
+
+	}
+
+	err = insertSeedDocuments(authorsCollection, authors)
+
+	if err != nil {
+		return nil, fmt.Errorf("seeding authors failed: %w", err)
+	}
+
+	err = insertSeedDocuments(sourcesCollection, sources)
+
+	if err != nil {
+		return nil, fmt.Errorf("seeding sources failed: %w", err)
+	}
+
+	err = insertSeedDocuments(definitionsCollection, definitions)
+
+	if err != nil {
+		return nil, fmt.Errorf("seeding definitions failed: %w", err)
+	}
+
+	// the slugs are assigned in the order of the IDs, so they are deterministic as well
+	err = syncSlugs()
+
+	if err != nil {
+		return nil, fmt.Errorf("assigning slugs failed: %w", err)
+	}
+
+	result.Authors = len(authors)
+	result.Sources = len(sources)
+	result.Definitions = len(definitions)
+
+	return &result, nil
+
+}
+
+/* Approves the entity at the given date, if it was not approved before */
+func (seeder *seeder) approve(approved *bool, approvedBy **string, approvedDate **time.Time, submittedDate time.Time, date time.Time) {
+
+	if *approved && (*approvedDate).Before(date) {
+		return
+	}
+
+	if date.Before(submittedDate) {
+		date = submittedDate
+	}
+
+	moderator := seeder.options.ModeratorId
+	*approved = true
+	*approvedBy = &moderator
+	*approvedDate = &date
+
+}
+
+func insertSeedDocuments[T interface{}](collection *mongo.Collection, documents []*T) error {
+
+	if len(documents) == 0 {
+		return nil
+	}
+
+	insertDocuments := []interface{}{}
+	for _, document := range documents {
+		insertDocuments = append(insertDocuments, document)
+	}
+
+	_, err := collection.InsertMany(dbContext, insertDocuments)
+	return err
+
+}
+
+func findSeedSource(sources []*types.Source, id primitive.ObjectID) *types.Source {
+
+	for _, source := range sources {
+		if source.ID == id {
+			return source
+		}
+	}
+
+	return nil
+
+}
+
+func findSeedAuthor(authors []*types.Author, id primitive.ObjectID) *types.Author {
+
+	for _, author := range authors {
+		if author.ID == id {
+			return author
+		}
+	}
+
+	return nil
+
+}
+
+var seedStartDate = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func (seeder *seeder) createAuthors() []*types.Author {
+
+	authors := []*types.Author{}
+
+	for index := 0; index < seeder.options.Persons+seeder.options.Organizations; index++ {
+
+		submittedDate := seeder.date(seedStartDate, 365)
+
+		author := types.Author{
+			ID:             seeder.objectId(submittedDate),
+			SubmittedBy:    seeder.user(),
+			SubmittedDate:  submittedDate,
+			LastChangeDate: submittedDate,
+		}
+
+		if index < seeder.options.Persons {
+			author.Type = types.EnumAuthorType.Person
+			author.PersonProperties = &types.PersonProperties{
+				FirstName: seeder.pick(seedFirstNames),
+				LastName:  seeder.pick(seedLastNames),
+			}
+		} else {
+			author.Type = types.EnumAuthorType.Organization
+			name := seedOrganizationNames[(index-seeder.options.Persons)%len(seedOrganizationNames)]
+			author.OrganizationProperties = &types.OrganizationProperties{OrganizationName: name}
+		}
+
+		author.UpdateSuggestKeys()
+		authors = append(authors, &author)
+
+	}
+
+	return authors
+
+}
+
+func (seeder *seeder) createSources(authors []*types.Author) []*types.Source {
+
+	sources := []*types.Source{}
+
+	if len(authors) == 0 {
+		return sources
+	}
+
+	total := seeder.options.Books + seeder.options.Journals + seeder.options.Websites
+
+	for index := 0; index < total; index++ {
+
+		submittedDate := seeder.date(seedStartDate.AddDate(1, 0, 0), 365)
+
+		source := types.Source{
+			ID:             seeder.objectId(submittedDate),
+			SubmittedBy:    seeder.user(),
+			SubmittedDate:  submittedDate,
+			LastChangeDate: submittedDate,
+			Language:       types.EnumLanguage.English,
+		}
+
+		authorCount := 1 + seeder.random.Intn(3)
+		for _, authorIndex := range seeder.random.Perm(len(authors))[:minInt(authorCount, len(authors))] {
+			source.Authors = append(source.Authors, authors[authorIndex].ID)
+		}
+
+		publicationDate := seeder.date(time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC), 365*72)
+
+		switch {
+		case index < seeder.options.Books:
+			source.Type = types.EnumSourceType.Book
+			source.BookProperties = &types.BookProperties{
+				Title:            seeder.title(),
+				PublicationDate:  &publicationDate,
+				PublicationPlace: seeder.pick(seedPlaces),
+				Edition:          fmt.Sprintf("%d", 1+seeder.random.Intn(4)),
+				Publisher:        seeder.pick(seedPublishers),
+				ISBN:             seeder.isbn(),
+			}
+		case index < seeder.options.Books+seeder.options.Journals:
+			pagesFrom := 1 + seeder.random.Intn(400)
+			source.Type = types.EnumSourceType.Journal
+			source.JournalProperties = &types.JournalProperties{
+				JournalName:     seeder.pick(seedJournalNames),
+				Title:           seeder.title(),
+				PublicationDate: &publicationDate,
+				PagesFrom:       pagesFrom,
+				PagesTo:         pagesFrom + 5 + seeder.random.Intn(30),
+				DOI:             fmt.Sprintf("10.%04d/seed.%d", 1000+seeder.random.Intn(9000), index),
+				Publisher:       seeder.pick(seedPublishers),
+			}
+		default:
+			website := seedWebsites[seeder.random.Intn(len(seedWebsites))]
+			title := seeder.title()
+			source.Type = types.EnumSourceType.Web
+			source.WebProperties = &types.WebProperties{
+				ArticleName: title,
+				URL:         fmt.Sprintf("https://%s/%s", website.host, slug.Make(title)),
+				WebsiteName: website.name,
+				AccessDate:  seeder.date(seedStartDate, 365),
+			}
+
+			// not every web source has a publication date
+			if seeder.random.Intn(3) > 0 {
+				webPublicationDate := seeder.date(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), 365*20)
+				source.WebProperties.PublicationDate = &webPublicationDate
+			}
+		}
+
+		source.UpdateSuggestKeys()
+		sources = append(sources, &source)
+
+	}
+
+	return sources
+
+}
+
+func (seeder *seeder) createDefinitions(sources []*types.Source, result *types.SeedResult) []*types.Definition {
+
+	definitions := []*types.Definition{}
+
+	if len(sources) == 0 {
+		return definitions
+	}
+
+	// the default categories in a fixed order, so the same seed creates the same data
+	categories := []types.DefinitionCategory{}
+	for _, category := range defaultCategories {
+		categories = append(categories, category.Key)
+	}
+	statuses := []types.DefinitionStatus{types.EnumDefinitionStatus.Approved, types.EnumDefinitionStatus.Pending, types.EnumDefinitionStatus.Declined}
+
+	for index := 0; index < seeder.options.Definitions; index++ {
+
+		// cycle through the categories first and then through the statuses, so every combination exists
+		category := categories[index%len(categories)]
+		status := statuses[(index/len(categories))%len(statuses)]
+
+		source := sources[seeder.random.Intn(len(sources))]
+		submittedDate := seeder.date(source.SubmittedDate, 180)
+
+		rejectionLog := []*types.Rejection{}
+
+		definition := types.Definition{
+			ID:             seeder.objectId(submittedDate),
+			SubmittedBy:    seeder.user(),
+			SubmittedDate:  submittedDate,
+			LastChangeDate: submittedDate,
+			RejectionLog:   &rejectionLog,
+			Language:       types.EnumLanguage.English,
+			Content: fmt.Sprintf("%s is %s %s.",
+				seeder.pick(seedDefinitionSubjects[category]),
+				seeder.pick(seedDefinitionAbilities),
+				seeder.pick(seedDefinitionQualifiers)),
+			Source:     source.ID,
+			Categories: []types.DefinitionCategory{category},
+		}
+
+		// quotes of journal articles are located on one of the pages of the article
+		if source.JournalProperties != nil {
+			page := source.JournalProperties.PagesFrom + seeder.random.Intn(source.JournalProperties.PagesTo-source.JournalProperties.PagesFrom+1)
+			definition.Locator = &types.Locator{Type: types.EnumLocatorType.Page, From: fmt.Sprintf("%d", page)}
+		}
+
+		switch status {
+		case types.EnumDefinitionStatus.Approved:
+			// some approved definitions were declined before
+			if seeder.random.Intn(4) == 0 {
+				seeder.reject(&definition)
+				definition.LastChangeDate = definition.GetLatestRejection().Add(time.Duration(1+seeder.random.Intn(72)) * time.Hour)
+			}
+			approvedDate := definition.LastChangeDate.Add(time.Duration(1+seeder.random.Intn(240)) * time.Hour)
+			moderator := seeder.options.ModeratorId
+			definition.Approved = true
+			definition.ApprovedBy = &moderator
+			definition.ApprovedDate = &approvedDate
+			result.ApprovedDefinitions++
+		case types.EnumDefinitionStatus.Pending:
+			// some pending definitions were changed after a rejection
+			if seeder.random.Intn(3) == 0 {
+				seeder.reject(&definition)
+				definition.LastChangeDate = definition.GetLatestRejection().Add(time.Duration(1+seeder.random.Intn(72)) * time.Hour)
+			}
+			result.PendingDefinitions++
+		case types.EnumDefinitionStatus.Declined:
+			seeder.reject(&definition)
+			result.DeclinedDefinitions++
+		}
+
+		definitions = append(definitions, &definition)
+
+	}
+
+	return definitions
+
+}
+
+func (seeder *seeder) reject(definition *types.Definition) {
+
+	rejectedDate := definition.LastChangeDate.Add(time.Duration(1+seeder.random.Intn(240)) * time.Hour)
+
+	*definition.RejectionLog = append(*definition.RejectionLog, &types.Rejection{
+		ID:           seeder.objectId(rejectedDate),
+		RejectedBy:   seeder.options.ModeratorId,
+		RejectedDate: rejectedDate,
+		Content:      seeder.pick(seedRejectionReasons),
+	})
+
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
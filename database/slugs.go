@@ -0,0 +1,359 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"github.com/gosimple/slug"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxSlugLength = 60
+const maxSlugAttempts = 1000
+
+/* Number of words of the content used for the slug of a definition */
+const definitionSlugWords = 8
+
+var slugsCollection *mongo.Collection
+
+/* The unique index guarantees, that a slug (current or alias) belongs to only one entity */
+func createSlugIndexes() error {
+
+	_, err := slugsCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "entity", Value: 1}, {Key: "slug", Value: 1}}, Options: options.Index().SetName("entity_slug").SetUnique(true)},
+		{Keys: bson.D{{Key: "entity", Value: 1}, {Key: "target", Value: 1}}, Options: options.Index().SetName("entity_target")},
+	})
+
+	return err
+
+}
+
+func dropSlugIndexes() error {
+
+	for _, name := range []string{"entity_slug", "entity_target"} {
+		_, err := slugsCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+/* Slug of the text, shortened at a word boundary. Falls back to the entity name, if the text has no usable characters. */
+func createSlugBase(entity types.SlugEntity, text string) string {
+
+	base := slug.Make(text)
+
+	if len(base) > maxSlugLength {
+
+		base = base[:maxSlugLength]
+
+		if index := strings.LastIndex(base, "-"); index > 0 {
+			base = base[:index]
+		}
+
+	}
+
+	if len(base) == 0 {
+		return entity.String()
+	}
+
+	return base
+
+}
+
+/* A slug matches the base, if it is the base itself or the base with a numeric suffix from a collision */
+func slugMatchesBase(currentSlug string, base string) bool {
+
+	if currentSlug == base {
+		return true
+	}
+
+	if !strings.HasPrefix(currentSlug, base+"-") {
+		return false
+	}
+
+	suffix, err := strconv.Atoi(strings.TrimPrefix(currentSlug, base+"-"))
+
+	return err == nil && suffix >= 2
+
+}
+
+func getAuthorSlugText(author *types.Author) string {
+	return author.GetDisplayName()
+}
+
+func getSourceSlugText(source *types.Source) string {
+	return source.GetDisplayTitle()
+}
+
+func getDefinitionSlugText(definition *types.Definition) string {
+
+	words := strings.Fields(definition.Content)
+
+	if len(words) > definitionSlugWords {
+		words = words[:definitionSlugWords]
+	}
+
+	return strings.Join(words, " ")
+
+}
+
+/*
+Registers the first free slug of "base", "base-2", "base-3", ... for the target and turns the other slugs
+of the target into aliases. A former slug of the same target can be taken again. The context can be the
+context of a transaction, which writes the slug together with its entity.
+*/
+func assignSlug(ctx context.Context, entity types.SlugEntity, target primitive.ObjectID, text string) (string, error) {
+
+	base := createSlugBase(entity, text)
+
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+
+		candidate := base
+		if attempt > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		// a duplicate key error aborts a transaction, so slugs of other entities are skipped beforehand
+		taken, err := slugsCollection.CountDocuments(ctx, bson.M{"entity": entity, "slug": candidate, "target": bson.M{"$ne": target}})
+
+		if err != nil {
+			return "", err
+		}
+
+		if taken > 0 {
+			continue
+		}
+
+		err = registerSlug(ctx, entity, target, candidate)
+
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		return candidate, nil
+
+	}
+
+	return "", constants.ErrorSlugUnavailable
+
+}
+
+/* Returns a duplicate key error, if the slug belongs to another entity */
+func registerSlug(ctx context.Context, entity types.SlugEntity, target primitive.ObjectID, slug string) error {
+
+	filter := bson.M{"entity": entity, "slug": slug, "target": target}
+	update := bson.M{
+		"$set":         bson.M{"alias": false},
+		"$setOnInsert": bson.M{"created_date": time.Now()},
+	}
+
+	_, err := slugsCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = slugsCollection.UpdateMany(ctx,
+		bson.M{"entity": entity, "target": target, "slug": bson.M{"$ne": slug}, "alias": false},
+		bson.M{"$set": bson.M{"alias": true}},
+	)
+
+	return err
+
+}
+
+/* Keeps the current slug, if the text still leads to it. Otherwise a new slug is assigned and the current one becomes an alias. */
+func updateSlug(ctx context.Context, entity types.SlugEntity, target primitive.ObjectID, currentSlug string, text string) (string, error) {
+
+	if len(currentSlug) > 0 && slugMatchesBase(currentSlug, createSlugBase(entity, text)) {
+		return currentSlug, nil
+	}
+
+	return assignSlug(ctx, entity, target, text)
+
+}
+
+func deleteSlugs(ctx context.Context, entity types.SlugEntity, target primitive.ObjectID) error {
+
+	_, err := slugsCollection.DeleteMany(ctx, bson.M{"entity": entity, "target": target})
+	return err
+
+}
+
+/* Returns the target of the slug and whether the slug is only an alias */
+func resolveSlug(entity types.SlugEntity, slug string) (*types.Slug, error) {
+
+	var entry types.Slug
+	err := slugsCollection.FindOne(dbContext, bson.M{"entity": entity, "slug": slug}).Decode(&entry)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorSlugNotFound
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+
+}
+
+/* The returned bool is true, if the slug is an alias and the client should be redirected to the current slug */
+func GetAuthorBySlug(slug string) (*types.Author, bool, error) {
+
+	entry, err := resolveSlug(types.EnumSlugEntity.Author, slug)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	author, err := GetAuthor(entry.Target)
+
+	return author, entry.Alias, err
+
+}
+
+func GetSourceBySlug(slug string) (*types.Source, bool, error) {
+
+	entry, err := resolveSlug(types.EnumSlugEntity.Source, slug)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	source, err := GetSource(entry.Target)
+
+	return source, entry.Alias, err
+
+}
+
+func GetDefinitionBySlug(slug string) (*types.Definition, bool, error) {
+
+	entry, err := resolveSlug(types.EnumSlugEntity.Definition, slug)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	definition, err := GetDefinitionByObjectId(entry.Target)
+
+	return definition, entry.Alias, err
+
+}
+
+/*
+Makes sure every author, source and definition has a registered slug, which matches its current name. Existing
+slugs are registered first, so they are kept or become aliases. Used after imports and by the slug migration.
+*/
+func syncSlugs() error {
+
+	authors, err := getDocuments[types.Author](authorsCollection, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+
+	if err != nil {
+		return err
+	}
+
+	for _, author := range authors {
+
+		err = syncSlug(authorsCollection, types.EnumSlugEntity.Author, author.ID, author.SlugId, getAuthorSlugText(author))
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	sources, err := getDocuments[types.Source](sourcesCollection, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+
+		err = syncSlug(sourcesCollection, types.EnumSlugEntity.Source, source.ID, source.SlugId, getSourceSlugText(source))
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	definitions, err := getDocuments[types.Definition](definitionsCollection, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+
+		err = syncSlug(definitionsCollection, types.EnumSlugEntity.Definition, definition.ID, definition.SlugId, getDefinitionSlugText(definition))
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+func syncSlug(collection *mongo.Collection, entity types.SlugEntity, target primitive.ObjectID, currentSlug string, text string) error {
+
+	if len(currentSlug) > 0 {
+
+		// checked beforehand like in assignSlug, the unique index does not exist before the migration create_slug_indexes
+		taken, err := slugsCollection.CountDocuments(dbContext, bson.M{"entity": entity, "slug": currentSlug, "target": bson.M{"$ne": target}})
+
+		if err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			currentSlug = ""
+		} else {
+
+			err = registerSlug(dbContext, entity, target, currentSlug)
+
+			// the slug was taken by another entity in the meantime, so a new one is needed
+			if mongo.IsDuplicateKeyError(err) {
+				currentSlug = ""
+			} else if err != nil {
+				return err
+			}
+
+		}
+
+	}
+
+	newSlug, err := updateSlug(dbContext, entity, target, currentSlug, text)
+
+	if err != nil {
+		return err
+	}
+
+	if newSlug == currentSlug {
+		return nil
+	}
+
+	_, err = collection.UpdateByID(dbContext, target, bson.M{"$set": bson.M{"slug_id": newSlug}})
+	return err
+
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"yacoid_server/types"
+)
+
+func TestCreateSlugBase(t *testing.T) {
+
+	tests := []struct {
+		entity   types.SlugEntity
+		text     string
+		expected string
+	}{
+		{types.EnumSlugEntity.Author, "Alan Turing", "alan-turing"},
+		{types.EnumSlugEntity.Author, "  Alan   Turing  ", "alan-turing"},
+		{types.EnumSlugEntity.Author, "Café Crème", "cafe-creme"},
+		{types.EnumSlugEntity.Author, "Łódź", "lodz"},
+		{types.EnumSlugEntity.Source, "Über Größe", "uber-grosse"},
+		{types.EnumSlugEntity.Author, "", "author"},
+		{types.EnumSlugEntity.Source, "!!!", "source"},
+		{types.EnumSlugEntity.Definition, "   ", "definition"},
+		// a trailing number stays part of the base
+		{types.EnumSlugEntity.Source, "Computing Machinery 2", "computing-machinery-2"},
+		// shortened at the last word boundary before the maximum length
+		{types.EnumSlugEntity.Definition, strings.Repeat("word ", 20), "word-word-word-word-word-word-word-word-word-word-word-word"},
+	}
+
+	for _, test := range tests {
+
+		actual := createSlugBase(test.entity, test.text)
+
+		if actual != test.expected {
+			t.Errorf("createSlugBase(%s, %q) = %q, expected %q", test.entity, test.text, actual, test.expected)
+		}
+
+		if len(actual) > maxSlugLength {
+			t.Errorf("createSlugBase(%s, %q) is longer than %d characters", test.entity, test.text, maxSlugLength)
+		}
+
+	}
+
+}
+
+func TestSlugMatchesBase(t *testing.T) {
+
+	tests := []struct {
+		slug     string
+		base     string
+		expected bool
+	}{
+		{"alan-turing", "alan-turing", true},
+		{"alan-turing-2", "alan-turing", true},
+		{"alan-turing-15", "alan-turing", true},
+		// suffixes start at 2, the first slug has none
+		{"alan-turing-1", "alan-turing", false},
+		{"alan-turing-0", "alan-turing", false},
+		{"alan-turing--2", "alan-turing", false},
+		{"alan-turing-x", "alan-turing", false},
+		{"alan-turing-2a", "alan-turing", false},
+		{"alan-turing-", "alan-turing", false},
+		{"alan-turingx", "alan-turing", false},
+		{"alan", "alan-turing", false},
+		{"turing-2", "alan-turing", false},
+		{"author-3", "author", true},
+	}
+
+	for _, test := range tests {
+		if actual := slugMatchesBase(test.slug, test.base); actual != test.expected {
+			t.Errorf("slugMatchesBase(%q, %q) = %v, expected %v", test.slug, test.base, actual, test.expected)
+		}
+	}
+
+}
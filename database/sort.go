@@ -0,0 +1,146 @@
+package database
+
+import (
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slices"
+)
+
+type sortEntity int
+
+const (
+	sortDefinitions sortEntity = iota
+	sortSources
+	sortAuthors
+)
+
+const sortKeyField = "sort_key"
+
+var allowedSortFields = map[sortEntity][]types.SortField{
+	sortDefinitions: {
+		types.EnumSortField.SubmittedDate, types.EnumSortField.ApprovedDate, types.EnumSortField.LastChangeDate,
+		types.EnumSortField.Relevance, types.EnumSortField.AuthorLastName, types.EnumSortField.SourceTitle,
+		types.EnumSortField.Score,
+	},
+	sortSources: {
+		types.EnumSortField.SubmittedDate, types.EnumSortField.ApprovedDate, types.EnumSortField.LastChangeDate,
+		types.EnumSortField.Relevance, types.EnumSortField.AuthorLastName, types.EnumSortField.SourceTitle,
+	},
+	sortAuthors: {
+		types.EnumSortField.SubmittedDate, types.EnumSortField.ApprovedDate, types.EnumSortField.LastChangeDate,
+		types.EnumSortField.Relevance, types.EnumSortField.AuthorLastName,
+	},
+}
+
+/* Last name of a person or name of an organization. The prefix is "$" for the root document or "$$this." inside $map. */
+func authorNameExpression(prefix string) bson.D {
+	return bson.D{{Key: "$toLower", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+		prefix + "person_properties.last_name",
+		prefix + "organization_properties.organization_name",
+		"",
+	}}}}}
+}
+
+func sourceTitleExpression(prefix string) bson.D {
+	return bson.D{{Key: "$toLower", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+		prefix + "book_properties.title",
+		prefix + "journal_properties.title",
+		prefix + "web_properties.article_name",
+		"",
+	}}}}}
+}
+
+/* Alphabetically first author name of the authors in the given array field */
+func firstAuthorNameExpression(authorsField string) bson.D {
+	return bson.D{{Key: "$min", Value: bson.D{{Key: "$map", Value: bson.D{
+		{Key: "input", Value: authorsField},
+		{Key: "in", Value: authorNameExpression("$$this.")},
+	}}}}}
+}
+
+/*
+Returns the stages adding the sort key as "sort_key" and the $sort stage. Both are separated, because the
+sort key may use the text score, which is not available inside $facet. Without a sort the documents are
+sorted by their ID. The ID is always used as tiebreaker, so the order is stable.
+*/
+func createSortStages(entity sortEntity, sort *types.SortRequest, textSearch bool) (bson.A, bson.D, error) {
+
+	if sort == nil {
+		return bson.A{}, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}, nil
+	}
+
+	if !slices.Contains(allowedSortFields[entity], sort.Field) {
+		return nil, nil, constants.ErrorInvalidSort
+	}
+
+	if sort.Field == types.EnumSortField.Relevance && !textSearch {
+		return nil, nil, constants.ErrorInvalidSort
+	}
+
+	stages := bson.A{}
+	temporaryFields := bson.D{}
+
+	var sortKey interface{}
+
+	switch sort.Field {
+	case types.EnumSortField.SubmittedDate, types.EnumSortField.ApprovedDate, types.EnumSortField.LastChangeDate:
+		// missing dates (e.g. not yet approved) get the zero time, so cursors can compare them
+		sortKey = bson.D{{Key: "$ifNull", Value: bson.A{"$" + sort.Field.String(), time.Time{}}}}
+	case types.EnumSortField.Relevance:
+		sortKey = bson.D{{Key: "$meta", Value: "textScore"}}
+	case types.EnumSortField.Score:
+		// definitions without ratings have the score 0
+		sortKey = bson.D{{Key: "$ifNull", Value: bson.A{"$rating.score", 0}}}
+	case types.EnumSortField.AuthorLastName:
+
+		switch entity {
+		case sortAuthors:
+			sortKey = authorNameExpression("$")
+		case sortSources:
+			stages = append(stages, lookupStage("authors", "authors", "sort_authors"))
+			temporaryFields = append(temporaryFields, bson.E{Key: "sort_authors", Value: 0})
+			sortKey = firstAuthorNameExpression("$sort_authors")
+		case sortDefinitions:
+			stages = append(stages, lookupStage("sources", "source", "sort_source"), lookupStage("authors", "sort_source.authors", "sort_authors"))
+			temporaryFields = append(temporaryFields, bson.E{Key: "sort_source", Value: 0}, bson.E{Key: "sort_authors", Value: 0})
+			sortKey = firstAuthorNameExpression("$sort_authors")
+		}
+
+	case types.EnumSortField.SourceTitle:
+
+		switch entity {
+		case sortSources:
+			sortKey = sourceTitleExpression("$")
+		case sortDefinitions:
+			stages = append(stages, lookupStage("sources", "source", "sort_source"))
+			temporaryFields = append(temporaryFields, bson.E{Key: "sort_source", Value: 0})
+			sortKey = bson.D{{Key: "$arrayElemAt", Value: bson.A{
+				bson.D{{Key: "$map", Value: bson.D{
+					{Key: "input", Value: "$sort_source"},
+					{Key: "in", Value: sourceTitleExpression("$$this.")},
+				}}},
+				0,
+			}}}
+		}
+
+	}
+
+	stages = append(stages, bson.D{{Key: "$addFields", Value: bson.D{{Key: sortKeyField, Value: sortKey}}}})
+
+	if len(temporaryFields) > 0 {
+		stages = append(stages, bson.D{{Key: "$project", Value: temporaryFields}})
+	}
+
+	direction := 1
+	if sort.IsDescending() {
+		direction = -1
+	}
+
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: sortKeyField, Value: direction}, {Key: "_id", Value: direction}}}}
+
+	return stages, sortStage, nil
+
+}
@@ -2,7 +2,6 @@ package database
 
 import (
 	"time"
-	"yacoid_server/auth"
 	"yacoid_server/common"
 	"yacoid_server/constants"
 	"yacoid_server/types"
@@ -16,62 +15,98 @@ import (
 
 func SourceToResponse(source *types.Source) (*types.SourceResponse, error) {
 
-	response := types.SourceResponse{}
-	response.ID = source.ID
+	responses, err := sourcesToResponses([]*types.Source{source}, map[string]string{})
 
-	response.SubmittedBy = source.SubmittedBy
-	nickname, err := auth.GetNicknameOfUser(source.SubmittedBy)
-
-	if err == nil {
-		response.SubmittedByName = nickname
-	} else {
-		response.SubmittedByName = "<deleted>"
+	if err != nil {
+		return nil, err
 	}
 
-	response.SubmittedDate = source.SubmittedDate
-	response.Type = source.Type
+	return &(*responses)[0], nil
 
-	authors, err := GetAuthorsByIds(&source.Authors)
+}
 
-	if err != nil {
-		return nil, err
+func SourcesToResponses(sources *[]*types.Source) (*[]types.SourceResponse, error) {
+	return sourcesToResponses(*sources, map[string]string{})
+}
+
+/* The authors of all sources are loaded with one query */
+func sourcesToResponses(sources []*types.Source, nicknames map[string]string) (*[]types.SourceResponse, error) {
+
+	authorIds := []primitive.ObjectID{}
+	for _, source := range sources {
+		authorIds = append(authorIds, source.Authors...)
 	}
 
-	authorResponses, err := AuthorsToResponses(authors)
+	authors, err := GetAuthorsByIds(&authorIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	response.Authors = *authorResponses
+	authorResponses := map[primitive.ObjectID]*types.AuthorResponse{}
+	for _, author := range *authors {
+		authorResponses[author.ID] = authorToResponse(author, nicknames)
+	}
 
-	response.BookProperties = source.BookProperties
-	response.JournalProperties = source.JournalProperties
-	response.WebProperties = source.WebProperties
+	responses := []types.SourceResponse{}
 
-	return &response, nil
+	for _, source := range sources {
 
-}
+		response := types.SourceResponse{}
+		response.ID = source.ID
+		response.SlugId = source.SlugId
+		response.Language = source.Language
 
-func SourcesToResponses(sources *[]*types.Source) (*[]types.SourceResponse, error) {
+		response.SubmittedBy = source.SubmittedBy
+		response.SubmittedByName = getNickname(nicknames, source.SubmittedBy)
 
-	responses := []types.SourceResponse{}
+		response.SubmittedDate = source.SubmittedDate
+		response.Type = source.Type
 
-	for _, source := range *sources {
+		// in the order of the source, deleted authors are skipped
+		response.Authors = []types.AuthorResponse{}
+		for _, authorId := range source.Authors {
+			if authorResponse, ok := authorResponses[authorId]; ok {
+				response.Authors = append(response.Authors, *authorResponse)
+			}
+		}
 
-		response, err := SourceToResponse(source)
+		response.BookProperties = source.BookProperties
+		response.JournalProperties = source.JournalProperties
+		response.WebProperties = source.WebProperties
 
-		if err != nil {
-			return nil, err
-		}
+		responses = append(responses, response)
 
-		responses = append(responses, *response)
 	}
 
 	return &responses, nil
 
 }
 
+/* The responses of the sources with the IDs, keyed by their ID */
+func getSourceResponsesByIds(ids []primitive.ObjectID, nicknames map[string]string) (map[primitive.ObjectID]*types.SourceResponse, error) {
+
+	sources, err := getDocuments[types.Source](sourcesCollection, bson.M{"_id": bson.M{"$in": ids}}, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := sourcesToResponses(sources, nicknames)
+
+	if err != nil {
+		return nil, err
+	}
+
+	responseMap := map[primitive.ObjectID]*types.SourceResponse{}
+	for index := range *responses {
+		responseMap[(*responses)[index].ID] = &(*responses)[index]
+	}
+
+	return responseMap, nil
+
+}
+
 func CreateSource(request *types.CreateSourceRequest, userId string) (*primitive.ObjectID, error) {
 
 	var source types.Source
@@ -86,6 +121,11 @@ func CreateSource(request *types.CreateSourceRequest, userId string) (*primitive
 	source.Approved = false
 
 	source.Type = request.Type
+	source.Language = types.DefaultLanguage
+
+	if request.Language != nil {
+		source.Language = *request.Language
+	}
 
 	if request.Type == types.EnumSourceType.Book && request.BookProperties != nil {
 		source.BookProperties = request.BookProperties
@@ -110,8 +150,34 @@ func CreateSource(request *types.CreateSourceRequest, userId string) (*primitive
 	}
 
 	source.Authors = authorIds
+	source.UpdateSuggestKeys()
+
+	// the slug and the references of the authors are registered together with the source
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+
+		slugId, err := assignSlug(ctx, types.EnumSlugEntity.Source, source.ID, getSourceSlugText(&source))
+
+		if err != nil {
+			return err
+		}
+
+		source.SlugId = slugId
+
+		err = touchReferencedAuthors(ctx, source.Authors, now)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = sourcesCollection.InsertOne(ctx, source)
+
+		if err != nil {
+			return err
+		}
 
-	_, err = sourcesCollection.InsertOne(dbContext, source)
+		return recordDomainEvent(ctx, types.EnumDomainEventType.SourceSubmitted, source.ID, userId, nil)
+
+	})
 
 	if err != nil {
 		return nil, err
@@ -121,7 +187,7 @@ func CreateSource(request *types.CreateSourceRequest, userId string) (*primitive
 
 }
 
-func DeleteSource(sourceId string) (*[]string, error) {
+func DeleteSource(sourceId string, userId string) (*[]string, error) {
 
 	id, err := primitive.ObjectIDFromHex(sourceId)
 
@@ -129,38 +195,88 @@ func DeleteSource(sourceId string) (*[]string, error) {
 		return nil, err
 	}
 
-	definitions, err := GetDefinitionsWithSource(id)
-
-	if err != nil {
-		return nil, err
+	filter := bson.M{
+		"_id": id,
 	}
-	if len(*definitions) > 0 {
 
-		definitionIds := []string{}
+	var definitionIds []string
+
+	/*
+		The references are checked in the transaction, new references write the source (see touchReferencedSources),
+		so they conflict with the deletion and either the reference or the deletion is repeated.
+	*/
+	err = withTransaction(func(ctx mongo.SessionContext) error {
 
-		for _, definition := range *definitions {
-			definitionIds = append(definitionIds, definition.ID.Hex())
+		definitionIds = nil
+		referencingIds, err := definitionsCollection.Distinct(ctx, "_id", createSourceReferenceFilter(id))
+
+		if err != nil {
+			return err
 		}
 
-		return &definitionIds, constants.ErrorSourceDeletionBecauseInUse
+		if len(referencingIds) > 0 {
 
-	}
+			for _, definitionId := range referencingIds {
+				definitionIds = append(definitionIds, definitionId.(primitive.ObjectID).Hex())
+			}
 
-	filter := bson.M{
-		"_id": id,
+			return constants.ErrorSourceDeletionBecauseInUse
+
+		}
+
+		result, err := sourcesCollection.DeleteOne(ctx, filter)
+
+		if err != nil {
+			return err
+		}
+
+		if result.DeletedCount == 0 {
+			return constants.ErrorSourceNotFound
+		}
+
+		// the notes and slugs are deleted together with the source
+		err = deleteModeratorNotes(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		err = deleteSlugs(ctx, types.EnumSlugEntity.Source, id)
+
+		if err != nil {
+			return err
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.SourceDeleted, id, userId, nil)
+
+	})
+
+	if err == constants.ErrorSourceDeletionBecauseInUse {
+		return &definitionIds, err
 	}
 
-	result, err := sourcesCollection.DeleteOne(dbContext, filter)
+	return nil, err
+
+}
+
+/*
+Has to be called inside of withTransaction. Writes the referenced sources, so a concurrent deletion of one of them
+conflicts with the new reference. Sources, which were deleted in the meantime, fail the transaction.
+*/
+func touchReferencedSources(ctx mongo.SessionContext, ids []primitive.ObjectID, now time.Time) error {
+
+	uniqueIds := uniqueObjectIDs(ids)
+	result, err := sourcesCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": uniqueIds}}, bson.M{"$set": bson.M{"last_reference_date": now}})
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return nil, constants.ErrorSourceNotFound
+	if result.MatchedCount < int64(len(uniqueIds)) {
+		return constants.ErrorSourceNotFound
 	}
 
-	return nil, nil
+	return nil
 
 }
 
@@ -236,11 +352,16 @@ func GetSourcesWithAuthor(authorId primitive.ObjectID) (*[]*types.Source, error)
 
 }
 
-func ApproveSource(sourceId primitive.ObjectID, userId string) error {
+/* Has to be called inside of withTransaction, sources are only approved together with a definition */
+func ApproveSource(ctx mongo.SessionContext, sourceId primitive.ObjectID, userId string) error {
 
-	source, err := GetSource(sourceId)
+	var source types.Source
+	err := sourcesCollection.FindOne(ctx, bson.M{"_id": sourceId}).Decode(&source)
 
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return constants.ErrorSourceNotFound
+		}
 		return err
 	}
 
@@ -248,7 +369,7 @@ func ApproveSource(sourceId primitive.ObjectID, userId string) error {
 		return constants.ErrorSourceAlreadyApproved
 	}
 
-	err = ApproveAuthors(source.Authors, userId)
+	err = ApproveAuthors(ctx, source.Authors, userId)
 
 	if err != nil && err != constants.ErrorAuthorAlreadyApproved {
 		return err
@@ -268,7 +389,7 @@ func ApproveSource(sourceId primitive.ObjectID, userId string) error {
 	}
 
 	var result bson.M
-	updateError := sourcesCollection.FindOneAndUpdate(dbContext, filter, update, nil).Decode(&result)
+	updateError := sourcesCollection.FindOneAndUpdate(ctx, filter, update, nil).Decode(&result)
 
 	if updateError != nil {
 		if updateError == mongo.ErrNoDocuments {
@@ -277,7 +398,7 @@ func ApproveSource(sourceId primitive.ObjectID, userId string) error {
 		return updateError
 	}
 
-	return nil
+	return recordDomainEvent(ctx, types.EnumDomainEventType.SourceApproved, sourceId, userId, nil)
 
 }
 
@@ -308,6 +429,10 @@ func ChangeSource(request *types.ChangeSourceRequest, userId string, validate *v
 		changed = true
 	}
 
+	if request.Language != nil {
+		source.Language = *request.Language
+	}
+
 	if request.Authors != nil {
 
 		authorIds, err := stringsToObjectIDs(request.Authors)
@@ -494,22 +619,45 @@ func ChangeSource(request *types.ChangeSourceRequest, userId string, validate *v
 	/* Update existing source */
 
 	source.LastChangeDate = time.Now()
+	source.UpdateSuggestKeys()
 
 	filter := bson.M{
 		"_id": id,
 	}
 
-	result, err := sourcesCollection.ReplaceOne(dbContext, filter, source, nil)
+	return withTransaction(func(ctx mongo.SessionContext) error {
 
-	if err != nil {
-		return err
-	}
+		slugId, err := updateSlug(ctx, types.EnumSlugEntity.Source, source.ID, source.SlugId, getSourceSlugText(source))
 
-	if result.MatchedCount == 0 {
-		return constants.ErrorSourceNotFound
-	}
+		if err != nil {
+			return err
+		}
 
-	return nil
+		source.SlugId = slugId
+
+		if request.Authors != nil {
+
+			err = touchReferencedAuthors(ctx, source.Authors, source.LastChangeDate)
+
+			if err != nil {
+				return err
+			}
+
+		}
+
+		result, err := sourcesCollection.ReplaceOne(ctx, filter, source, nil)
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return constants.ErrorSourceNotFound
+		}
+
+		return recordDomainEvent(ctx, types.EnumDomainEventType.SourceChanged, id, userId, nil)
+
+	})
 
 }
 
@@ -546,13 +694,35 @@ func GetSources(request *types.SourcePageRequest) ([]*types.Source, error) {
 		return nil, constants.ErrorInvalidType
 	}
 
-	options := options.FindOptions{}
+	filter := CreateSourceFilterQuery(request.Filter)
+	textSearch := request.Filter != nil && request.Filter.Text != nil && len(*request.Filter.Text) > 0
 
-	options.SetLimit(int64(request.PageSize))
-	options.SetSkip(int64((request.Page - 1) * request.PageSize))
+	sortKeyStages, sortStage, err := createSortStages(sortSources, request.Sort, textSearch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$match", Value: filter}}}
+	pipeline = append(pipeline, sortKeyStages...)
+	pipeline = append(pipeline,
+		sortStage,
+		bson.D{{Key: "$skip", Value: int64((request.Page - 1) * request.PageSize)}},
+		bson.D{{Key: "$limit", Value: int64(request.PageSize)}},
+	)
+
+	return aggregateDocuments[types.Source](sourcesCollection, pipeline, options.Aggregate())
+
+}
+
+func GetSourceCursorPage(request *types.SourceCursorRequest) (*types.CursorPage[types.Source], error) {
 
 	filter := CreateSourceFilterQuery(request.Filter)
-	return getDocuments[types.Source](sourcesCollection, filter, &options)
+	textSearch := request.Filter != nil && request.Filter.Text != nil && len(*request.Filter.Text) > 0
+
+	filterStages := bson.A{bson.D{{Key: "$match", Value: filter}}}
+
+	return getCursorPage[types.Source](sourcesCollection, sortSources, filterStages, request.Filter, textSearch, &request.CursorRequest)
 
 }
 
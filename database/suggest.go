@@ -0,0 +1,145 @@
+package database
+
+import (
+	"strings"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Suggestions need at least this share of the trigrams of the query, if not all words match as prefix */
+const minSuggestTrigramScore = 0.3
+
+/*
+Documents match, if every word of the query is a prefix of a word (or identifier) of the document or if
+enough trigrams match. Prefix matches are ranked first, then the share of matching trigrams decides.
+*/
+func createSuggestPipeline(query string, limit int, onlyApproved bool) (bson.A, error) {
+
+	words := common.NormalizeSearchText(query)
+
+	if len(words) == 0 {
+		return nil, constants.ErrorQueryValueRequired
+	}
+
+	if limit <= 0 {
+		limit = types.DefaultSuggestionLimit
+	} else if limit > types.MaxSuggestionLimit {
+		limit = types.MaxSuggestionLimit
+	}
+
+	trigrams := common.CreateSearchTrigrams(words)
+	identifier := strings.Join(words, "")
+
+	conditions := bson.A{
+		bson.D{{Key: "suggest.prefixes", Value: bson.D{{Key: "$all", Value: words}}}},
+		bson.D{{Key: "suggest.prefixes", Value: identifier}},
+	}
+
+	if len(trigrams) > 0 {
+		conditions = append(conditions, bson.D{{Key: "suggest.trigrams", Value: bson.D{{Key: "$in", Value: trigrams}}}})
+	}
+
+	match := bson.D{{Key: "$or", Value: conditions}}
+
+	if onlyApproved {
+		match = append(match, bson.E{Key: "approved", Value: true})
+	}
+
+	prefixScore := bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "$setIsSubset", Value: bson.A{words, "$suggest.prefixes"}}},
+			bson.D{{Key: "$in", Value: bson.A{identifier, "$suggest.prefixes"}}},
+		}}},
+		1,
+		0,
+	}}}
+
+	var trigramScoreExpression interface{} = 0.0
+
+	if len(trigrams) > 0 {
+		trigramScoreExpression = bson.D{{Key: "$divide", Value: bson.A{
+			bson.D{{Key: "$size", Value: bson.D{{Key: "$setIntersection", Value: bson.A{trigrams, "$suggest.trigrams"}}}}},
+			len(trigrams),
+		}}}
+	}
+
+	return bson.A{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "suggest_prefix_score", Value: prefixScore},
+			{Key: "suggest_trigram_score", Value: trigramScoreExpression},
+		}}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "suggest_prefix_score", Value: 1}},
+			bson.D{{Key: "suggest_trigram_score", Value: bson.D{{Key: "$gte", Value: minSuggestTrigramScore}}}},
+		}}}}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "suggest_prefix_score", Value: -1},
+			{Key: "suggest_trigram_score", Value: -1},
+			{Key: "approved", Value: -1},
+			{Key: "_id", Value: 1},
+		}}},
+		bson.D{{Key: "$limit", Value: int64(limit)}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "suggest", Value: 0},
+			{Key: "suggest_prefix_score", Value: 0},
+			{Key: "suggest_trigram_score", Value: 0},
+		}}},
+	}, nil
+
+}
+
+func suggestDocuments[T interface{}](collection *mongo.Collection, query string, limit int, onlyApproved bool) ([]*T, error) {
+
+	pipeline, err := createSuggestPipeline(query, limit, onlyApproved)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregateDocuments[T](collection, pipeline, options.Aggregate())
+
+}
+
+/* Ranked authors for a typeahead. Unapproved authors are only included, if onlyApproved is false. */
+func SuggestAuthors(query string, limit int, onlyApproved bool) ([]types.SuggestionResponse, error) {
+
+	authors, err := suggestDocuments[types.Author](authorsCollection, query, limit, onlyApproved)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []types.SuggestionResponse{}
+
+	for _, author := range authors {
+		suggestions = append(suggestions, author.ToSuggestionResponse())
+	}
+
+	return suggestions, nil
+
+}
+
+/* Ranked sources for a typeahead. Unapproved sources are only included, if onlyApproved is false. */
+func SuggestSources(query string, limit int, onlyApproved bool) ([]types.SuggestionResponse, error) {
+
+	sources, err := suggestDocuments[types.Source](sourcesCollection, query, limit, onlyApproved)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []types.SuggestionResponse{}
+
+	for _, source := range sources {
+		suggestions = append(suggestions, source.ToSuggestionResponse())
+	}
+
+	return suggestions, nil
+
+}
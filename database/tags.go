@@ -0,0 +1,363 @@
+package database
+
+import (
+	"sort"
+	"time"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var tagsCollection *mongo.Collection
+
+/* The unique index guarantees, that concurrent proposals of the same name create only one tag */
+func createTagIndexes() error {
+
+	_, err := tagsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetName("key").SetUnique(true),
+	})
+
+	return err
+
+}
+
+func dropTagIndexes() error {
+
+	_, err := tagsCollection.Indexes().DropOne(dbContext, "key")
+	return err
+
+}
+
+/*
+Returns the IDs of the tags with the given names. Unknown names are proposed as new unapproved tags, names of
+merged tags resolve to the tag they were merged into. It runs in the transaction of the definition, so proposed
+tags are only stored together with it.
+*/
+func proposeTags(ctx mongo.SessionContext, names []string, userId string) ([]primitive.ObjectID, error) {
+
+	tagIds := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+
+	for _, name := range names {
+
+		key := types.CreateTagKey(name)
+
+		if len(key) == 0 {
+			return nil, constants.ErrorInvalidTag
+		}
+
+		update := bson.M{"$setOnInsert": types.Tag{
+			ID:           primitive.NewObjectID(),
+			Name:         types.NormalizeTagName(name),
+			Key:          key,
+			ProposedBy:   userId,
+			ProposedDate: time.Now(),
+		}}
+
+		var tag types.Tag
+		err := tagsCollection.FindOneAndUpdate(ctx, bson.M{"key": key}, update, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&tag)
+
+		if err != nil {
+			return nil, err
+		}
+
+		id := tag.ID
+		if tag.MergedInto != nil {
+			id = *tag.MergedInto
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			tagIds = append(tagIds, id)
+		}
+
+	}
+
+	return tagIds, nil
+
+}
+
+/* The tags with the IDs, keyed by their ID */
+func getTagsByIds(tagIds []primitive.ObjectID) (map[primitive.ObjectID]*types.Tag, error) {
+
+	tagMap := map[primitive.ObjectID]*types.Tag{}
+
+	if len(tagIds) == 0 {
+		return tagMap, nil
+	}
+
+	tags, err := getDocuments[types.Tag](tagsCollection, bson.M{"_id": bson.M{"$in": tagIds}}, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		tagMap[tag.ID] = tag
+	}
+
+	return tagMap, nil
+
+}
+
+/* The tags of a definition in the order of the definition. Unapproved tags are only included with includeUnapproved. */
+func getDefinitionTags(tagIds []primitive.ObjectID, tagMap map[primitive.ObjectID]*types.Tag, includeUnapproved bool) []types.TagReference {
+
+	references := []types.TagReference{}
+
+	for _, id := range tagIds {
+		if tag, exists := tagMap[id]; exists && (tag.Approved || includeUnapproved) {
+			references = append(references, tag.ToReference())
+		}
+	}
+
+	return references
+
+}
+
+func getTagByObjectId(id primitive.ObjectID) (*types.Tag, error) {
+
+	var tag types.Tag
+	err := tagsCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&tag)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+
+}
+
+/* Returns the ID of the tag with the key of the name, following merges. Nil, if no such tag exists. */
+func findTagIdByName(name string) (*primitive.ObjectID, error) {
+
+	var tag types.Tag
+	err := tagsCollection.FindOne(dbContext, bson.M{"key": types.CreateTagKey(name)}).Decode(&tag)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if tag.MergedInto != nil {
+		return tag.MergedInto, nil
+	}
+
+	return &tag.ID, nil
+
+}
+
+/* All tags, which are not merged, with the number of approved definitions using them, most used first */
+func GetTags(includeUnapproved bool) ([]types.TagResponse, error) {
+
+	filter := bson.M{"merged_into": bson.M{"$exists": false}}
+
+	if !includeUnapproved {
+		filter["approved"] = true
+	}
+
+	tags, err := getDocuments[types.Tag](tagsCollection, filter, options.Find())
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := aggregateDocuments[struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}](definitionsCollection, bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"approved": true, "tags.0": bson.M{"$exists": true}}}},
+		bson.D{{Key: "$unwind", Value: "$tags"}},
+		bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$tags"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+	}, options.Aggregate())
+
+	if err != nil {
+		return nil, err
+	}
+
+	countMap := map[primitive.ObjectID]int{}
+	for _, count := range counts {
+		countMap[count.ID] = count.Count
+	}
+
+	responses := []types.TagResponse{}
+
+	for _, tag := range tags {
+		responses = append(responses, types.TagResponse{
+			ID:       tag.ID.Hex(),
+			Name:     tag.Name,
+			Key:      tag.Key,
+			Approved: tag.Approved,
+			Count:    countMap[tag.ID],
+		})
+	}
+
+	sort.SliceStable(responses, func(i, j int) bool {
+		if responses[i].Count != responses[j].Count {
+			return responses[i].Count > responses[j].Count
+		}
+		return responses[i].Key < responses[j].Key
+	})
+
+	return responses, nil
+
+}
+
+func ApproveTag(tagId string, userId string) error {
+
+	id, err := primitive.ObjectIDFromHex(tagId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	tag, err := getTagByObjectId(id)
+
+	if err != nil {
+		return err
+	}
+
+	if tag.MergedInto != nil {
+		return constants.ErrorTagMerged
+	}
+
+	if tag.Approved {
+		return constants.ErrorTagAlreadyApproved
+	}
+
+	_, err = tagsCollection.UpdateOne(dbContext, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"approved_by":   userId,
+		"approved_date": time.Now(),
+		"approved":      true,
+	}})
+
+	return err
+
+}
+
+/*
+Replaces the source tags by the target tag in all definitions. The source tags are kept as merged, so their
+names resolve to the target when they are proposed again.
+*/
+func MergeTags(request *types.MergeTagsRequest) error {
+
+	targetId, err := primitive.ObjectIDFromHex(request.TargetId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	target, err := getTagByObjectId(targetId)
+
+	if err != nil {
+		return err
+	}
+
+	if target.MergedInto != nil {
+		return constants.ErrorTagMerged
+	}
+
+	sourceIds, err := stringsToObjectIDs(&request.SourceIds)
+
+	if err != nil {
+		return err
+	}
+
+	for _, sourceId := range sourceIds {
+
+		if sourceId == targetId {
+			return constants.ErrorTagMergeIntoItself
+		}
+
+		source, err := getTagByObjectId(sourceId)
+
+		if err != nil {
+			return err
+		}
+
+		if source.MergedInto != nil {
+			return constants.ErrorTagMerged
+		}
+
+	}
+
+	for _, sourceId := range sourceIds {
+
+		// two updates, because one update can not add and remove on the same array
+		_, err = definitionsCollection.UpdateMany(dbContext, bson.M{"tags": sourceId}, bson.M{"$addToSet": bson.M{"tags": targetId}})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = definitionsCollection.UpdateMany(dbContext, bson.M{"tags": sourceId}, bson.M{"$pull": bson.M{"tags": sourceId}})
+
+		if err != nil {
+			return err
+		}
+
+		// tags merged into the source before now point to the target
+		_, err = tagsCollection.UpdateMany(dbContext, bson.M{"$or": bson.A{bson.M{"_id": sourceId}, bson.M{"merged_into": sourceId}}}, bson.M{"$set": bson.M{"merged_into": targetId}})
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+/* Removes the tag from all definitions and deletes it together with the tags merged into it */
+func DeleteTag(tagId string) error {
+
+	id, err := primitive.ObjectIDFromHex(tagId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	_, err = getTagByObjectId(id)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = definitionsCollection.UpdateMany(dbContext, bson.M{"tags": id}, bson.M{"$pull": bson.M{"tags": id}})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tagsCollection.DeleteMany(dbContext, bson.M{"$or": bson.A{bson.M{"_id": id}, bson.M{"merged_into": id}}})
+
+	return err
+
+}
+
+func createDefinitionTagIndex() error {
+
+	_, err := definitionsCollection.Indexes().CreateOne(dbContext, mongo.IndexModel{
+		Keys: bson.D{{Key: "tags", Value: 1}}, Options: options.Index().SetName("tags"),
+	})
+
+	return err
+
+}
+
+func dropDefinitionTagIndex() error {
+
+	_, err := definitionsCollection.Indexes().DropOne(dbContext, "tags")
+	return err
+
+}
@@ -0,0 +1,515 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+	"yacoid_server/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var webhooksCollection *mongo.Collection
+var webhookDeliveriesCollection *mongo.Collection
+
+/* Wakes up the worker, when new deliveries were created */
+var webhookSignal = make(chan struct{}, 1)
+
+const (
+	webhookPollInterval = 15 * time.Second
+	// a claimed delivery is retried after this time, if the server stopped during the attempt
+	webhookClaimTimeout = 2 * time.Minute
+)
+
+func createWebhookIndexes() error {
+
+	_, err := webhookDeliveriesCollection.Indexes().CreateMany(dbContext, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_date", Value: 1}}, Options: options.Index().SetName("status_next_attempt_date")},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_date", Value: -1}}, Options: options.Index().SetName("webhook_id_created_date")},
+		{
+			Keys: bson.D{{Key: "idempotency_key", Value: 1}},
+			Options: options.Index().
+				SetName("idempotency_key").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}),
+		},
+	})
+
+	return err
+
+}
+
+func dropWebhookIndexes() error {
+
+	for _, name := range []string{"status_next_attempt_date", "webhook_id_created_date", "idempotency_key"} {
+		_, err := webhookDeliveriesCollection.Indexes().DropOne(dbContext, name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func validateWebhookUrl(webhookUrl string) error {
+
+	parsed, err := url.Parse(webhookUrl)
+
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return constants.ErrorInvalidWebhookUrl
+	}
+
+	return nil
+
+}
+
+/* The returned webhook contains the secret, it is not shown again */
+func CreateWebhook(request *types.CreateWebhookRequest, userId string) (*types.Webhook, error) {
+
+	err := validateWebhookUrl(request.URL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := common.CreateRandomToken(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := types.Webhook{
+		ID:             primitive.NewObjectID(),
+		URL:            request.URL,
+		Secret:         secret,
+		Events:         []types.WebhookEvent{},
+		Active:         request.Active == nil || *request.Active,
+		CreatedBy:      userId,
+		CreatedDate:    now,
+		LastChangeDate: now,
+	}
+
+	if request.Description != nil {
+		webhook.Description = *request.Description
+	}
+
+	if request.Events != nil {
+		webhook.Events = *request.Events
+	}
+
+	_, err = webhooksCollection.InsertOne(dbContext, webhook)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+
+}
+
+func GetWebhooks() ([]*types.Webhook, error) {
+	return getDocuments[types.Webhook](webhooksCollection, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_date", Value: 1}}))
+}
+
+func getWebhookByObjectId(id primitive.ObjectID) (*types.Webhook, error) {
+
+	var webhook types.Webhook
+	err := webhooksCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&webhook)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+
+}
+
+func ChangeWebhook(request *types.ChangeWebhookRequest) error {
+
+	id, err := primitive.ObjectIDFromHex(request.ID)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	update := bson.D{{Key: "last_change_date", Value: time.Now()}}
+
+	if request.URL != nil {
+
+		err = validateWebhookUrl(*request.URL)
+
+		if err != nil {
+			return err
+		}
+
+		update = append(update, bson.E{Key: "url", Value: *request.URL})
+
+	}
+
+	if request.Description != nil {
+		update = append(update, bson.E{Key: "description", Value: *request.Description})
+	}
+
+	if request.Events != nil {
+		update = append(update, bson.E{Key: "events", Value: *request.Events})
+	}
+
+	if request.Active != nil {
+		update = append(update, bson.E{Key: "active", Value: *request.Active})
+	}
+
+	result, err := webhooksCollection.UpdateOne(dbContext, bson.M{"_id": id}, bson.M{"$set": update})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return constants.ErrorWebhookNotFound
+	}
+
+	return nil
+
+}
+
+/* Replaces the secret, pending deliveries are signed with the new secret */
+func RotateWebhookSecret(webhookId string) (*string, error) {
+
+	id, err := primitive.ObjectIDFromHex(webhookId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	secret, err := common.CreateRandomToken(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := webhooksCollection.UpdateOne(dbContext, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"secret":           secret,
+		"last_change_date": time.Now(),
+	}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, constants.ErrorWebhookNotFound
+	}
+
+	return &secret, nil
+
+}
+
+/* Deletes the webhook with its delivery log */
+func DeleteWebhook(webhookId string) error {
+
+	id, err := primitive.ObjectIDFromHex(webhookId)
+
+	if err != nil {
+		return constants.ErrorInvalidID
+	}
+
+	result, err := webhooksCollection.DeleteOne(dbContext, bson.M{"_id": id})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return constants.ErrorWebhookNotFound
+	}
+
+	_, err = webhookDeliveriesCollection.DeleteMany(dbContext, bson.M{"webhook_id": id})
+	return err
+
+}
+
+/* The delivery log of the webhook, newest first, and the total count */
+func GetWebhookDeliveries(request *types.WebhookDeliveryPageRequest) ([]*types.WebhookDelivery, int, error) {
+
+	webhookId, err := primitive.ObjectIDFromHex(request.WebhookId)
+
+	if err != nil {
+		return nil, 0, constants.ErrorInvalidID
+	}
+
+	filter := bson.M{"webhook_id": webhookId}
+
+	if request.Status != nil {
+		filter["status"] = *request.Status
+	}
+
+	total, err := countDocuments(webhookDeliveriesCollection, filter, nil)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_date", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((request.Page - 1) * request.PageSize)).
+		SetLimit(int64(request.PageSize))
+
+	deliveries, err := getDocuments[types.WebhookDelivery](webhookDeliveriesCollection, filter, findOptions)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+
+}
+
+/* Sends the stored payload of the delivery again and returns the attempt */
+func RedeliverWebhook(deliveryId string) (*types.WebhookAttempt, error) {
+
+	id, err := primitive.ObjectIDFromHex(deliveryId)
+
+	if err != nil {
+		return nil, constants.ErrorInvalidID
+	}
+
+	var delivery types.WebhookDelivery
+	err = webhookDeliveriesCollection.FindOne(dbContext, bson.M{"_id": id}).Decode(&delivery)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, constants.ErrorWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return attemptWebhookDelivery(&delivery, true)
+
+}
+
+/*
+Handler of the domain events. Creates a delivery for every active webhook subscribed to the event and wakes up
+the worker. The deliveries have idempotency keys, so a repeated call does not create a delivery twice.
+*/
+func handleWebhookEvent(event *types.DomainEvent, idempotencyKey string) error {
+
+	// not every domain event is published to webhooks
+	webhookEvent, err := types.ParseStringToWebhookEvent(event.Type.String())
+
+	if err != nil {
+		return nil
+	}
+
+	webhooks, err := getDocuments[types.Webhook](webhooksCollection, bson.M{"active": true}, options.Find())
+
+	if err != nil {
+		return err
+	}
+
+	created := false
+
+	for _, subscriber := range webhooks {
+
+		if !subscriber.IsSubscribed(webhookEvent) {
+			continue
+		}
+
+		now := time.Now()
+		delivery := types.WebhookDelivery{
+			ID:              primitive.NewObjectID(),
+			WebhookId:       subscriber.ID,
+			Event:           webhookEvent,
+			Status:          types.EnumWebhookDeliveryStatus.Pending,
+			Attempts:        []types.WebhookAttempt{},
+			NextAttemptDate: &now,
+			CreatedDate:     now,
+			IdempotencyKey:  idempotencyKey + ":" + subscriber.ID.Hex(),
+		}
+
+		payload, err := json.Marshal(types.WebhookPayload{
+			ID:          delivery.ID.Hex(),
+			Event:       webhookEvent,
+			CreatedDate: event.CreatedDate,
+			Data: types.WebhookPayloadData{
+				Entity:   webhookEvent.GetEntity(),
+				EntityId: event.EntityId.Hex(),
+				UserId:   event.UserId,
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		delivery.Payload = string(payload)
+
+		_, err = webhookDeliveriesCollection.InsertOne(dbContext, delivery)
+
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		created = true
+
+	}
+
+	if created {
+		select {
+		case webhookSignal <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+
+}
+
+/* Delivers due deliveries in the background, until the process ends */
+func StartWebhookWorker() {
+
+	go func() {
+
+		for {
+
+			deliverDueWebhooks()
+
+			select {
+			case <-webhookSignal:
+			case <-time.After(webhookPollInterval):
+			}
+
+		}
+
+	}()
+
+}
+
+func deliverDueWebhooks() {
+
+	for {
+
+		delivery, err := claimDueWebhookDelivery()
+
+		if err != nil {
+			fmt.Printf("Could not load webhook deliveries: %v\n", err)
+			return
+		}
+
+		if delivery == nil {
+			return
+		}
+
+		_, err = attemptWebhookDelivery(delivery, false)
+
+		if err != nil {
+			fmt.Printf("Could not record webhook delivery %s: %v\n", delivery.ID.Hex(), err)
+		}
+
+	}
+
+}
+
+/* Claims the oldest due delivery by moving its next attempt, so a delivery is only attempted once at a time */
+func claimDueWebhookDelivery() (*types.WebhookDelivery, error) {
+
+	now := time.Now()
+	filter := bson.M{
+		"status":            types.EnumWebhookDeliveryStatus.Pending,
+		"next_attempt_date": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_date": now.Add(webhookClaimTimeout)}}
+
+	var delivery types.WebhookDelivery
+	err := webhookDeliveriesCollection.FindOneAndUpdate(dbContext, filter, update, options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_date", Value: 1}})).Decode(&delivery)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+
+}
+
+/*
+Sends the delivery and records the attempt. Failed automatic attempts are retried with exponential backoff, until
+webhook.MaxAttempts is reached. A failed manual attempt does not change the schedule of a pending delivery.
+*/
+func attemptWebhookDelivery(delivery *types.WebhookDelivery, manual bool) (*types.WebhookAttempt, error) {
+
+	attempt := types.WebhookAttempt{Date: time.Now(), Manual: manual}
+
+	subscriber, err := getWebhookByObjectId(delivery.WebhookId)
+
+	// deliveries of deactivated webhooks fail without retries
+	if err == nil && !subscriber.Active {
+		err = constants.ErrorWebhookInactive
+	}
+
+	if err == nil {
+		attempt.StatusCode, err = webhook.Send(subscriber.URL, subscriber.Secret, delivery.Event.String(), delivery.ID.Hex(), []byte(delivery.Payload))
+	}
+
+	attempt.Duration = time.Since(attempt.Date).Milliseconds()
+
+	set := bson.M{}
+
+	if err == nil {
+
+		set["status"] = types.EnumWebhookDeliveryStatus.Succeeded
+		set["next_attempt_date"] = nil
+
+	} else {
+
+		attempt.Error = err.Error()
+
+		failedAttempts := 1
+		for _, previous := range delivery.Attempts {
+			if !previous.Manual {
+				failedAttempts++
+			}
+		}
+
+		if !manual && failedAttempts < webhook.MaxAttempts && err != constants.ErrorWebhookNotFound && err != constants.ErrorWebhookInactive {
+			set["next_attempt_date"] = time.Now().Add(webhook.Backoff(failedAttempts))
+		} else if !manual || delivery.Status != types.EnumWebhookDeliveryStatus.Pending {
+			set["status"] = types.EnumWebhookDeliveryStatus.Failed
+			set["next_attempt_date"] = nil
+		}
+
+	}
+
+	update := bson.M{"$push": bson.M{"attempts": attempt}}
+
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+
+	_, updateError := webhookDeliveriesCollection.UpdateOne(dbContext, bson.M{"_id": delivery.ID}, update)
+
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	return &attempt, nil
+
+}
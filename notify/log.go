@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"fmt"
+	"yacoid_server/types"
+)
+
+const logChannelName = "log"
+
+/* Prints notifications to stdout, used for development */
+type LogChannel struct{}
+
+func (channel *LogChannel) Name() string {
+	return logChannelName
+}
+
+func (channel *LogChannel) Deliver(notification *types.Notification) error {
+
+	fmt.Printf("[notification] %s for user %s: %s\n%s\n", notification.Type.String(), notification.UserId, notification.Subject, notification.Message)
+	return nil
+
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+)
+
+/* Delivers recorded notifications to the user, e.g. by email. Deliver must not modify the notification. */
+type Channel interface {
+	Name() string
+	Deliver(notification *types.Notification) error
+}
+
+var channelsMutex sync.RWMutex
+
+/* Without registered channels notifications are only logged */
+var channels = []Channel{&LogChannel{}}
+
+/* Replaces the default log channel on the first call, further calls add channels */
+func RegisterChannel(channel Channel) {
+
+	channelsMutex.Lock()
+	defer channelsMutex.Unlock()
+
+	if len(channels) == 1 && channels[0].Name() == logChannelName {
+		channels = []Channel{}
+	}
+
+	channels = append(channels, channel)
+
+}
+
+/* Registers the sender selected by NOTIFY_SENDER ("log" or "smtp"). Without it notifications are only logged. */
+func Initialize() error {
+
+	switch os.Getenv(constants.EnvNotifySender) {
+	case "", logChannelName:
+		return nil
+	case smtpChannelName:
+
+		channel, err := NewSMTPChannelFromEnvironment()
+
+		if err != nil {
+			return err
+		}
+
+		RegisterChannel(channel)
+		return nil
+
+	}
+
+	return constants.ErrorInvalidNotifySender
+
+}
+
+/* Delivers the notification through every registered channel. A failing channel does not stop the others. */
+func Deliver(notification *types.Notification) error {
+
+	channelsMutex.RLock()
+	defer channelsMutex.RUnlock()
+
+	var firstError error
+
+	for _, channel := range channels {
+
+		err := channel.Deliver(notification)
+
+		if err != nil {
+			fmt.Printf("Could not deliver notification %s through %s: %v\n", notification.ID.Hex(), channel.Name(), err)
+
+			if firstError == nil {
+				firstError = err
+			}
+		}
+
+	}
+
+	return firstError
+
+}
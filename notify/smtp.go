@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+	"yacoid_server/auth"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+)
+
+const (
+	smtpChannelName = "smtp"
+	smtpDialTimeout = 10 * time.Second
+	// the whole conversation with the server, so a hanging server does not block the email worker
+	smtpTimeout = 30 * time.Second
+)
+
+/*
+Sends notifications as plain text emails to the address of the user in the auth system. Without a username
+no authentication is used, e.g. for a local SMTP sink like MailHog.
+*/
+type SMTPChannel struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPChannelFromEnvironment() (*SMTPChannel, error) {
+
+	channel := SMTPChannel{
+		host:     os.Getenv(constants.EnvSmtpHost),
+		port:     os.Getenv(constants.EnvSmtpPort),
+		username: os.Getenv(constants.EnvSmtpUsername),
+		password: os.Getenv(constants.EnvSmtpPassword),
+		from:     os.Getenv(constants.EnvSmtpFrom),
+	}
+
+	if len(channel.host) == 0 || len(channel.from) == 0 {
+		return nil, constants.ErrorSmtpNotConfigured
+	}
+
+	if len(channel.port) == 0 {
+		channel.port = "25"
+	}
+
+	return &channel, nil
+
+}
+
+func (channel *SMTPChannel) Name() string {
+	return smtpChannelName
+}
+
+func (channel *SMTPChannel) Deliver(notification *types.Notification) error {
+
+	user, err := auth.GetUser(notification.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if len(user.Email) == 0 {
+		return constants.ErrorUserHasNoEmail
+	}
+
+	var smtpAuth smtp.Auth
+
+	if len(channel.username) > 0 {
+		smtpAuth = smtp.PlainAuth("", channel.username, channel.password, channel.host)
+	}
+
+	return channel.sendMail(smtpAuth, user.Email, createEmail(channel.from, user.Email, notification))
+
+}
+
+/* Like smtp.SendMail, but with a timeout for connecting and a deadline for the whole conversation */
+func (channel *SMTPChannel) sendMail(smtpAuth smtp.Auth, to string, email []byte) error {
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(channel.host, channel.port), smtpDialTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, channel.host)
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: channel.host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if smtpAuth != nil {
+		err = client.Auth(smtpAuth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(channel.from)
+
+	if err != nil {
+		return err
+	}
+
+	err = client.Rcpt(to)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(email)
+
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+
+}
+
+func createEmail(from string, to string, notification *types.Notification) []byte {
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", notification.Subject),
+		"Date: " + notification.CreatedDate.Format(time.RFC1123Z),
+		fmt.Sprintf("Message-ID: <%s@yacoid>", notification.ID.Hex()),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+
+	// SMTP requires CRLF line endings
+	body := strings.ReplaceAll(strings.ReplaceAll(notification.Message, "\r\n", "\n"), "\n", "\r\n")
+
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+
+}
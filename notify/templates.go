@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"yacoid_server/constants"
+	"yacoid_server/types"
+)
+
+/* The values available in the templates. Definition and Comment are shortened excerpts. */
+type TemplateData struct {
+	Definition  string
+	Reason      string
+	Comment     string
+	SavedSearch string
+	Author      string
+}
+
+type notificationTemplate struct {
+	subject *template.Template
+	message *template.Template
+}
+
+func newTemplate(name string, subject string, message string) notificationTemplate {
+	return notificationTemplate{
+		subject: template.Must(template.New(name + "_subject").Parse(subject)),
+		message: template.Must(template.New(name + "_message").Parse(message)),
+	}
+}
+
+var templates = map[types.NotificationType]notificationTemplate{
+	types.EnumNotificationType.SavedSearchMatch: newTemplate("saved_search_match",
+		`New definition for "{{.SavedSearch}}"`,
+		`A new definition matches your saved search "{{.SavedSearch}}":
+
+"{{.Definition}}"`),
+	types.EnumNotificationType.DefinitionApproved: newTemplate("definition_approved",
+		`Your definition was approved`,
+		`Your definition was approved and is now visible to everyone:
+
+"{{.Definition}}"`),
+	types.EnumNotificationType.DefinitionRejected: newTemplate("definition_rejected",
+		`Your definition was declined`,
+		`Your definition was declined by a moderator:
+
+"{{.Definition}}"
+
+Reason:
+{{.Reason}}
+
+You can change the definition and it will be reviewed again.`),
+	types.EnumNotificationType.CommentReply: newTemplate("comment_reply",
+		`{{.Author}} replied to your comment`,
+		`{{.Author}} replied to your comment "{{.Comment}}" on the definition "{{.Definition}}".`),
+}
+
+/* Returns the subject and the message of the notification type filled with the data */
+func Render(notificationType types.NotificationType, data TemplateData) (string, string, error) {
+
+	notificationTemplate, ok := templates[notificationType]
+
+	if !ok {
+		return "", "", constants.ErrorInvalidEnum
+	}
+
+	subject := bytes.Buffer{}
+
+	err := notificationTemplate.subject.Execute(&subject, data)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	message := bytes.Buffer{}
+
+	err = notificationTemplate.message.Execute(&message, data)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return subject.String(), message.String(), nil
+
+}
+
+/* Shortens the text to the maximum number of characters at a word boundary */
+func Excerpt(text string, maxLength int) string {
+
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+
+	if len(runes) <= maxLength {
+		return text
+	}
+
+	excerpt := string(runes[:maxLength])
+
+	if index := strings.LastIndex(excerpt, " "); index > 0 {
+		excerpt = excerpt[:index]
+	}
+
+	return excerpt + "…"
+
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"yacoid_server/constants"
+)
+
+/* Positions are character (rune) offsets in the query, starting at 0 */
+type Node interface {
+	Position() int
+}
+
+type AndNode struct {
+	Children []Node
+	position int
+}
+
+type OrNode struct {
+	Children []Node
+	position int
+}
+
+type NotNode struct {
+	Child    Node
+	position int
+}
+
+/* A word or a quoted phrase without a field, which is searched in the content */
+type TermNode struct {
+	Text     string
+	Phrase   bool
+	position int
+}
+
+/*
+A field filter like category:artificial_intelligence. For ranges (year:1950..1960) IsRange is true and
+From and To contain the bounds, an open bound is empty.
+*/
+type FieldNode struct {
+	Field         string
+	Value         string
+	Phrase        bool
+	IsRange       bool
+	From          string
+	To            string
+	position      int
+	valuePosition int
+}
+
+func (node *AndNode) Position() int   { return node.position }
+func (node *OrNode) Position() int    { return node.position }
+func (node *NotNode) Position() int   { return node.position }
+func (node *TermNode) Position() int  { return node.position }
+func (node *FieldNode) Position() int { return node.position }
+
+/* Position of the value after the colon */
+func (node *FieldNode) ValuePosition() int { return node.valuePosition }
+
+type SyntaxError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func newSyntaxError(position int, format string, arguments ...interface{}) *SyntaxError {
+	return &SyntaxError{Position: position, Message: fmt.Sprintf(format, arguments...)}
+}
+
+/* Creates a syntax error for a node, e.g. for an invalid value found while compiling the query */
+func NewSyntaxError(position int, format string, arguments ...interface{}) *SyntaxError {
+	return newSyntaxError(position, format, arguments...)
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", constants.ErrorQuerySyntax.Error(), err.Position, err.Message)
+}
+
+func (err *SyntaxError) Unwrap() error {
+	return constants.ErrorQuerySyntax
+}
+
+func AsSyntaxError(err error) (*SyntaxError, bool) {
+
+	var syntaxError *SyntaxError
+
+	if errors.As(err, &syntaxError) {
+		return syntaxError, true
+	}
+
+	return nil, false
+
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenWord
+	tokenPhrase
+	tokenColon
+	tokenMinus
+	tokenLeftParen
+	tokenRightParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+func (tokenType tokenType) String() string {
+	switch tokenType {
+	case tokenEOF:
+		return "end of query"
+	case tokenWord:
+		return "word"
+	case tokenPhrase:
+		return "phrase"
+	case tokenColon:
+		return "\":\""
+	case tokenMinus:
+		return "\"-\""
+	case tokenLeftParen:
+		return "\"(\""
+	case tokenRightParen:
+		return "\")\""
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	}
+	return "unknown"
+}
+
+type token struct {
+	tokenType tokenType
+	text      string
+	// position of the first character (in runes, starting at 0)
+	position int
+	// true, if whitespace precedes the token
+	spaceBefore bool
+}
+
+func isWordCharacter(character rune) bool {
+	return !unicode.IsSpace(character) && character != '"' && character != '(' && character != ')' && character != ':'
+}
+
+func tokenize(input string) ([]token, error) {
+
+	runes := []rune(input)
+	tokens := []token{}
+	spaceBefore := true
+
+	for index := 0; index < len(runes); {
+
+		character := runes[index]
+
+		if unicode.IsSpace(character) {
+			spaceBefore = true
+			index++
+			continue
+		}
+
+		current := token{position: index, spaceBefore: spaceBefore}
+		spaceBefore = false
+
+		switch {
+		case character == '(':
+			current.tokenType = tokenLeftParen
+			index++
+		case character == ')':
+			current.tokenType = tokenRightParen
+			index++
+		case character == ':':
+			current.tokenType = tokenColon
+			index++
+		case character == '-' && current.spaceBefore && index+1 < len(runes) && !unicode.IsSpace(runes[index+1]):
+			// only a leading minus negates, "non-verbal" is one word
+			current.tokenType = tokenMinus
+			index++
+		case character == '"':
+
+			end := index + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			if end >= len(runes) {
+				return nil, newSyntaxError(index, "unterminated phrase, a closing quote is missing")
+			}
+
+			current.tokenType = tokenPhrase
+			current.text = string(runes[index+1 : end])
+			index = end + 1
+
+		default:
+
+			end := index
+			for end < len(runes) && isWordCharacter(runes[end]) {
+				end++
+			}
+
+			current.tokenType = tokenWord
+			current.text = string(runes[index:end])
+			index = end
+
+			switch current.text {
+			case "AND":
+				current.tokenType = tokenAnd
+			case "OR":
+				current.tokenType = tokenOr
+			case "NOT":
+				current.tokenType = tokenNot
+			}
+
+		}
+
+		tokens = append(tokens, current)
+
+	}
+
+	tokens = append(tokens, token{tokenType: tokenEOF, position: len(runes), spaceBefore: spaceBefore})
+
+	return tokens, nil
+
+}
@@ -0,0 +1,243 @@
+package query
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+/* Fields, that can be used as field:value */
+var Fields = []string{"category", "tag", "author", "year", "type", "language", "source"}
+
+/* Fields, whose value may be a range like 1950..1960 */
+var rangeFields = []string{"year"}
+
+const rangeSeparator = ".."
+
+/*
+Parses a query. The grammar (OR binds weaker than AND, which is implicit between two expressions):
+
+	query   = or
+	or      = and { "OR" and }
+	and     = unary { [ "AND" ] unary }
+	unary   = ( "-" | "NOT" ) unary | primary
+	primary = "(" or ")" | field ":" ( word | phrase ) | phrase | word
+*/
+func Parse(input string) (Node, error) {
+
+	tokens, err := tokenize(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser := parser{tokens: tokens}
+
+	if parser.peek().tokenType == tokenEOF {
+		return nil, newSyntaxError(0, "the query is empty")
+	}
+
+	node, err := parser.parseOr()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if next := parser.peek(); next.tokenType != tokenEOF {
+		return nil, newSyntaxError(next.position, "unexpected %s", next.tokenType)
+	}
+
+	return node, nil
+
+}
+
+type parser struct {
+	tokens []token
+	index  int
+}
+
+func (parser *parser) peek() token {
+	return parser.tokens[parser.index]
+}
+
+func (parser *parser) next() token {
+
+	current := parser.tokens[parser.index]
+
+	if current.tokenType != tokenEOF {
+		parser.index++
+	}
+
+	return current
+
+}
+
+func (parser *parser) parseOr() (Node, error) {
+
+	first, err := parser.parseAnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Node{first}
+
+	for parser.peek().tokenType == tokenOr {
+
+		parser.next()
+		child, err := parser.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, child)
+
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+
+	return &OrNode{Children: children, position: first.Position()}, nil
+
+}
+
+func (parser *parser) parseAnd() (Node, error) {
+
+	first, err := parser.parseUnary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Node{first}
+
+	for {
+
+		switch parser.peek().tokenType {
+		case tokenEOF, tokenOr, tokenRightParen:
+
+			if len(children) == 1 {
+				return first, nil
+			}
+
+			return &AndNode{Children: children, position: first.Position()}, nil
+
+		case tokenAnd:
+			parser.next()
+		}
+
+		child, err := parser.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, child)
+
+	}
+
+}
+
+func (parser *parser) parseUnary() (Node, error) {
+
+	current := parser.peek()
+
+	if current.tokenType == tokenMinus || current.tokenType == tokenNot {
+
+		parser.next()
+		child, err := parser.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotNode{Child: child, position: current.position}, nil
+
+	}
+
+	return parser.parsePrimary()
+
+}
+
+func (parser *parser) parsePrimary() (Node, error) {
+
+	current := parser.next()
+
+	switch current.tokenType {
+	case tokenLeftParen:
+
+		node, err := parser.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := parser.next(); closing.tokenType != tokenRightParen {
+			return nil, newSyntaxError(closing.position, "expected \")\" to close the group opened at position %d, found %s", current.position, closing.tokenType)
+		}
+
+		return node, nil
+
+	case tokenPhrase:
+		return &TermNode{Text: current.text, Phrase: true, position: current.position}, nil
+
+	case tokenWord:
+
+		if colon := parser.peek(); colon.tokenType == tokenColon && !colon.spaceBefore {
+			parser.next()
+			return parser.parseField(current)
+		}
+
+		return &TermNode{Text: current.text, position: current.position}, nil
+
+	case tokenEOF:
+		return nil, newSyntaxError(current.position, "unexpected end of query, expected a search term")
+
+	}
+
+	return nil, newSyntaxError(current.position, "unexpected %s", current.tokenType)
+
+}
+
+func (parser *parser) parseField(fieldToken token) (Node, error) {
+
+	field := strings.ToLower(fieldToken.text)
+
+	if !slices.Contains(Fields, field) {
+		return nil, newSyntaxError(fieldToken.position, "unknown field \"%s\", known fields are %s", fieldToken.text, strings.Join(Fields, ", "))
+	}
+
+	value := parser.next()
+
+	if value.spaceBefore || (value.tokenType != tokenWord && value.tokenType != tokenPhrase) {
+		return nil, newSyntaxError(value.position, "expected a value after \"%s:\"", fieldToken.text)
+	}
+
+	node := FieldNode{
+		Field:         field,
+		Value:         value.text,
+		Phrase:        value.tokenType == tokenPhrase,
+		position:      fieldToken.position,
+		valuePosition: value.position,
+	}
+
+	// other fields take values containing ".." literally, e.g. source:U.S..
+	if value.tokenType == tokenWord && slices.Contains(rangeFields, field) && strings.Contains(value.text, rangeSeparator) {
+
+		bounds := strings.SplitN(value.text, rangeSeparator, 2)
+
+		if len(bounds[0]) == 0 && len(bounds[1]) == 0 {
+			return nil, newSyntaxError(value.position, "a range needs at least one bound")
+		}
+
+		node.IsRange = true
+		node.From = bounds[0]
+		node.To = bounds[1]
+
+	}
+
+	return &node, nil
+
+}
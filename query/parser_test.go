@@ -0,0 +1,212 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/* A compact form of the tree, e.g. and(a, not("b c"), year:1950..1960) */
+func formatNode(node Node) string {
+
+	formatChildren := func(name string, children []Node) string {
+
+		parts := []string{}
+		for _, child := range children {
+			parts = append(parts, formatNode(child))
+		}
+
+		return name + "(" + strings.Join(parts, ", ") + ")"
+
+	}
+
+	switch node := node.(type) {
+	case *AndNode:
+		return formatChildren("and", node.Children)
+	case *OrNode:
+		return formatChildren("or", node.Children)
+	case *NotNode:
+		return formatChildren("not", []Node{node.Child})
+	case *TermNode:
+		if node.Phrase {
+			return fmt.Sprintf("%q", node.Text)
+		}
+		return node.Text
+	case *FieldNode:
+		if node.IsRange {
+			return node.Field + ":" + node.From + ".." + node.To
+		}
+		if node.Phrase {
+			return fmt.Sprintf("%s:%q", node.Field, node.Value)
+		}
+		return node.Field + ":" + node.Value
+	}
+
+	return "?"
+
+}
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"turing", "turing"},
+		{"machine learning", "and(machine, learning)"},
+		{"a AND b", "and(a, b)"},
+		{"a OR b", "or(a, b)"},
+		{"a b OR c", "or(and(a, b), c)"},
+		{"a OR b c", "or(a, and(b, c))"},
+		{"a AND b OR c AND d", "or(and(a, b), and(c, d))"},
+		{"(a OR b) c", "and(or(a, b), c)"},
+		{"a (b OR (c d))", "and(a, or(b, and(c, d)))"},
+		{"-a", "not(a)"},
+		{"NOT a", "not(a)"},
+		{"a -b", "and(a, not(b))"},
+		{"-(a OR b)", "not(or(a, b))"},
+		{"NOT -a", "not(not(a))"},
+		{"-a OR b", "or(not(a), b)"},
+		{"non-verbal", "non-verbal"},
+		{"a - b", "and(a, -, b)"},
+		{"and or not", "and(and, or, not)"},
+		{`"machine learning"`, `"machine learning"`},
+		{`"a b" -"c d"`, `and("a b", not("c d"))`},
+		{"category:artificial_intelligence", "category:artificial_intelligence"},
+		{"Category:ai", "category:ai"},
+		{`source:"On Computable Numbers"`, `source:"On Computable Numbers"`},
+		{"tag:ai -language:de", "and(tag:ai, not(language:de))"},
+		{"year:1950..1960", "year:1950..1960"},
+		{"year:..1960", "year:..1960"},
+		{"year:1950..", "year:1950.."},
+		{"year:1950", "year:1950"},
+		{"source:U.S..", "source:U.S.."},
+		{"author:turing OR author:shannon year:..1950", "or(author:turing, and(author:shannon, year:..1950))"},
+	}
+
+	for _, test := range tests {
+
+		node, err := Parse(test.input)
+
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.input, err)
+			continue
+		}
+
+		if actual := formatNode(node); actual != test.expected {
+			t.Errorf("Parse(%q) = %s, expected %s", test.input, actual, test.expected)
+		}
+
+	}
+
+}
+
+func TestParseRange(t *testing.T) {
+
+	node, err := Parse("year:..1960")
+
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	field, ok := node.(*FieldNode)
+
+	if !ok {
+		t.Fatalf("expected a field, got %s", formatNode(node))
+	}
+
+	if !field.IsRange || field.From != "" || field.To != "1960" || field.Value != "..1960" {
+		t.Errorf("unexpected range %+v", field)
+	}
+
+}
+
+func TestParsePositions(t *testing.T) {
+
+	// positions are runes, "ä" takes two bytes
+	node, err := Parse(`ä -tag:x "b c"`)
+
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	and, ok := node.(*AndNode)
+
+	if !ok || len(and.Children) != 3 {
+		t.Fatalf("expected three terms, got %s", formatNode(node))
+	}
+
+	not := and.Children[1].(*NotNode)
+	field := not.Child.(*FieldNode)
+
+	positions := []struct {
+		name     string
+		actual   int
+		expected int
+	}{
+		{"and", and.Position(), 0},
+		{"term", and.Children[0].Position(), 0},
+		{"not", not.Position(), 2},
+		{"field", field.Position(), 3},
+		{"value", field.ValuePosition(), 7},
+		{"phrase", and.Children[2].Position(), 9},
+	}
+
+	for _, position := range positions {
+		if position.actual != position.expected {
+			t.Errorf("position of %s is %d, expected %d", position.name, position.actual, position.expected)
+		}
+	}
+
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{"", 0},
+		{"   ", 0},
+		{"a OR", 4},
+		{"a AND", 5},
+		{"OR a", 0},
+		{"a (", 3},
+		{"(a b", 4},
+		{"a b)", 3},
+		{"()", 1},
+		{"NOT", 3},
+		{`a "b c`, 2},
+		{"foo:bar", 0},
+		{"a unknown:b", 2},
+		{"tag: x", 5},
+		{"tag:", 4},
+		{"tag:(a)", 4},
+		{"year:..", 5},
+		{"ä ü)", 3},
+		{`"äöü" "x`, 6},
+	}
+
+	for _, test := range tests {
+
+		_, err := Parse(test.input)
+
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error at position %d", test.input, test.position)
+			continue
+		}
+
+		syntaxError, ok := AsSyntaxError(err)
+
+		if !ok {
+			t.Errorf("Parse(%q) failed with %v, expected a syntax error", test.input, err)
+			continue
+		}
+
+		if syntaxError.Position != test.position {
+			t.Errorf("Parse(%q) failed at position %d (%s), expected position %d", test.input, syntaxError.Position, syntaxError.Message, test.position)
+		}
+
+	}
+
+}
@@ -1,37 +1,10 @@
 package main
 
 import (
-	"fmt"
-	"yacoid_server/api"
-	"yacoid_server/auth"
-	"yacoid_server/common"
-	"yacoid_server/database"
+	"os"
+	"yacoid_server/cli"
 )
 
 func main() {
-
-	err := common.LoadEnvironmentVariables()
-
-	if err != nil {
-		fmt.Printf("Failed to load env variables from .env-file. Using OS env variables. Error: %v\n", err)
-	}
-
-	err = database.Connect()
-
-	if err != nil {
-		panic(fmt.Sprintf("Failed to connect to database: %v\n", err))
-	}
-
-	err = auth.Initialize()
-
-	if err != nil {
-		panic(fmt.Sprintf("Failed to connect to auth system: %v\n", err))
-	}
-
-	err = api.StartAPI()
-	
-	if err != nil {
-		panic(fmt.Sprintf("Failed to connect to auth system: %v\n", err))
-	}
-
+	os.Exit(cli.Run(os.Args[1:]))
 }
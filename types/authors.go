@@ -33,6 +33,24 @@ type Author struct {
 	Type                   AuthorType              `bson:"type" json:"type" validate:"required,is-author-type"`
 	PersonProperties       *PersonProperties       `bson:"person_properties" json:"personProperties" validate:"required_without=OrganizationProperties,omitempty,dive"`
 	OrganizationProperties *OrganizationProperties `bson:"organization_properties" json:"organizationProperties" validate:"required_without=PersonProperties,omitempty,dive"`
+	Suggest                *SuggestKeys            `bson:"suggest,omitempty" json:"-"`
+	// set, when a source references the author, so a concurrent deletion of the author conflicts
+	LastReferenceDate *time.Time `bson:"last_reference_date,omitempty" json:"-"`
+}
+
+/* First and last name of a person or the name of an organization */
+func (author *Author) GetDisplayName() string {
+
+	if author.PersonProperties != nil {
+		return author.PersonProperties.FirstName + " " + author.PersonProperties.LastName
+	}
+
+	if author.OrganizationProperties != nil {
+		return author.OrganizationProperties.OrganizationName
+	}
+
+	return ""
+
 }
 
 func (object *Author) Validate(validate *validator.Validate) []string {
@@ -99,6 +117,7 @@ func (request *AuthorPageCountRequest) Validate(validate *validator.Validate) []
 type AuthorPageRequest struct {
 	PageSize int           `json:"pageSize" validate:"required,min=1"`
 	Page     int           `json:"page" validate:"required,min=1"`
+	Sort     *SortRequest  `json:"sort" validate:"omitempty,dive"`
 	Filter   *AuthorFilter `json:"filter" validate:"omitempty,dive"`
 }
 
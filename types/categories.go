@@ -0,0 +1,56 @@
+package types
+
+import (
+	"time"
+	"yacoid_server/common"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A category of the taxonomy. Definitions reference categories by their key, so the key can not be changed.
+Categories without parent are top level categories, siblings are sorted by Order and then by key.
+*/
+type Category struct {
+	ID             primitive.ObjectID  `bson:"_id" json:"id"`
+	Key            DefinitionCategory  `bson:"key" json:"key"`
+	Parent         *DefinitionCategory `bson:"parent" json:"parent"`
+	Names          map[Language]string `bson:"names" json:"names"`
+	Descriptions   map[Language]string `bson:"descriptions" json:"descriptions"`
+	Order          int                 `bson:"order" json:"order"`
+	CreatedDate    time.Time           `bson:"created_date" json:"createdDate"`
+	LastChangeDate time.Time           `bson:"last_change_date" json:"lastChangeDate"`
+}
+
+/* A category with its child categories */
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+type CreateCategoryRequest struct {
+	// lower case letters, digits and underscores, e.g. collective_intelligence
+	Key          string              `json:"key" validate:"required,min=1,max=50"`
+	Parent       *string             `json:"parent" validate:"omitempty,min=1"`
+	Names        map[Language]string `json:"names" validate:"required,min=1,dive,keys,is-language,endkeys,required,max=100"`
+	Descriptions map[Language]string `json:"descriptions" validate:"omitempty,dive,keys,is-language,endkeys,max=1000"`
+	Order        *int                `json:"order" validate:"omitempty"`
+}
+
+func (request *CreateCategoryRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* Parent "" moves the category to the top level. Names and Descriptions replace the existing ones. */
+type ChangeCategoryRequest struct {
+	Key          string              `json:"key" validate:"required"`
+	Parent       *string             `json:"parent" validate:"omitempty"`
+	Names        map[Language]string `json:"names" validate:"omitempty,min=1,dive,keys,is-language,endkeys,required,max=100"`
+	Descriptions map[Language]string `json:"descriptions" validate:"omitempty,dive,keys,is-language,endkeys,max=1000"`
+	Order        *int                `json:"order" validate:"omitempty"`
+}
+
+func (request *ChangeCategoryRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
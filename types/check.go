@@ -0,0 +1,17 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type IntegrityIssue struct {
+	Collection string             `json:"collection"`
+	ID         primitive.ObjectID `json:"id"`
+	Problem    string             `json:"problem"`
+}
+
+type IntegrityReport struct {
+	Issues []IntegrityIssue `json:"issues"`
+}
+
+func (report *IntegrityReport) Add(collection string, id primitive.ObjectID, problem string) {
+	report.Issues = append(report.Issues, IntegrityIssue{Collection: collection, ID: id, Problem: problem})
+}
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+Formats the citation of a definition in an author-date style, e.g.
+"Turing, A. (1950). Computing Machinery and Intelligence. Mind, p. 433." Paraphrases start with "Cf.".
+*/
+func FormatCitation(source *SourceResponse, locator *Locator, paraphrase bool) string {
+
+	parts := []string{}
+
+	if paraphrase {
+		parts = append(parts, "Cf.")
+	}
+
+	if authors := formatCitationAuthors(source.Authors); len(authors) > 0 {
+		parts = append(parts, authors)
+	}
+
+	year := "n.d."
+	if publicationDate := source.GetPublicationDate(); publicationDate != nil {
+		year = strconv.Itoa(publicationDate.Year())
+	}
+
+	parts = append(parts, "("+year+").")
+
+	work := []string{}
+
+	switch {
+	case source.BookProperties != nil:
+		work = append(work, source.BookProperties.Title)
+		if len(source.BookProperties.Publisher) > 0 {
+			work = append(work, source.BookProperties.Publisher)
+		}
+	case source.JournalProperties != nil:
+		work = append(work, source.JournalProperties.Title, source.JournalProperties.JournalName)
+	case source.WebProperties != nil:
+		work = append(work, source.WebProperties.ArticleName, source.WebProperties.WebsiteName)
+	}
+
+	citation := strings.Join(append(parts, strings.Join(work, ". ")), " ")
+
+	if locator != nil {
+		citation += ", " + locator.String()
+	}
+
+	if source.WebProperties != nil {
+		citation += ". " + source.WebProperties.URL
+	}
+
+	return citation + "."
+
+}
+
+/* "Last, F." for persons and the name for organizations, joined as "A, B, & C" */
+func formatCitationAuthors(authors []AuthorResponse) string {
+
+	names := []string{}
+
+	for _, author := range authors {
+
+		if author.PersonProperties != nil {
+
+			name := author.PersonProperties.LastName
+			initials := []string{}
+
+			for _, firstName := range strings.Fields(author.PersonProperties.FirstName) {
+				initials = append(initials, string([]rune(firstName)[0])+".")
+			}
+
+			if len(initials) > 0 {
+				name += ", " + strings.Join(initials, " ")
+			}
+
+			names = append(names, name)
+
+		} else if author.OrganizationProperties != nil {
+			names = append(names, author.OrganizationProperties.OrganizationName)
+		}
+
+	}
+
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " & " + names[1]
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+
+}
+
+/* Appends the secondary sources quoting the definition, e.g. "... Cited in Smith, J. (1990). ..." */
+func AppendSecondaryCitations(citation string, references []SourceReferenceResponse) string {
+
+	for _, reference := range references {
+		if reference.Relation == EnumSourceRelation.CitedIn {
+			citation += " Cited in " + reference.Citation
+		}
+	}
+
+	return citation
+
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func person(firstName string, lastName string) AuthorResponse {
+	return AuthorResponse{Type: EnumAuthorType.Person, PersonProperties: &PersonProperties{FirstName: firstName, LastName: lastName}}
+}
+
+func organization(name string) AuthorResponse {
+	return AuthorResponse{Type: EnumAuthorType.Organization, OrganizationProperties: &OrganizationProperties{OrganizationName: name}}
+}
+
+func TestFormatCitation(t *testing.T) {
+
+	published := time.Date(1950, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	book := &SourceResponse{
+		Authors:        []AuthorResponse{person("Alan Mathison", "Turing")},
+		BookProperties: &BookProperties{Title: "Computing Machinery and Intelligence", PublicationDate: &published, Publisher: "Mind"},
+	}
+
+	journal := &SourceResponse{
+		Authors:           []AuthorResponse{person("Claude", "Shannon"), person("Warren", "Weaver")},
+		JournalProperties: &JournalProperties{Title: "A Mathematical Theory of Communication", JournalName: "Bell System Technical Journal"},
+	}
+
+	web := &SourceResponse{
+		Authors:       []AuthorResponse{person("Ada", "Lovelace"), organization("ACM"), person("Émile", "Zola")},
+		WebProperties: &WebProperties{ArticleName: "Notes", WebsiteName: "Example", URL: "https://example.org/notes", PublicationDate: &published},
+	}
+
+	tests := []struct {
+		name       string
+		source     *SourceResponse
+		locator    *Locator
+		paraphrase bool
+		expected   string
+	}{
+		{"book", book, nil, false, "Turing, A. M. (1950). Computing Machinery and Intelligence. Mind."},
+		{"page", book, &Locator{Type: EnumLocatorType.Page, From: "433"}, false, "Turing, A. M. (1950). Computing Machinery and Intelligence. Mind, p. 433."},
+		{"paraphrase", book, &Locator{Type: EnumLocatorType.PageRange, From: "433", To: "460"}, true, "Cf. Turing, A. M. (1950). Computing Machinery and Intelligence. Mind, pp. 433–460."},
+		{"no date", journal, nil, false, "Shannon, C. & Weaver, W. (n.d.). A Mathematical Theory of Communication. Bell System Technical Journal."},
+		{"web", web, &Locator{Type: EnumLocatorType.Section, From: "2.3"}, false, "Lovelace, A., ACM, & Zola, É. (1950). Notes. Example, sec. 2.3. https://example.org/notes."},
+		{"no authors", &SourceResponse{BookProperties: &BookProperties{Title: "Anonymous"}}, nil, false, "(n.d.). Anonymous."},
+	}
+
+	for _, test := range tests {
+		if actual := FormatCitation(test.source, test.locator, test.paraphrase); actual != test.expected {
+			t.Errorf("FormatCitation of the %s citation = %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+
+}
+
+func TestAppendSecondaryCitations(t *testing.T) {
+
+	references := []SourceReferenceResponse{
+		{Relation: EnumSourceRelation.CitedIn, Citation: "Smith, J. (1990). Quotes."},
+		{Relation: EnumSourceRelation.AlsoIn, Citation: "Doe, J. (2000). Other."},
+	}
+
+	expected := "Turing, A. (1950). Mind. Cited in Smith, J. (1990). Quotes."
+
+	if actual := AppendSecondaryCitations("Turing, A. (1950). Mind.", references); actual != expected {
+		t.Errorf("AppendSecondaryCitations = %q, expected %q", actual, expected)
+	}
+
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A named list of approved definitions of a user, e.g. a reading list. The order of Items is the order of the
+collection. Unlisted collections can be read by everyone knowing the ShareToken, public collections by everyone.
+*/
+type Collection struct {
+	ID             primitive.ObjectID   `bson:"_id" json:"id"`
+	UserId         string               `bson:"user_id" json:"userId"`
+	Name           string               `bson:"name" json:"name"`
+	Description    string               `bson:"description" json:"description"`
+	Visibility     CollectionVisibility `bson:"visibility" json:"visibility"`
+	ShareToken     *string              `bson:"share_token,omitempty" json:"-"`
+	Items          []CollectionItem     `bson:"items" json:"items"`
+	CreatedDate    time.Time            `bson:"created_date" json:"createdDate"`
+	LastChangeDate time.Time            `bson:"last_change_date" json:"lastChangeDate"`
+}
+
+/* A definition in a collection with a personal note */
+type CollectionItem struct {
+	DefinitionId primitive.ObjectID `bson:"definition_id" json:"definitionId"`
+	Note         string             `bson:"note" json:"note"`
+	AddedDate    time.Time          `bson:"added_date" json:"addedDate"`
+}
+
+/* Items is only set, if a single collection is requested. ShareToken is only shown to the owner. */
+type CollectionResponse struct {
+	ID             primitive.ObjectID        `json:"id"`
+	UserId         string                    `json:"userId"`
+	UserName       string                    `json:"userName"`
+	Name           string                    `json:"name"`
+	Description    string                    `json:"description"`
+	Visibility     CollectionVisibility      `json:"visibility"`
+	ShareToken     *string                   `json:"shareToken,omitempty"`
+	ItemCount      int                       `json:"itemCount"`
+	Items          *[]CollectionItemResponse `json:"items,omitempty"`
+	CreatedDate    time.Time                 `json:"createdDate"`
+	LastChangeDate time.Time                 `json:"lastChangeDate"`
+}
+
+type CollectionItemResponse struct {
+	Definition DefinitionResponse `json:"definition"`
+	Note       string             `json:"note"`
+	AddedDate  time.Time          `json:"addedDate"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string                `json:"name" validate:"required,min=1,max=100"`
+	Description *string               `json:"description" validate:"omitempty,max=1000"`
+	Visibility  *CollectionVisibility `json:"visibility" validate:"omitempty,is-collection-visibility"`
+}
+
+func (request *CreateCollectionRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeCollectionRequest struct {
+	ID          string                `json:"id" validate:"required"`
+	Name        *string               `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string               `json:"description" validate:"omitempty,max=1000"`
+	Visibility  *CollectionVisibility `json:"visibility" validate:"omitempty,is-collection-visibility"`
+}
+
+func (request *ChangeCollectionRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* Without Position the definition is appended, otherwise it is inserted at the position (starting with 0) */
+type AddCollectionItemRequest struct {
+	CollectionId string  `json:"collectionId" validate:"required"`
+	DefinitionId string  `json:"definitionId" validate:"required"`
+	Note         *string `json:"note" validate:"omitempty,max=1000"`
+	Position     *int    `json:"position" validate:"omitempty,min=0"`
+}
+
+func (request *AddCollectionItemRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeCollectionItemRequest struct {
+	CollectionId string `json:"collectionId" validate:"required"`
+	DefinitionId string `json:"definitionId" validate:"required"`
+	Note         string `json:"note" validate:"max=1000"`
+}
+
+func (request *ChangeCollectionItemRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* DefinitionIds must contain every definition of the collection exactly once */
+type OrderCollectionRequest struct {
+	CollectionId  string   `json:"collectionId" validate:"required"`
+	DefinitionIds []string `json:"definitionIds" validate:"required,dive,required"`
+}
+
+func (request *OrderCollectionRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type CollectionVisibility string
+
+type collectionVisibilityList struct {
+	Unknown  CollectionVisibility
+	Private  CollectionVisibility
+	Unlisted CollectionVisibility
+	Public   CollectionVisibility
+}
+
+var EnumCollectionVisibility = &collectionVisibilityList{
+	Unknown:  "unknown",
+	Private:  "private",
+	Unlisted: "unlisted",
+	Public:   "public",
+}
+
+var collectionVisibilityMap = map[string]CollectionVisibility{
+	"private":  EnumCollectionVisibility.Private,
+	"unlisted": EnumCollectionVisibility.Unlisted,
+	"public":   EnumCollectionVisibility.Public,
+}
+
+func ParseStringToCollectionVisibility(str string) (CollectionVisibility, error) {
+	visibility, ok := collectionVisibilityMap[strings.ToLower(str)]
+	if ok {
+		return visibility, nil
+	} else {
+		return visibility, constants.ErrorInvalidEnum
+	}
+}
+
+func (visibility CollectionVisibility) String() string {
+	switch visibility {
+	case EnumCollectionVisibility.Private:
+		return "private"
+	case EnumCollectionVisibility.Unlisted:
+		return "unlisted"
+	case EnumCollectionVisibility.Public:
+		return "public"
+	}
+	return "unknown"
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"time"
+	"yacoid_server/common"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A comment on a definition or a reply to another comment (ParentId). Content is Markdown. Deleted comments
+with replies are kept without content, so the thread stays intact. Hidden comments are only shown to moderators.
+*/
+type Comment struct {
+	ID             primitive.ObjectID  `bson:"_id" json:"id"`
+	DefinitionId   primitive.ObjectID  `bson:"definition_id" json:"definitionId"`
+	ParentId       *primitive.ObjectID `bson:"parent_id" json:"parentId"`
+	SubmittedBy    string              `bson:"submitted_by" json:"submittedBy"`
+	SubmittedDate  time.Time           `bson:"submitted_date" json:"submittedDate"`
+	LastChangeDate time.Time           `bson:"last_change_date" json:"lastChangeDate"`
+	Content        string              `bson:"content" json:"content"`
+	Deleted        bool                `bson:"deleted" json:"deleted"`
+	Hidden         bool                `bson:"hidden" json:"hidden"`
+	HiddenBy       *string             `bson:"hidden_by" json:"hiddenBy"`
+	HiddenDate     *time.Time          `bson:"hidden_date" json:"hiddenDate"`
+}
+
+/* Content and HTML are empty for deleted comments and for hidden comments, unless they are requested by a moderator */
+type CommentResponse struct {
+	ID              primitive.ObjectID  `json:"id"`
+	DefinitionId    primitive.ObjectID  `json:"definitionId"`
+	ParentId        *primitive.ObjectID `json:"parentId"`
+	SubmittedBy     string              `json:"submittedBy"`
+	SubmittedByName string              `json:"submittedByName"`
+	SubmittedDate   time.Time           `json:"submittedDate"`
+	Edited          bool                `json:"edited"`
+	Content         string              `json:"content"`
+	HTML            string              `json:"html"`
+	Deleted         bool                `json:"deleted"`
+	Hidden          bool                `json:"hidden"`
+	ReplyCount      int                 `json:"replyCount"`
+}
+
+type CreateCommentRequest struct {
+	DefinitionId string `json:"definitionId" validate:"required"`
+	// the comment, which is answered
+	ParentId *string `json:"parentId" validate:"omitempty,min=1"`
+	Content  string  `json:"content" validate:"required,min=1,max=10000"`
+}
+
+func (request *CreateCommentRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeCommentRequest struct {
+	ID      string `json:"id" validate:"required"`
+	Content string `json:"content" validate:"required,min=1,max=10000"`
+}
+
+func (request *ChangeCommentRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* Without ParentId the top level comments of the definition are returned, otherwise the replies to the parent */
+type CommentPageRequest struct {
+	DefinitionId  string  `json:"definitionId" validate:"required"`
+	ParentId      *string `json:"parentId" validate:"omitempty,min=1"`
+	Page          int     `json:"page" validate:"required,min=1"`
+	PageSize      int     `json:"pageSize" validate:"required,min=1,max=100"`
+	IncludeHidden bool    `json:"includeHidden"`
+}
+
+func (request *CommentPageRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
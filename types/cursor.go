@@ -0,0 +1,57 @@
+package types
+
+import (
+	"yacoid_server/common"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type CursorRequest struct {
+	Limit             int          `json:"limit" validate:"required,min=1,max=100"`
+	Cursor            *string      `json:"cursor" validate:"omitempty,min=1"`
+	IncludeTotalCount *bool        `json:"includeTotalCount" validate:"omitempty"`
+	Sort              *SortRequest `json:"sort" validate:"omitempty,dive"`
+}
+
+type CursorPageResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor *string     `json:"nextCursor"`
+	PrevCursor *string     `json:"prevCursor"`
+	TotalCount *int        `json:"totalCount,omitempty"`
+	Highlights interface{} `json:"highlights,omitempty"`
+}
+
+type CursorPage[T interface{}] struct {
+	Items      []*T
+	NextCursor *string
+	PrevCursor *string
+	TotalCount *int
+}
+
+type DefinitionCursorRequest struct {
+	CursorRequest
+	AdminInformation *bool             `json:"adminInformation" validate:"omitempty"`
+	Filter           *DefinitionFilter `json:"filter" validate:"omitempty,dive"`
+}
+
+func (request *DefinitionCursorRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type SourceCursorRequest struct {
+	CursorRequest
+	Filter *SourceFilter `json:"filter" validate:"omitempty,dive"`
+}
+
+func (request *SourceCursorRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type AuthorCursorRequest struct {
+	CursorRequest
+	Filter *AuthorFilter `json:"filter" validate:"omitempty,dive"`
+}
+
+func (request *AuthorCursorRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
@@ -11,42 +11,111 @@ import (
 )
 
 type DefinitionsOfUserResponse struct {
-	ID              primitive.ObjectID    `bson:"_id" json:"id"`
-	SubmittedBy     string                `bson:"submitted_by" json:"submittedBy"`
-	SubmittedByName string                `bson:"submitted_by_name" json:"submittedByName"`
-	SubmittedDate   time.Time             `bson:"submitted_date" json:"submittedDate"`
-	ApprovedBy      *string               `bson:"approved_by" json:"approvedBy"`
-	ApprovedDate    *time.Time            `bson:"approved_date" json:"approvedDate"`
-	Approved        bool                  `bson:"approved" json:"approved"`
-	RejectionLog    *[]*RejectionResponse `bson:"rejection_log" json:"rejectionLog"`
-	Content         string                `bson:"content" json:"content"`
-	Source          SourceResponse        `bson:"source" json:"source"`
-	Category        DefinitionCategory    `bson:"category" json:"category"`
-	Status          DefinitionStatus      `bson:"status" json:"status"`
+	ID                primitive.ObjectID        `bson:"_id" json:"id"`
+	SlugId            string                    `bson:"slug_id" json:"slugId"`
+	SubmittedBy       string                    `bson:"submitted_by" json:"submittedBy"`
+	SubmittedByName   string                    `bson:"submitted_by_name" json:"submittedByName"`
+	SubmittedDate     time.Time                 `bson:"submitted_date" json:"submittedDate"`
+	ApprovedBy        *string                   `bson:"approved_by" json:"approvedBy"`
+	ApprovedDate      *time.Time                `bson:"approved_date" json:"approvedDate"`
+	Approved          bool                      `bson:"approved" json:"approved"`
+	RejectionLog      *[]*RejectionResponse     `bson:"rejection_log" json:"rejectionLog"`
+	Content           string                    `bson:"content" json:"content"`
+	Language          Language                  `bson:"language" json:"language"`
+	Source            SourceResponse            `bson:"source" json:"source"`
+	Locator           *Locator                  `bson:"locator" json:"locator"`
+	Paraphrase        bool                      `bson:"paraphrase" json:"paraphrase"`
+	Citation          string                    `bson:"citation" json:"citation"`
+	AdditionalSources []SourceReferenceResponse `bson:"additional_sources" json:"additionalSources"`
+	Categories        []DefinitionCategory      `bson:"categories" json:"categories"`
+	Tags              []TagReference            `bson:"tags" json:"tags"`
+	TranslationOf     *primitive.ObjectID       `bson:"translation_of" json:"translationOf"`
+	Translations      []TranslationReference    `bson:"translations" json:"translations"`
+	CommentCount      int                       `bson:"comment_count" json:"commentCount"`
+	Rating            DefinitionRating          `bson:"rating" json:"rating"`
+	// only set for moderators and admins, see database.AddModeratorNotesToDefinitions
+	ModeratorNotes *[]ModeratorNoteResponse `bson:"moderator_notes" json:"moderatorNotes,omitempty"`
+	Status         DefinitionStatus         `bson:"status" json:"status"`
 }
 
 type DefinitionResponse struct {
-	ID              primitive.ObjectID `bson:"_id" json:"id"`
-	SubmittedBy     string             `bson:"submitted_by" json:"submittedBy"`
-	SubmittedByName string             `bson:"submitted_by_name" json:"submittedByName"`
-	SubmittedDate   time.Time          `bson:"submitted_date" json:"submittedDate"`
-	Content         string             `bson:"content" json:"content"`
-	Source          SourceResponse     `bson:"source" json:"source"`
-	Category        DefinitionCategory `bson:"category" json:"category"`
+	ID                primitive.ObjectID        `bson:"_id" json:"id"`
+	SlugId            string                    `bson:"slug_id" json:"slugId"`
+	SubmittedBy       string                    `bson:"submitted_by" json:"submittedBy"`
+	SubmittedByName   string                    `bson:"submitted_by_name" json:"submittedByName"`
+	SubmittedDate     time.Time                 `bson:"submitted_date" json:"submittedDate"`
+	Content           string                    `bson:"content" json:"content"`
+	Language          Language                  `bson:"language" json:"language"`
+	Source            SourceResponse            `bson:"source" json:"source"`
+	Locator           *Locator                  `bson:"locator" json:"locator"`
+	Paraphrase        bool                      `bson:"paraphrase" json:"paraphrase"`
+	Citation          string                    `bson:"citation" json:"citation"`
+	AdditionalSources []SourceReferenceResponse `bson:"additional_sources" json:"additionalSources"`
+	Categories        []DefinitionCategory      `bson:"categories" json:"categories"`
+	Tags              []TagReference            `bson:"tags" json:"tags"`
+	TranslationOf     *primitive.ObjectID       `bson:"translation_of" json:"translationOf"`
+	Translations      []TranslationReference    `bson:"translations" json:"translations"`
+	CommentCount      int                       `bson:"comment_count" json:"commentCount"`
+	Rating            DefinitionRating          `bson:"rating" json:"rating"`
 }
 
+/*
+A definition is either an original text or a translation of an original text (TranslationOf). Translations share
+the source, locator, categories and tags of the original, and the original can not be deleted while it has translations.
+Locator is the place inside the source, Paraphrase is false for verbatim quotes. Source is the primary source,
+AdditionalSources are further works containing the definition or secondary sources quoting it. Rating is
+aggregated from the ratings of the users, see database.RateDefinition.
+*/
 type Definition struct {
-	ID             primitive.ObjectID `bson:"_id" json:"id"`
-	SubmittedBy    string             `bson:"submitted_by" json:"submittedBy"`
-	SubmittedDate  time.Time          `bson:"submitted_date" json:"submittedDate"`
-	LastChangeDate time.Time          `bson:"last_change_date" json:"lastChangeDate"`
-	ApprovedBy     *string            `bson:"approved_by" json:"approvedBy"`
-	ApprovedDate   *time.Time         `bson:"approved_date" json:"approvedDate"`
-	Approved       bool               `bson:"approved" json:"approved"`
-	RejectionLog   *[]*Rejection      `bson:"rejection_log" json:"-"`
-	Content        string             `bson:"content" json:"content"`
-	Source         primitive.ObjectID `bson:"source" json:"source"`
-	Category       DefinitionCategory `bson:"category" json:"category"`
+	ID                primitive.ObjectID   `bson:"_id" json:"id"`
+	SlugId            string               `bson:"slug_id" json:"slugId"`
+	SubmittedBy       string               `bson:"submitted_by" json:"submittedBy"`
+	SubmittedDate     time.Time            `bson:"submitted_date" json:"submittedDate"`
+	LastChangeDate    time.Time            `bson:"last_change_date" json:"lastChangeDate"`
+	ApprovedBy        *string              `bson:"approved_by" json:"approvedBy"`
+	ApprovedDate      *time.Time           `bson:"approved_date" json:"approvedDate"`
+	Approved          bool                 `bson:"approved" json:"approved"`
+	RejectionLog      *[]*Rejection        `bson:"rejection_log" json:"-"`
+	Content           string               `bson:"content" json:"content"`
+	Language          Language             `bson:"language" json:"language"`
+	Source            primitive.ObjectID   `bson:"source" json:"source"`
+	Locator           *Locator             `bson:"locator,omitempty" json:"locator"`
+	Paraphrase        bool                 `bson:"paraphrase" json:"paraphrase"`
+	AdditionalSources []SourceReference    `bson:"additional_sources,omitempty" json:"additionalSources"`
+	Categories        []DefinitionCategory `bson:"categories" json:"categories"`
+	Tags              []primitive.ObjectID `bson:"tags,omitempty" json:"tags"`
+	TranslationOf     *primitive.ObjectID  `bson:"translation_of,omitempty" json:"translationOf"`
+	Rating            DefinitionRating     `bson:"rating" json:"rating"`
+	// set on the original, when a translation is submitted or approved, so concurrent changes of the translations conflict
+	LastTranslationDate *time.Time `bson:"last_translation_date,omitempty" json:"-"`
+}
+
+/* The primary source followed by the additional sources */
+func (definition *Definition) GetSourceIds() []primitive.ObjectID {
+
+	sourceIds := []primitive.ObjectID{definition.Source}
+
+	for _, reference := range definition.AdditionalSources {
+		sourceIds = append(sourceIds, reference.Source)
+	}
+
+	return sourceIds
+
+}
+
+func (definition *Definition) IsTranslation() bool {
+	return definition.TranslationOf != nil
+}
+
+/* The ID of the original text, which is the definition itself if it is not a translation */
+func (definition *Definition) GetOriginalId() primitive.ObjectID {
+
+	if definition.TranslationOf != nil {
+		return *definition.TranslationOf
+	}
+
+	return definition.ID
+
 }
 
 func (definition *Definition) IsApproved() bool {
@@ -102,13 +171,73 @@ type Rejection struct {
 	Content      string             `bson:"content" json:"content" validate:"required"`
 }
 
+/* An approved version of the same text in another language */
+type TranslationReference struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	SlugId   string             `bson:"slug_id" json:"slugId"`
+	Language Language           `bson:"language" json:"language"`
+	Original bool               `bson:"original" json:"original"`
+}
+
+type SourceReference struct {
+	Source   primitive.ObjectID `bson:"source" json:"source"`
+	Relation SourceRelation     `bson:"relation" json:"relation"`
+	Locator  *Locator           `bson:"locator,omitempty" json:"locator"`
+}
+
+type SourceReferenceResponse struct {
+	Source   SourceResponse `bson:"source" json:"source"`
+	Relation SourceRelation `bson:"relation" json:"relation"`
+	Locator  *Locator       `bson:"locator" json:"locator"`
+	Citation string         `bson:"citation" json:"citation"`
+}
+
+type SourceReferenceRequest struct {
+	SourceId string         `json:"sourceId" validate:"required"`
+	Relation SourceRelation `json:"relation" validate:"required,is-source-relation"`
+	Locator  *Locator       `json:"locator" validate:"omitempty"`
+}
+
 type SubmitDefinitionRequest struct {
-	Content  string             `json:"content" validate:"required,min=1"`
-	SourceId string             `json:"sourceId" validate:"required"`
-	Category DefinitionCategory `json:"category" validate:"required,is-definition-category"`
+	Content           string                    `json:"content" validate:"required,min=1"`
+	SourceId          string                    `json:"sourceId" validate:"required"`
+	Locator           *Locator                  `json:"locator" validate:"omitempty"`
+	Paraphrase        *bool                     `json:"paraphrase" validate:"omitempty"`
+	AdditionalSources *[]SourceReferenceRequest `json:"additionalSources" validate:"omitempty,max=10,dive"`
+	Categories        []DefinitionCategory      `json:"categories" validate:"required,min=1,max=5,unique,dive,is-definition-category"`
+	Language          *Language                 `json:"language" validate:"omitempty,is-language"`
+	// names of existing or proposed tags
+	Tags *[]string `json:"tags" validate:"omitempty,max=10,dive,min=1,max=40"`
 }
 
 func (request *SubmitDefinitionRequest) Validate(validate *validator.Validate) []string {
+
+	errorFields := common.ValidateStruct(request, validate)
+
+	if request.Locator != nil {
+		errorFields = append(errorFields, request.Locator.validateValues()...)
+	}
+
+	if request.AdditionalSources != nil {
+		for _, reference := range *request.AdditionalSources {
+			if reference.Locator != nil {
+				errorFields = append(errorFields, reference.Locator.validateValues()...)
+			}
+		}
+	}
+
+	return errorFields
+
+}
+
+/* The translation is submitted for moderation like every other definition */
+type SubmitTranslationRequest struct {
+	DefinitionId string   `json:"definitionId" validate:"required"`
+	Content      string   `json:"content" validate:"required,min=1"`
+	Language     Language `json:"language" validate:"required,is-language"`
+}
+
+func (request *SubmitTranslationRequest) Validate(validate *validator.Validate) []string {
 	return common.ValidateStruct(request, validate)
 }
 
@@ -125,6 +254,7 @@ type DefinitionPageRequest struct {
 	PageSize         int               `json:"pageSize" validate:"required,min=1"`
 	Page             int               `json:"page" validate:"required,min=1"`
 	AdminInformation *bool             `json:"adminInformation" validate:"omitempty"`
+	Sort             *SortRequest      `json:"sort" validate:"omitempty,dive"`
 	Filter           *DefinitionFilter `json:"filter" validate:"omitempty,dive"`
 }
 
@@ -142,79 +272,115 @@ func (request *RejectRequest) Validate(validate *validator.Validate) []string {
 }
 
 type ChangeDefinitionRequest struct {
-	ID       *string             `json:"id" validate:"required"`
-	Content  *string             `json:"content" validate:"omitempty,min=1"`
-	SourceId *string             `json:"sourceId" validate:"omitempty,min=1"`
-	Category *DefinitionCategory `json:"category" validate:"omitempty,is-definition-category"`
+	ID         *string  `json:"id" validate:"required"`
+	Content    *string  `json:"content" validate:"omitempty,min=1"`
+	SourceId   *string  `json:"sourceId" validate:"omitempty,min=1"`
+	Locator    *Locator `json:"locator" validate:"omitempty"`
+	Paraphrase *bool    `json:"paraphrase" validate:"omitempty"`
+	// replaces all additional sources
+	AdditionalSources *[]SourceReferenceRequest `json:"additionalSources" validate:"omitempty,max=10,dive"`
+	Categories        *[]DefinitionCategory     `json:"categories" validate:"omitempty,min=1,max=5,unique,dive,is-definition-category"`
+	Language          *Language                 `json:"language" validate:"omitempty,is-language"`
+	Tags              *[]string                 `json:"tags" validate:"omitempty,max=10,dive,min=1,max=40"`
+	// removes the locator, if no new locator is given
+	RemoveLocator *bool `json:"removeLocator" validate:"omitempty"`
 }
 
 func (request *ChangeDefinitionRequest) Validate(validate *validator.Validate) []string {
-	return common.ValidateStruct(request, validate)
+
+	errorFields := common.ValidateStruct(request, validate)
+
+	if request.Locator != nil {
+		errorFields = append(errorFields, request.Locator.validateValues()...)
+	}
+
+	if request.AdditionalSources != nil {
+		for _, reference := range *request.AdditionalSources {
+			if reference.Locator != nil {
+				errorFields = append(errorFields, reference.Locator.validateValues()...)
+			}
+		}
+	}
+
+	return errorFields
+
 }
 
+/*
+PublishingYears and the inclusive range PublishingYearFrom/PublishingYearTo can be combined. Definitions
+whose source has no publication date are excluded by a year filter, unless IncludeUnknownPublishingYear is true.
+Language is used to stem the Content search and restricts the result to definitions of this language.
+Languages matches definitions in one of the languages, OriginalsOnly excludes translations.
+TagIds matches definitions with at least one of the tags.
+Query is a query of the query language (see query.Parse), which is combined with all other filters.
+*/
 type DefinitionFilter struct {
-	Approved        *bool                 `json:"approved" bson:"approved" validate:"omitempty"`
-	Content         *string               `json:"content" bson:"content" validate:"omitempty,min=1"`
-	Categories      *[]DefinitionCategory `json:"categories" bson:"categories" validate:"omitempty,dive,is-definition-category"`
-	AuthorIds       *[]string             `json:"authors" bson:"authors" validate:"omitempty,min=1"`
-	PublishingYears *[]int                `json:"publishingYears" bson:"publishing_years" validate:"omitempty,min=1"`
-	UserId          *string               `json:"userId" bson:"user_id" validate:"omitempty,min=1"`
+	Approved                     *bool                 `json:"approved" bson:"approved" validate:"omitempty"`
+	Content                      *string               `json:"content" bson:"content" validate:"omitempty,min=1"`
+	Query                        *string               `json:"query" bson:"query" validate:"omitempty,min=1"`
+	SearchMode                   *SearchMode           `json:"searchMode" bson:"search_mode" validate:"omitempty,is-search-mode"`
+	Language                     *Language             `json:"language" bson:"language" validate:"omitempty,is-language"`
+	Languages                    *[]Language           `json:"languages" bson:"languages" validate:"omitempty,dive,is-language"`
+	OriginalsOnly                *bool                 `json:"originalsOnly" bson:"originals_only" validate:"omitempty"`
+	Categories                   *[]DefinitionCategory `json:"categories" bson:"categories" validate:"omitempty,dive,is-definition-category"`
+	AuthorIds                    *[]string             `json:"authors" bson:"authors" validate:"omitempty,min=1"`
+	TagIds                       *[]string             `json:"tags" bson:"tags" validate:"omitempty,min=1"`
+	PublishingYears              *[]int                `json:"publishingYears" bson:"publishing_years" validate:"omitempty,min=1"`
+	PublishingYearFrom           *int                  `json:"publishingYearFrom" bson:"publishing_year_from" validate:"omitempty"`
+	PublishingYearTo             *int                  `json:"publishingYearTo" bson:"publishing_year_to" validate:"omitempty"`
+	IncludeUnknownPublishingYear *bool                 `json:"includeUnknownPublishingYear" bson:"include_unknown_publishing_year" validate:"omitempty"`
+	UserId                       *string               `json:"userId" bson:"user_id" validate:"omitempty,min=1"`
+	MinScore                     *int                  `json:"minScore" bson:"min_score" validate:"omitempty"`
 }
 
-type DefinitionCategory string
+/* How an additional source relates to the definition */
+type SourceRelation string
 
-type definitionCategoryList struct {
-	Unknown                DefinitionCategory
-	HumanIntelligence      DefinitionCategory
-	ArtificialIntelligence DefinitionCategory
-	MachineIntelligence    DefinitionCategory
-	PlantIntelligence      DefinitionCategory
-	AlienIntelligence      DefinitionCategory
+type sourceRelationList struct {
+	Unknown SourceRelation
+	// the definition also appears in the source
+	AlsoIn SourceRelation
+	// the source quotes the definition from the primary source
+	CitedIn SourceRelation
 }
 
-var EnumDefinitionCategory = &definitionCategoryList{
-	Unknown:                "unknown",
-	HumanIntelligence:      "human_intelligence",
-	ArtificialIntelligence: "artificial_intelligence",
-	MachineIntelligence:    "machine_intelligence",
-	PlantIntelligence:      "plant_intelligence",
-	AlienIntelligence:      "alien_intelligence",
+var EnumSourceRelation = &sourceRelationList{
+	Unknown: "unknown",
+	AlsoIn:  "also_in",
+	CitedIn: "cited_in",
 }
 
-var definitionCategoryMap = map[string]DefinitionCategory{
-	"human_intelligence":      EnumDefinitionCategory.HumanIntelligence,
-	"artificial_intelligence": EnumDefinitionCategory.ArtificialIntelligence,
-	"machine_intelligence":    EnumDefinitionCategory.MachineIntelligence,
-	"plant_intelligence":      EnumDefinitionCategory.PlantIntelligence,
-	"alien_intelligence":      EnumDefinitionCategory.AlienIntelligence,
+var sourceRelationMap = map[string]SourceRelation{
+	"also_in":  EnumSourceRelation.AlsoIn,
+	"cited_in": EnumSourceRelation.CitedIn,
 }
 
-func ParseStringToDefinitionCategory(str string) (DefinitionCategory, error) {
-	definitionCategory, ok := definitionCategoryMap[strings.ToLower(str)]
+func ParseStringToSourceRelation(str string) (SourceRelation, error) {
+	sourceRelation, ok := sourceRelationMap[strings.ToLower(str)]
 	if ok {
-		return definitionCategory, nil
+		return sourceRelation, nil
 	} else {
-		return definitionCategory, constants.ErrorInvalidEnum
+		return sourceRelation, constants.ErrorInvalidEnum
 	}
 }
 
-func (definitionCategory DefinitionCategory) String() string {
-	switch definitionCategory {
-	case EnumDefinitionCategory.HumanIntelligence:
-		return "human_intelligence"
-	case EnumDefinitionCategory.ArtificialIntelligence:
-		return "artificial_intelligence"
-	case EnumDefinitionCategory.MachineIntelligence:
-		return "machine_intelligence"
-	case EnumDefinitionCategory.PlantIntelligence:
-		return "plant_intelligence"
-	case EnumDefinitionCategory.AlienIntelligence:
-		return "alien_intelligence"
-
+func (sourceRelation SourceRelation) String() string {
+	switch sourceRelation {
+	case EnumSourceRelation.AlsoIn:
+		return "also_in"
+	case EnumSourceRelation.CitedIn:
+		return "cited_in"
 	}
 	return "unknown"
 }
 
+/* The key of a category, the categories are managed in the database (see Category) */
+type DefinitionCategory string
+
+func (definitionCategory DefinitionCategory) String() string {
+	return string(definitionCategory)
+}
+
 type DefinitionStatus string
 
 type definitionStatusList struct {
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/constants"
+)
+
+const DumpFormatVersion = 1
+
+type DumpManifest struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Status    DumpStatus          `json:"status"`
+	Files     []DumpManifestEntry `json:"files"`
+}
+
+func (manifest *DumpManifest) GetEntry(collection string) *DumpManifestEntry {
+
+	for index := range manifest.Files {
+		if manifest.Files[index].Collection == collection {
+			return &manifest.Files[index]
+		}
+	}
+
+	return nil
+
+}
+
+type DumpManifestEntry struct {
+	Collection string `json:"collection"`
+	File       string `json:"file"`
+	Count      int    `json:"count"`
+	SHA256     string `json:"sha256"`
+	// only set for definitions, because the rejection logs are embedded in the definition documents
+	RejectionCount *int `json:"rejectionCount,omitempty"`
+}
+
+type DumpOptions struct {
+	Directory string
+	Status    DumpStatus
+}
+
+type RestoreOptions struct {
+	Directory string
+	DryRun    bool
+}
+
+type RestoreReport struct {
+	Definitions int      `json:"definitions"`
+	Sources     int      `json:"sources"`
+	Authors     int      `json:"authors"`
+	Tags        int      `json:"tags"`
+	Categories  int      `json:"categories"`
+	Rejections  int      `json:"rejections"`
+	Issues      []string `json:"issues"`
+	Written     bool     `json:"written"`
+}
+
+type DumpStatus string
+
+type dumpStatusList struct {
+	Unknown    DumpStatus
+	All        DumpStatus
+	Approved   DumpStatus
+	Unapproved DumpStatus
+}
+
+var EnumDumpStatus = &dumpStatusList{
+	Unknown:    "unknown",
+	All:        "all",
+	Approved:   "approved",
+	Unapproved: "unapproved",
+}
+
+var dumpStatusMap = map[string]DumpStatus{
+	"all":        EnumDumpStatus.All,
+	"approved":   EnumDumpStatus.Approved,
+	"unapproved": EnumDumpStatus.Unapproved,
+}
+
+func ParseStringToDumpStatus(str string) (DumpStatus, error) {
+	dumpStatus, ok := dumpStatusMap[strings.ToLower(str)]
+	if ok {
+		return dumpStatus, nil
+	} else {
+		return dumpStatus, constants.ErrorInvalidEnum
+	}
+}
+
+func (dumpStatus DumpStatus) String() string {
+	switch dumpStatus {
+	case EnumDumpStatus.All:
+		return "all"
+	case EnumDumpStatus.Approved:
+		return "approved"
+	case EnumDumpStatus.Unapproved:
+		return "unapproved"
+	}
+	return "unknown"
+}
@@ -0,0 +1,245 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/constants"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A state change, which is written to the outbox in the same transaction as the change itself. The dispatcher
+passes it to every registered handler until all of them succeeded, so handlers are called at least once.
+Handled contains the names of the handlers, which already succeeded.
+*/
+type DomainEvent struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Type     DomainEventType    `bson:"type" json:"type"`
+	EntityId primitive.ObjectID `bson:"entity_id" json:"entityId"`
+	// the user, who caused the event, empty for deletions of definitions, sources and authors
+	UserId string `bson:"user_id" json:"userId,omitempty"`
+	// additional IDs of the change, e.g. "rejectionId"
+	Data            map[string]string `bson:"data,omitempty" json:"data,omitempty"`
+	Status          DomainEventStatus `bson:"status" json:"status"`
+	Handled         []string          `bson:"handled" json:"handled"`
+	Attempts        int               `bson:"attempts" json:"attempts"`
+	LastError       string            `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	NextAttemptDate *time.Time        `bson:"next_attempt_date" json:"nextAttemptDate"`
+	CreatedDate     time.Time         `bson:"created_date" json:"createdDate"`
+	DispatchedDate  *time.Time        `bson:"dispatched_date,omitempty" json:"dispatchedDate,omitempty"`
+}
+
+/*
+The key, which identifies the call of a handler for this event. It stays the same, when the call is repeated,
+so handlers use it to skip work they already did.
+*/
+func (event *DomainEvent) IdempotencyKey(handler string) string {
+	return event.ID.Hex() + ":" + handler
+}
+
+/* An event shown to moderators in the live moderation stream */
+type ModerationEvent struct {
+	ID          string          `json:"id"`
+	Type        DomainEventType `json:"type"`
+	Entity      string          `json:"entity"`
+	EntityId    string          `json:"entityId"`
+	UserId      string          `json:"userId,omitempty"`
+	CreatedDate time.Time       `json:"createdDate"`
+}
+
+/* Events are named "<entity>.<action>" like the webhook events */
+type DomainEventType string
+
+type domainEventTypeList struct {
+	Unknown             DomainEventType
+	DefinitionSubmitted DomainEventType
+	DefinitionChanged   DomainEventType
+	DefinitionApproved  DomainEventType
+	DefinitionRejected  DomainEventType
+	DefinitionDeleted   DomainEventType
+	DefinitionRated     DomainEventType
+	SourceSubmitted     DomainEventType
+	SourceChanged       DomainEventType
+	SourceApproved      DomainEventType
+	SourceDeleted       DomainEventType
+	AuthorSubmitted     DomainEventType
+	AuthorChanged       DomainEventType
+	AuthorApproved      DomainEventType
+	AuthorDeleted       DomainEventType
+	CommentCreated      DomainEventType
+	CommentChanged      DomainEventType
+	CommentDeleted      DomainEventType
+	CommentHidden       DomainEventType
+	CommentUnhidden     DomainEventType
+}
+
+var EnumDomainEventType = &domainEventTypeList{
+	Unknown:             "unknown",
+	DefinitionSubmitted: "definition.submitted",
+	DefinitionChanged:   "definition.changed",
+	DefinitionApproved:  "definition.approved",
+	DefinitionRejected:  "definition.rejected",
+	DefinitionDeleted:   "definition.deleted",
+	DefinitionRated:     "definition.rated",
+	SourceSubmitted:     "source.submitted",
+	SourceChanged:       "source.changed",
+	SourceApproved:      "source.approved",
+	SourceDeleted:       "source.deleted",
+	AuthorSubmitted:     "author.submitted",
+	AuthorChanged:       "author.changed",
+	AuthorApproved:      "author.approved",
+	AuthorDeleted:       "author.deleted",
+	CommentCreated:      "comment.created",
+	CommentChanged:      "comment.changed",
+	CommentDeleted:      "comment.deleted",
+	CommentHidden:       "comment.hidden",
+	CommentUnhidden:     "comment.unhidden",
+}
+
+var domainEventTypeMap = map[string]DomainEventType{
+	"definition.submitted": EnumDomainEventType.DefinitionSubmitted,
+	"definition.changed":   EnumDomainEventType.DefinitionChanged,
+	"definition.approved":  EnumDomainEventType.DefinitionApproved,
+	"definition.rejected":  EnumDomainEventType.DefinitionRejected,
+	"definition.deleted":   EnumDomainEventType.DefinitionDeleted,
+	"definition.rated":     EnumDomainEventType.DefinitionRated,
+	"source.submitted":     EnumDomainEventType.SourceSubmitted,
+	"source.changed":       EnumDomainEventType.SourceChanged,
+	"source.approved":      EnumDomainEventType.SourceApproved,
+	"source.deleted":       EnumDomainEventType.SourceDeleted,
+	"author.submitted":     EnumDomainEventType.AuthorSubmitted,
+	"author.changed":       EnumDomainEventType.AuthorChanged,
+	"author.approved":      EnumDomainEventType.AuthorApproved,
+	"author.deleted":       EnumDomainEventType.AuthorDeleted,
+	"comment.created":      EnumDomainEventType.CommentCreated,
+	"comment.changed":      EnumDomainEventType.CommentChanged,
+	"comment.deleted":      EnumDomainEventType.CommentDeleted,
+	"comment.hidden":       EnumDomainEventType.CommentHidden,
+	"comment.unhidden":     EnumDomainEventType.CommentUnhidden,
+}
+
+func ParseStringToDomainEventType(str string) (DomainEventType, error) {
+	eventType, ok := domainEventTypeMap[strings.ToLower(str)]
+	if ok {
+		return eventType, nil
+	} else {
+		return eventType, constants.ErrorInvalidEnum
+	}
+}
+
+func (eventType DomainEventType) String() string {
+	switch eventType {
+	case EnumDomainEventType.DefinitionSubmitted:
+		return "definition.submitted"
+	case EnumDomainEventType.DefinitionChanged:
+		return "definition.changed"
+	case EnumDomainEventType.DefinitionApproved:
+		return "definition.approved"
+	case EnumDomainEventType.DefinitionRejected:
+		return "definition.rejected"
+	case EnumDomainEventType.DefinitionDeleted:
+		return "definition.deleted"
+	case EnumDomainEventType.DefinitionRated:
+		return "definition.rated"
+	case EnumDomainEventType.SourceSubmitted:
+		return "source.submitted"
+	case EnumDomainEventType.SourceChanged:
+		return "source.changed"
+	case EnumDomainEventType.SourceApproved:
+		return "source.approved"
+	case EnumDomainEventType.SourceDeleted:
+		return "source.deleted"
+	case EnumDomainEventType.AuthorSubmitted:
+		return "author.submitted"
+	case EnumDomainEventType.AuthorChanged:
+		return "author.changed"
+	case EnumDomainEventType.AuthorApproved:
+		return "author.approved"
+	case EnumDomainEventType.AuthorDeleted:
+		return "author.deleted"
+	case EnumDomainEventType.CommentCreated:
+		return "comment.created"
+	case EnumDomainEventType.CommentChanged:
+		return "comment.changed"
+	case EnumDomainEventType.CommentDeleted:
+		return "comment.deleted"
+	case EnumDomainEventType.CommentHidden:
+		return "comment.hidden"
+	case EnumDomainEventType.CommentUnhidden:
+		return "comment.unhidden"
+	}
+	return "unknown"
+}
+
+/* The entity of the event, e.g. "definition" */
+func (eventType DomainEventType) GetEntity() string {
+	entity, _, _ := strings.Cut(eventType.String(), ".")
+	return entity
+}
+
+/* Changes of definitions, sources and authors, which moderators have to review or which end a review. Ratings are not reviewed. */
+func (eventType DomainEventType) IsModerationEvent() bool {
+	entity := eventType.GetEntity()
+	if eventType == EnumDomainEventType.Unknown || eventType == EnumDomainEventType.DefinitionRated {
+		return false
+	}
+	return entity == "definition" || entity == "source" || entity == "author"
+}
+
+func ModerationEventTypes() []DomainEventType {
+
+	eventTypes := []DomainEventType{}
+
+	for _, eventType := range domainEventTypeMap {
+		if eventType.IsModerationEvent() {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	return eventTypes
+
+}
+
+type DomainEventStatus string
+
+type domainEventStatusList struct {
+	Unknown    DomainEventStatus
+	Pending    DomainEventStatus
+	Dispatched DomainEventStatus
+	Failed     DomainEventStatus
+}
+
+var EnumDomainEventStatus = &domainEventStatusList{
+	Unknown:    "unknown",
+	Pending:    "pending",
+	Dispatched: "dispatched",
+	Failed:     "failed",
+}
+
+var domainEventStatusMap = map[string]DomainEventStatus{
+	"pending":    EnumDomainEventStatus.Pending,
+	"dispatched": EnumDomainEventStatus.Dispatched,
+	"failed":     EnumDomainEventStatus.Failed,
+}
+
+func ParseStringToDomainEventStatus(str string) (DomainEventStatus, error) {
+	status, ok := domainEventStatusMap[strings.ToLower(str)]
+	if ok {
+		return status, nil
+	} else {
+		return status, constants.ErrorInvalidEnum
+	}
+}
+
+func (status DomainEventStatus) String() string {
+	switch status {
+	case EnumDomainEventStatus.Pending:
+		return "pending"
+	case EnumDomainEventStatus.Dispatched:
+		return "dispatched"
+	case EnumDomainEventStatus.Failed:
+		return "failed"
+	}
+	return "unknown"
+}
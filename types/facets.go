@@ -0,0 +1,16 @@
+package types
+
+type FacetBucket struct {
+	// category, source type, author ID, language or publication year (null if the source has no publication date)
+	Value interface{} `bson:"_id" json:"value"`
+	Label string      `bson:"label,omitempty" json:"label,omitempty"`
+	Count int         `bson:"count" json:"count"`
+}
+
+type DefinitionFacets struct {
+	Categories      []FacetBucket `bson:"categories" json:"categories"`
+	SourceTypes     []FacetBucket `bson:"source_types" json:"sourceTypes"`
+	Authors         []FacetBucket `bson:"authors" json:"authors"`
+	PublishingYears []FacetBucket `bson:"publishing_years" json:"publishingYears"`
+	Languages       []FacetBucket `bson:"languages" json:"languages"`
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"strings"
+	"yacoid_server/constants"
+)
+
+/* Language of a definition or source. The values are ISO 639-1 codes, which are also language codes of the MongoDB text search. */
+type Language string
+
+type languageList struct {
+	Unknown Language
+	English Language
+	German  Language
+	French  Language
+}
+
+var EnumLanguage = &languageList{
+	Unknown: "unknown",
+	English: "en",
+	German:  "de",
+	French:  "fr",
+}
+
+var languageMap = map[string]Language{
+	"en": EnumLanguage.English,
+	"de": EnumLanguage.German,
+	"fr": EnumLanguage.French,
+}
+
+const DefaultLanguage = Language("en")
+
+func ParseStringToLanguage(str string) (Language, error) {
+	language, ok := languageMap[strings.ToLower(str)]
+	if ok {
+		return language, nil
+	} else {
+		return language, constants.ErrorInvalidEnum
+	}
+}
+
+func (language Language) String() string {
+	switch language {
+	case EnumLanguage.English:
+		return "en"
+	case EnumLanguage.German:
+		return "de"
+	case EnumLanguage.French:
+		return "fr"
+	}
+	return "unknown"
+}
@@ -0,0 +1,130 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"yacoid_server/constants"
+)
+
+var timestampRegex = regexp.MustCompile(`^\d{1,2}(:[0-5]\d){1,2}$`)
+
+/*
+The place of a definition inside its source. From is the page, section, chapter, paragraph or timestamp
+(h:mm:ss or mm:ss), To is only used by page ranges and timestamps. Values are strings, because pages and
+sections are not always numbers (e.g. "xii" or "2.3").
+*/
+type Locator struct {
+	Type LocatorType `bson:"type" json:"type" validate:"required,is-locator-type"`
+	From string      `bson:"from" json:"from" validate:"required,min=1,max=50"`
+	To   string      `bson:"to,omitempty" json:"to,omitempty" validate:"omitempty,min=1,max=50"`
+}
+
+/* The checks depending on the type, the field tags are validated with the request containing the locator */
+func (locator *Locator) validateValues() []string {
+
+	errorFields := []string{}
+
+	switch locator.Type {
+	case EnumLocatorType.PageRange:
+		if len(locator.To) == 0 {
+			errorFields = append(errorFields, "Locator.To missing")
+		}
+	case EnumLocatorType.Timestamp:
+		if !timestampRegex.MatchString(locator.From) || (len(locator.To) > 0 && !timestampRegex.MatchString(locator.To)) {
+			errorFields = append(errorFields, "Locator timestamp invalid")
+		}
+	default:
+		if len(locator.To) > 0 {
+			errorFields = append(errorFields, "Locator.To only allowed for page ranges and timestamps")
+		}
+	}
+
+	return errorFields
+
+}
+
+/* Short form for citations, e.g. "p. 12", "pp. 12–15" or "at 1:02:03" */
+func (locator *Locator) String() string {
+
+	value := locator.From
+
+	if len(locator.To) > 0 {
+		value += "–" + locator.To
+	}
+
+	switch locator.Type {
+	case EnumLocatorType.Page:
+		return "p. " + value
+	case EnumLocatorType.PageRange:
+		return "pp. " + value
+	case EnumLocatorType.Section:
+		return "sec. " + value
+	case EnumLocatorType.Chapter:
+		return "ch. " + value
+	case EnumLocatorType.Paragraph:
+		return "para. " + value
+	case EnumLocatorType.Timestamp:
+		return "at " + value
+	}
+
+	return value
+
+}
+
+type LocatorType string
+
+type locatorTypeList struct {
+	Unknown   LocatorType
+	Page      LocatorType
+	PageRange LocatorType
+	Section   LocatorType
+	Chapter   LocatorType
+	Paragraph LocatorType
+	Timestamp LocatorType
+}
+
+var EnumLocatorType = &locatorTypeList{
+	Unknown:   "unknown",
+	Page:      "page",
+	PageRange: "page_range",
+	Section:   "section",
+	Chapter:   "chapter",
+	Paragraph: "paragraph",
+	Timestamp: "timestamp",
+}
+
+var locatorTypeMap = map[string]LocatorType{
+	"page":       EnumLocatorType.Page,
+	"page_range": EnumLocatorType.PageRange,
+	"section":    EnumLocatorType.Section,
+	"chapter":    EnumLocatorType.Chapter,
+	"paragraph":  EnumLocatorType.Paragraph,
+	"timestamp":  EnumLocatorType.Timestamp,
+}
+
+func ParseStringToLocatorType(str string) (LocatorType, error) {
+	locatorType, ok := locatorTypeMap[strings.ToLower(str)]
+	if ok {
+		return locatorType, nil
+	} else {
+		return locatorType, constants.ErrorInvalidEnum
+	}
+}
+
+func (locatorType LocatorType) String() string {
+	switch locatorType {
+	case EnumLocatorType.Page:
+		return "page"
+	case EnumLocatorType.PageRange:
+		return "page_range"
+	case EnumLocatorType.Section:
+		return "section"
+	case EnumLocatorType.Chapter:
+		return "chapter"
+	case EnumLocatorType.Paragraph:
+		return "paragraph"
+	case EnumLocatorType.Timestamp:
+		return "timestamp"
+	}
+	return "unknown"
+}
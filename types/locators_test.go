@@ -0,0 +1,78 @@
+package types
+
+import "testing"
+
+func TestLocatorString(t *testing.T) {
+
+	tests := []struct {
+		locator  Locator
+		expected string
+	}{
+		{Locator{Type: EnumLocatorType.Page, From: "12"}, "p. 12"},
+		{Locator{Type: EnumLocatorType.Page, From: "xii"}, "p. xii"},
+		{Locator{Type: EnumLocatorType.PageRange, From: "12", To: "15"}, "pp. 12–15"},
+		{Locator{Type: EnumLocatorType.Section, From: "2.3"}, "sec. 2.3"},
+		{Locator{Type: EnumLocatorType.Chapter, From: "4"}, "ch. 4"},
+		{Locator{Type: EnumLocatorType.Paragraph, From: "7"}, "para. 7"},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "1:02:03"}, "at 1:02:03"},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "02:03", To: "04:05"}, "at 02:03–04:05"},
+		{Locator{Type: EnumLocatorType.Unknown, From: "x"}, "x"},
+	}
+
+	for _, test := range tests {
+		if actual := test.locator.String(); actual != test.expected {
+			t.Errorf("%+v.String() = %q, expected %q", test.locator, actual, test.expected)
+		}
+	}
+
+}
+
+func TestLocatorValidateValues(t *testing.T) {
+
+	tests := []struct {
+		locator Locator
+		valid   bool
+	}{
+		{Locator{Type: EnumLocatorType.Page, From: "12"}, true},
+		{Locator{Type: EnumLocatorType.Page, From: "12", To: "15"}, false},
+		{Locator{Type: EnumLocatorType.PageRange, From: "12", To: "15"}, true},
+		{Locator{Type: EnumLocatorType.PageRange, From: "12"}, false},
+		{Locator{Type: EnumLocatorType.Section, From: "2.3", To: "2.4"}, false},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "1:02:03"}, true},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "02:03", To: "12:59"}, true},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "2:60"}, false},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "123:00"}, false},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "1:02:03:04"}, false},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "12"}, false},
+		{Locator{Type: EnumLocatorType.Timestamp, From: "1:02", To: "later"}, false},
+	}
+
+	for _, test := range tests {
+		if errorFields := test.locator.validateValues(); (len(errorFields) == 0) != test.valid {
+			t.Errorf("validateValues of %+v returned %v, expected valid = %v", test.locator, errorFields, test.valid)
+		}
+	}
+
+}
+
+func TestParseStringToLocatorType(t *testing.T) {
+
+	for _, name := range []string{"page", "page_range", "section", "chapter", "paragraph", "timestamp"} {
+
+		locatorType, err := ParseStringToLocatorType(name)
+
+		if err != nil || locatorType.String() != name {
+			t.Errorf("ParseStringToLocatorType(%q) = %q, %v", name, locatorType, err)
+		}
+
+	}
+
+	if locatorType, err := ParseStringToLocatorType("PAGE"); err != nil || locatorType != EnumLocatorType.Page {
+		t.Errorf("ParseStringToLocatorType is not case insensitive: %q, %v", locatorType, err)
+	}
+
+	if _, err := ParseStringToLocatorType("line"); err == nil {
+		t.Errorf("ParseStringToLocatorType accepted an unknown type")
+	}
+
+}
@@ -0,0 +1,16 @@
+package types
+
+import "time"
+
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	Applied     bool       `json:"applied"`
+	AppliedDate *time.Time `json:"appliedDate,omitempty"`
+}
+
+type AppliedMigration struct {
+	Version     int       `bson:"_id" json:"version"`
+	Name        string    `bson:"name" json:"name"`
+	AppliedDate time.Time `bson:"applied_date" json:"appliedDate"`
+}
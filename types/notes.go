@@ -0,0 +1,95 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/* An internal note of the staff on a definition, source or author. Notes are only visible to moderators and admins. */
+type ModeratorNote struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Entity         NoteEntity         `bson:"entity" json:"entity"`
+	EntityId       primitive.ObjectID `bson:"entity_id" json:"entityId"`
+	CreatedBy      string             `bson:"created_by" json:"createdBy"`
+	CreatedDate    time.Time          `bson:"created_date" json:"createdDate"`
+	LastChangeDate time.Time          `bson:"last_change_date" json:"lastChangeDate"`
+	Content        string             `bson:"content" json:"content"`
+}
+
+type ModeratorNoteResponse struct {
+	ID             primitive.ObjectID `json:"id"`
+	Entity         NoteEntity         `json:"entity"`
+	EntityId       primitive.ObjectID `json:"entityId"`
+	CreatedBy      string             `json:"createdBy"`
+	CreatedByName  string             `json:"createdByName"`
+	CreatedDate    time.Time          `json:"createdDate"`
+	LastChangeDate time.Time          `json:"lastChangeDate"`
+	Content        string             `json:"content"`
+}
+
+type CreateModeratorNoteRequest struct {
+	Entity   NoteEntity `json:"entity" validate:"required,is-note-entity"`
+	EntityId string     `json:"entityId" validate:"required"`
+	Content  string     `json:"content" validate:"required,min=1,max=5000"`
+}
+
+func (request *CreateModeratorNoteRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeModeratorNoteRequest struct {
+	ID      string `json:"id" validate:"required"`
+	Content string `json:"content" validate:"required,min=1,max=5000"`
+}
+
+func (request *ChangeModeratorNoteRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type NoteEntity string
+
+type noteEntityList struct {
+	Unknown    NoteEntity
+	Author     NoteEntity
+	Source     NoteEntity
+	Definition NoteEntity
+}
+
+var EnumNoteEntity = &noteEntityList{
+	Unknown:    "unknown",
+	Author:     "author",
+	Source:     "source",
+	Definition: "definition",
+}
+
+var noteEntityMap = map[string]NoteEntity{
+	"author":     EnumNoteEntity.Author,
+	"source":     EnumNoteEntity.Source,
+	"definition": EnumNoteEntity.Definition,
+}
+
+func ParseStringToNoteEntity(str string) (NoteEntity, error) {
+	noteEntity, ok := noteEntityMap[strings.ToLower(str)]
+	if ok {
+		return noteEntity, nil
+	} else {
+		return noteEntity, constants.ErrorInvalidEnum
+	}
+}
+
+func (noteEntity NoteEntity) String() string {
+	switch noteEntity {
+	case EnumNoteEntity.Author:
+		return "author"
+	case EnumNoteEntity.Source:
+		return "source"
+	case EnumNoteEntity.Definition:
+		return "definition"
+	}
+	return "unknown"
+}
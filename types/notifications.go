@@ -0,0 +1,175 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A recorded notification of a user, shown in the inbox. It is stored before it is delivered by the channels of the
+notify package. Subject and Message are rendered from the template of the type when the notification is created.
+*/
+type Notification struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserId        string              `bson:"user_id" json:"userId"`
+	Type          NotificationType    `bson:"type" json:"type"`
+	Subject       string              `bson:"subject" json:"subject"`
+	Message       string              `bson:"message" json:"message"`
+	DefinitionId  *primitive.ObjectID `bson:"definition_id,omitempty" json:"definitionId,omitempty"`
+	SavedSearchId *primitive.ObjectID `bson:"saved_search_id,omitempty" json:"savedSearchId,omitempty"`
+	CommentId     *primitive.ObjectID `bson:"comment_id,omitempty" json:"commentId,omitempty"`
+	CreatedDate   time.Time           `bson:"created_date" json:"createdDate"`
+	Read          bool                `bson:"read" json:"read"`
+	ReadDate      *time.Time          `bson:"read_date,omitempty" json:"readDate,omitempty"`
+	// notifications of domain events are only created once per event
+	IdempotencyKey *string `bson:"idempotency_key,omitempty" json:"-"`
+	// only set, if the user wanted an email, when the notification was created. The email is sent by a worker.
+	EmailStatus          *NotificationEmailStatus `bson:"email_status,omitempty" json:"-"`
+	EmailAttempts        int                      `bson:"email_attempts,omitempty" json:"-"`
+	EmailLastError       string                   `bson:"email_last_error,omitempty" json:"-"`
+	EmailNextAttemptDate *time.Time               `bson:"email_next_attempt_date,omitempty" json:"-"`
+	EmailSentDate        *time.Time               `bson:"email_sent_date,omitempty" json:"-"`
+}
+
+/*
+The notification settings of a user. Every notification is shown in the inbox, unless its type is muted.
+Notifications of the types in EmailTypes are also delivered by the channels of the notify package.
+*/
+type NotificationPreferences struct {
+	UserId     string             `bson:"_id" json:"-"`
+	EmailTypes []NotificationType `bson:"email_types" json:"emailTypes"`
+	MutedTypes []NotificationType `bson:"muted_types" json:"mutedTypes"`
+}
+
+/* Users without stored preferences get every notification in the inbox and by email */
+func DefaultNotificationPreferences(userId string) NotificationPreferences {
+	return NotificationPreferences{
+		UserId:     userId,
+		EmailTypes: []NotificationType{EnumNotificationType.SavedSearchMatch, EnumNotificationType.DefinitionApproved, EnumNotificationType.DefinitionRejected, EnumNotificationType.CommentReply},
+		MutedTypes: []NotificationType{},
+	}
+}
+
+func (preferences *NotificationPreferences) IsMuted(notificationType NotificationType) bool {
+	return common.ArrayContainsOr(&preferences.MutedTypes, notificationType)
+}
+
+func (preferences *NotificationPreferences) WantsEmail(notificationType NotificationType) bool {
+	return common.ArrayContainsOr(&preferences.EmailTypes, notificationType)
+}
+
+type ChangeNotificationPreferencesRequest struct {
+	EmailTypes *[]NotificationType `json:"emailTypes" validate:"omitempty,dive,is-notification-type"`
+	MutedTypes *[]NotificationType `json:"mutedTypes" validate:"omitempty,dive,is-notification-type"`
+}
+
+func (request *ChangeNotificationPreferencesRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* The inbox of the user, newest first. With UnreadOnly read notifications are left out. */
+type NotificationPageRequest struct {
+	Page       int  `json:"page" validate:"required,min=1"`
+	PageSize   int  `json:"pageSize" validate:"required,min=1,max=100"`
+	UnreadOnly bool `json:"unreadOnly"`
+}
+
+func (request *NotificationPageRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type NotificationType string
+
+type notificationTypeList struct {
+	Unknown            NotificationType
+	SavedSearchMatch   NotificationType
+	DefinitionApproved NotificationType
+	DefinitionRejected NotificationType
+	CommentReply       NotificationType
+}
+
+var EnumNotificationType = &notificationTypeList{
+	Unknown:            "unknown",
+	SavedSearchMatch:   "saved_search_match",
+	DefinitionApproved: "definition_approved",
+	DefinitionRejected: "definition_rejected",
+	CommentReply:       "comment_reply",
+}
+
+var notificationTypeMap = map[string]NotificationType{
+	"saved_search_match":  EnumNotificationType.SavedSearchMatch,
+	"definition_approved": EnumNotificationType.DefinitionApproved,
+	"definition_rejected": EnumNotificationType.DefinitionRejected,
+	"comment_reply":       EnumNotificationType.CommentReply,
+}
+
+func ParseStringToNotificationType(str string) (NotificationType, error) {
+	notificationType, ok := notificationTypeMap[strings.ToLower(str)]
+	if ok {
+		return notificationType, nil
+	} else {
+		return notificationType, constants.ErrorInvalidEnum
+	}
+}
+
+func (notificationType NotificationType) String() string {
+	switch notificationType {
+	case EnumNotificationType.SavedSearchMatch:
+		return "saved_search_match"
+	case EnumNotificationType.DefinitionApproved:
+		return "definition_approved"
+	case EnumNotificationType.DefinitionRejected:
+		return "definition_rejected"
+	case EnumNotificationType.CommentReply:
+		return "comment_reply"
+	}
+	return "unknown"
+}
+
+type NotificationEmailStatus string
+
+type notificationEmailStatusList struct {
+	Unknown NotificationEmailStatus
+	Pending NotificationEmailStatus
+	Sent    NotificationEmailStatus
+	Failed  NotificationEmailStatus
+}
+
+var EnumNotificationEmailStatus = &notificationEmailStatusList{
+	Unknown: "unknown",
+	Pending: "pending",
+	Sent:    "sent",
+	Failed:  "failed",
+}
+
+var notificationEmailStatusMap = map[string]NotificationEmailStatus{
+	"pending": EnumNotificationEmailStatus.Pending,
+	"sent":    EnumNotificationEmailStatus.Sent,
+	"failed":  EnumNotificationEmailStatus.Failed,
+}
+
+func ParseStringToNotificationEmailStatus(str string) (NotificationEmailStatus, error) {
+	status, ok := notificationEmailStatusMap[strings.ToLower(str)]
+	if ok {
+		return status, nil
+	} else {
+		return status, constants.ErrorInvalidEnum
+	}
+}
+
+func (status NotificationEmailStatus) String() string {
+	switch status {
+	case EnumNotificationEmailStatus.Pending:
+		return "pending"
+	case EnumNotificationEmailStatus.Sent:
+		return "sent"
+	case EnumNotificationEmailStatus.Failed:
+		return "failed"
+	}
+	return "unknown"
+}
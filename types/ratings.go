@@ -0,0 +1,83 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/* The rating of one user for an approved definition. A rating has a vote, a usefulness score (1-5) or both. */
+type Rating struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	DefinitionId   primitive.ObjectID `bson:"definition_id" json:"definitionId"`
+	UserId         string             `bson:"user_id" json:"userId"`
+	Vote           *Vote              `bson:"vote" json:"vote"`
+	Usefulness     *int               `bson:"usefulness" json:"usefulness"`
+	SubmittedDate  time.Time          `bson:"submitted_date" json:"submittedDate"`
+	LastChangeDate time.Time          `bson:"last_change_date" json:"lastChangeDate"`
+}
+
+/*
+The aggregated ratings, stored on the definition, so definitions can be sorted and filtered by them.
+Score is the number of upvotes minus the number of downvotes.
+*/
+type DefinitionRating struct {
+	Upvotes           int     `bson:"upvotes" json:"upvotes"`
+	Downvotes         int     `bson:"downvotes" json:"downvotes"`
+	Score             int     `bson:"score" json:"score"`
+	UsefulnessCount   int     `bson:"usefulness_count" json:"usefulnessCount"`
+	UsefulnessAverage float64 `bson:"usefulness_average" json:"usefulnessAverage"`
+}
+
+/* Rating again replaces the previous rating of the user */
+type RateDefinitionRequest struct {
+	DefinitionId string `json:"definitionId" validate:"required"`
+	Vote         *Vote  `json:"vote" validate:"required_without=Usefulness,omitempty,is-vote"`
+	Usefulness   *int   `json:"usefulness" validate:"required_without=Vote,omitempty,min=1,max=5"`
+}
+
+func (request *RateDefinitionRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type Vote string
+
+type voteList struct {
+	Unknown Vote
+	Up      Vote
+	Down    Vote
+}
+
+var EnumVote = &voteList{
+	Unknown: "unknown",
+	Up:      "up",
+	Down:    "down",
+}
+
+var voteMap = map[string]Vote{
+	"up":   EnumVote.Up,
+	"down": EnumVote.Down,
+}
+
+func ParseStringToVote(str string) (Vote, error) {
+	vote, ok := voteMap[strings.ToLower(str)]
+	if ok {
+		return vote, nil
+	} else {
+		return vote, constants.ErrorInvalidEnum
+	}
+}
+
+func (vote Vote) String() string {
+	switch vote {
+	case EnumVote.Up:
+		return "up"
+	case EnumVote.Down:
+		return "down"
+	}
+	return "unknown"
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"time"
+	"yacoid_server/common"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/* A named DefinitionFilter of a user. With Alerts the user is notified about newly approved definitions matching the filter. */
+type SavedSearch struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserId      string             `bson:"user_id" json:"userId"`
+	Name        string             `bson:"name" json:"name"`
+	Filter      DefinitionFilter   `bson:"filter" json:"filter"`
+	Alerts      bool               `bson:"alerts" json:"alerts"`
+	CreatedDate time.Time          `bson:"created_date" json:"createdDate"`
+}
+
+type SavedSearchResponse struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Filter      DefinitionFilter `json:"filter"`
+	Alerts      bool             `json:"alerts"`
+	CreatedDate time.Time        `json:"createdDate"`
+}
+
+func (savedSearch *SavedSearch) ToResponse() SavedSearchResponse {
+	return SavedSearchResponse{
+		ID:          savedSearch.ID.Hex(),
+		Name:        savedSearch.Name,
+		Filter:      savedSearch.Filter,
+		Alerts:      savedSearch.Alerts,
+		CreatedDate: savedSearch.CreatedDate,
+	}
+}
+
+type CreateSavedSearchRequest struct {
+	Name   string            `json:"name" validate:"required,min=1,max=100"`
+	Filter *DefinitionFilter `json:"filter" validate:"required"`
+	Alerts *bool             `json:"alerts" validate:"omitempty"`
+}
+
+func (request *CreateSavedSearchRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeSavedSearchRequest struct {
+	ID     string            `json:"id" validate:"required"`
+	Name   *string           `json:"name" validate:"omitempty,min=1,max=100"`
+	Filter *DefinitionFilter `json:"filter" validate:"omitempty"`
+	Alerts *bool             `json:"alerts" validate:"omitempty"`
+}
+
+func (request *ChangeSavedSearchRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"strings"
+	"yacoid_server/constants"
+)
+
+type SearchMode string
+
+type searchModeList struct {
+	Unknown SearchMode
+	Any     SearchMode
+	All     SearchMode
+	Phrase  SearchMode
+}
+
+/*
+Any: definitions containing any of the words, "quoted phrases" are required and -words are excluded.
+All: like Any, but every word is required. Phrase: the whole text is one phrase, without any syntax.
+*/
+var EnumSearchMode = &searchModeList{
+	Unknown: "unknown",
+	Any:     "any",
+	All:     "all",
+	Phrase:  "phrase",
+}
+
+var searchModeMap = map[string]SearchMode{
+	"any":    EnumSearchMode.Any,
+	"all":    EnumSearchMode.All,
+	"phrase": EnumSearchMode.Phrase,
+}
+
+func ParseStringToSearchMode(str string) (SearchMode, error) {
+	searchMode, ok := searchModeMap[strings.ToLower(str)]
+	if ok {
+		return searchMode, nil
+	} else {
+		return searchMode, constants.ErrorInvalidEnum
+	}
+}
+
+func (searchMode SearchMode) String() string {
+	switch searchMode {
+	case EnumSearchMode.Any:
+		return "any"
+	case EnumSearchMode.All:
+		return "all"
+	case EnumSearchMode.Phrase:
+		return "phrase"
+	}
+	return "unknown"
+}
+
+/* Snippet of a field with the matched terms wrapped in <mark> tags. The rest of the snippet is HTML escaped. */
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
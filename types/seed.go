@@ -0,0 +1,38 @@
+package types
+
+type SeedOptions struct {
+	Seed          int64
+	Persons       int
+	Organizations int
+	Books         int
+	Journals      int
+	Websites      int
+	Definitions   int
+	// IDs of the auth system used as submitters. Fake IDs are generated, if empty.
+	UserIds     []string
+	ModeratorId string
+	// Deletes all definitions, sources and authors with everything that belongs to them, including their events, notifications and webhook deliveries, before seeding
+	Drop bool
+}
+
+func DefaultSeedOptions() SeedOptions {
+	return SeedOptions{
+		Seed:          1,
+		Persons:       40,
+		Organizations: 10,
+		Books:         30,
+		Journals:      30,
+		Websites:      20,
+		Definitions:   150,
+		ModeratorId:   "seed-moderator",
+	}
+}
+
+type SeedResult struct {
+	Authors             int `json:"authors"`
+	Sources             int `json:"sources"`
+	Definitions         int `json:"definitions"`
+	ApprovedDefinitions int `json:"approvedDefinitions"`
+	PendingDefinitions  int `json:"pendingDefinitions"`
+	DeclinedDefinitions int `json:"declinedDefinitions"`
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/constants"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/* A registered slug. Every entity has one current slug, older slugs are kept as aliases and redirect to the current one. */
+type Slug struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Entity      SlugEntity         `bson:"entity" json:"entity"`
+	Slug        string             `bson:"slug" json:"slug"`
+	Target      primitive.ObjectID `bson:"target" json:"target"`
+	Alias       bool               `bson:"alias" json:"alias"`
+	CreatedDate time.Time          `bson:"created_date" json:"createdDate"`
+}
+
+type SlugEntity string
+
+type slugEntityList struct {
+	Unknown    SlugEntity
+	Author     SlugEntity
+	Source     SlugEntity
+	Definition SlugEntity
+}
+
+var EnumSlugEntity = &slugEntityList{
+	Unknown:    "unknown",
+	Author:     "author",
+	Source:     "source",
+	Definition: "definition",
+}
+
+var slugEntityMap = map[string]SlugEntity{
+	"author":     EnumSlugEntity.Author,
+	"source":     EnumSlugEntity.Source,
+	"definition": EnumSlugEntity.Definition,
+}
+
+func ParseStringToSlugEntity(str string) (SlugEntity, error) {
+	slugEntity, ok := slugEntityMap[strings.ToLower(str)]
+	if ok {
+		return slugEntity, nil
+	} else {
+		return slugEntity, constants.ErrorInvalidEnum
+	}
+}
+
+func (slugEntity SlugEntity) String() string {
+	switch slugEntity {
+	case EnumSlugEntity.Author:
+		return "author"
+	case EnumSlugEntity.Source:
+		return "source"
+	case EnumSlugEntity.Definition:
+		return "definition"
+	}
+	return "unknown"
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"strings"
+	"yacoid_server/constants"
+)
+
+type SortRequest struct {
+	Field SortField `json:"field" validate:"required,is-sort-field"`
+	Order SortOrder `json:"order" validate:"omitempty,is-sort-order"`
+}
+
+func (sort *SortRequest) IsDescending() bool {
+	return sort.Order == EnumSortOrder.Descending
+}
+
+type SortField string
+
+type sortFieldList struct {
+	Unknown        SortField
+	SubmittedDate  SortField
+	ApprovedDate   SortField
+	LastChangeDate SortField
+	Relevance      SortField
+	AuthorLastName SortField
+	SourceTitle    SortField
+	Score          SortField
+}
+
+var EnumSortField = &sortFieldList{
+	Unknown:        "unknown",
+	SubmittedDate:  "submitted_date",
+	ApprovedDate:   "approved_date",
+	LastChangeDate: "last_change_date",
+	Relevance:      "relevance",
+	AuthorLastName: "author_last_name",
+	SourceTitle:    "source_title",
+	Score:          "score",
+}
+
+var sortFieldMap = map[string]SortField{
+	"submitted_date":   EnumSortField.SubmittedDate,
+	"approved_date":    EnumSortField.ApprovedDate,
+	"last_change_date": EnumSortField.LastChangeDate,
+	"relevance":        EnumSortField.Relevance,
+	"author_last_name": EnumSortField.AuthorLastName,
+	"source_title":     EnumSortField.SourceTitle,
+	"score":            EnumSortField.Score,
+}
+
+func ParseStringToSortField(str string) (SortField, error) {
+	sortField, ok := sortFieldMap[strings.ToLower(str)]
+	if ok {
+		return sortField, nil
+	} else {
+		return sortField, constants.ErrorInvalidEnum
+	}
+}
+
+func (sortField SortField) String() string {
+	switch sortField {
+	case EnumSortField.SubmittedDate:
+		return "submitted_date"
+	case EnumSortField.ApprovedDate:
+		return "approved_date"
+	case EnumSortField.LastChangeDate:
+		return "last_change_date"
+	case EnumSortField.Relevance:
+		return "relevance"
+	case EnumSortField.AuthorLastName:
+		return "author_last_name"
+	case EnumSortField.SourceTitle:
+		return "source_title"
+	case EnumSortField.Score:
+		return "score"
+	}
+	return "unknown"
+}
+
+type SortOrder string
+
+type sortOrderList struct {
+	Unknown    SortOrder
+	Ascending  SortOrder
+	Descending SortOrder
+}
+
+var EnumSortOrder = &sortOrderList{
+	Unknown:    "unknown",
+	Ascending:  "asc",
+	Descending: "desc",
+}
+
+var sortOrderMap = map[string]SortOrder{
+	"asc":  EnumSortOrder.Ascending,
+	"desc": EnumSortOrder.Descending,
+}
+
+func ParseStringToSortOrder(str string) (SortOrder, error) {
+	sortOrder, ok := sortOrderMap[strings.ToLower(str)]
+	if ok {
+		return sortOrder, nil
+	} else {
+		return sortOrder, constants.ErrorInvalidEnum
+	}
+}
+
+func (sortOrder SortOrder) String() string {
+	switch sortOrder {
+	case EnumSortOrder.Ascending:
+		return "asc"
+	case EnumSortOrder.Descending:
+		return "desc"
+	}
+	return "unknown"
+}
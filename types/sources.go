@@ -12,10 +12,12 @@ import (
 
 type SourceResponse struct {
 	ID                primitive.ObjectID `bson:"_id" json:"id"`
+	SlugId            string             `bson:"slug_id" json:"slugId"`
 	SubmittedBy       string             `bson:"submitted_by" json:"submittedBy"`
 	SubmittedByName   string             `bson:"submitted_by_name" json:"submittedByName"`
 	SubmittedDate     time.Time          `bson:"submitted_date" json:"submittedDate"`
 	Type              SourceType         `bson:"type" json:"type" validate:"required,is-source-type"`
+	Language          Language           `bson:"language" json:"language"`
 	Authors           []AuthorResponse   `bson:"authors" json:"authors" validate:"required,min=1"`
 	BookProperties    *BookProperties    `bson:"book_properties" json:"bookProperties" validate:"required_without_all=JournalProperties WebProperties,omitempty,dive"`
 	JournalProperties *JournalProperties `bson:"journal_properties" json:"journalProperties" validate:"required_without_all=BookProperties WebProperties,omitempty,dive"`
@@ -24,6 +26,7 @@ type SourceResponse struct {
 
 type Source struct {
 	ID                primitive.ObjectID   `bson:"_id" json:"id"`
+	SlugId            string               `bson:"slug_id" json:"slugId"`
 	SubmittedBy       string               `bson:"submitted_by" json:"submittedBy"`
 	SubmittedDate     time.Time            `bson:"submitted_date" json:"submittedDate"`
 	LastChangeDate    time.Time            `bson:"last_change_date" json:"lastChangeDate"`
@@ -31,10 +34,60 @@ type Source struct {
 	ApprovedDate      *time.Time           `bson:"approved_date" json:"approvedDate"`
 	Approved          bool                 `bson:"approved" json:"approved"`
 	Type              SourceType           `bson:"type" json:"type" validate:"required,is-source-type"`
+	Language          Language             `bson:"language" json:"language"`
 	Authors           []primitive.ObjectID `bson:"authors" json:"authors" validate:"required,min=1"`
 	BookProperties    *BookProperties      `bson:"book_properties" json:"bookProperties" validate:"required_without_all=JournalProperties WebProperties,omitempty,dive"`
 	JournalProperties *JournalProperties   `bson:"journal_properties" json:"journalProperties" validate:"required_without_all=BookProperties WebProperties,omitempty,dive"`
 	WebProperties     *WebProperties       `bson:"web_properties" json:"webProperties" validate:"required_without_all=BookProperties JournalProperties,omitempty,dive"`
+	Suggest           *SuggestKeys         `bson:"suggest,omitempty" json:"-"`
+	// set, when a definition references the source, so a concurrent deletion of the source conflicts
+	LastReferenceDate *time.Time `bson:"last_reference_date,omitempty" json:"-"`
+}
+
+/* Title of a book or journal article or name of a web article */
+func (source *Source) GetDisplayTitle() string {
+
+	switch {
+	case source.BookProperties != nil:
+		return source.BookProperties.Title
+	case source.JournalProperties != nil:
+		return source.JournalProperties.Title
+	case source.WebProperties != nil:
+		return source.WebProperties.ArticleName
+	}
+
+	return ""
+
+}
+
+func (source *Source) GetPublicationDate() *time.Time {
+
+	switch {
+	case source.BookProperties != nil:
+		return source.BookProperties.PublicationDate
+	case source.JournalProperties != nil:
+		return source.JournalProperties.PublicationDate
+	case source.WebProperties != nil:
+		return source.WebProperties.PublicationDate
+	}
+
+	return nil
+
+}
+
+func (source *SourceResponse) GetPublicationDate() *time.Time {
+
+	switch {
+	case source.BookProperties != nil:
+		return source.BookProperties.PublicationDate
+	case source.JournalProperties != nil:
+		return source.JournalProperties.PublicationDate
+	case source.WebProperties != nil:
+		return source.WebProperties.PublicationDate
+	}
+
+	return nil
+
 }
 
 func (object *Source) Validate(validate *validator.Validate) []string {
@@ -88,6 +141,7 @@ type WebProperties struct {
 
 type CreateSourceRequest struct {
 	Type              SourceType         `bson:"type" json:"type" validate:"required,is-source-type"`
+	Language          *Language          `bson:"language" json:"language" validate:"omitempty,is-language"`
 	Authors           []string           `bson:"authors" json:"authors" validate:"required,min=1"`
 	BookProperties    *BookProperties    `bson:"book_properties" json:"bookProperties" validate:"required_without_all=JournalProperties WebProperties,omitempty,dive"`
 	JournalProperties *JournalProperties `bson:"journal_properties" json:"journalProperties" validate:"required_without_all=BookProperties WebProperties,omitempty,dive"`
@@ -101,6 +155,7 @@ func (object *CreateSourceRequest) Validate(validate *validator.Validate) []stri
 type ChangeSourceRequest struct {
 	ID                string                   `json:"id" validate:"required"`
 	Type              *SourceType              `json:"type" validate:"omitempty,is-source-type"`
+	Language          *Language                `json:"language" validate:"omitempty,is-language"`
 	Authors           *[]string                `json:"authors" validate:"omitempty,min=1"`
 	PublishingDate    time.Time                `bson:"publishing_date" json:"publishingDate" validate:"omitempty"`
 	BookProperties    *ChangeBookProperties    `json:"bookProperties" validate:"omitempty,dive"`
@@ -200,6 +255,7 @@ func (request *SourcePageCountRequest) Validate(validate *validator.Validate) []
 type SourcePageRequest struct {
 	PageSize int           `json:"pageSize" validate:"required,min=1"`
 	Page     int           `json:"page" validate:"required,min=1"`
+	Sort     *SortRequest  `json:"sort" validate:"omitempty,dive"`
 	Filter   *SourceFilter `json:"filter" validate:"omitempty,dive"`
 }
 
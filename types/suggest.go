@@ -0,0 +1,118 @@
+package types
+
+import (
+	"fmt"
+	"yacoid_server/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const DefaultSuggestionLimit = 10
+const MaxSuggestionLimit = 25
+
+/* Normalized keys for the typeahead suggestions. They are derived from the other fields and never sent to clients. */
+type SuggestKeys struct {
+	Prefixes []string `bson:"prefixes" json:"prefixes"`
+	Trigrams []string `bson:"trigrams" json:"trigrams"`
+}
+
+type SuggestionResponse struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Label    string             `bson:"label" json:"label"`
+	Type     string             `bson:"type" json:"type"`
+	Approved bool               `bson:"approved" json:"approved"`
+}
+
+/* Identifiers (ISBN, DOI, ...) are also indexed without separators, so "978-3" and "9783" find the same source. */
+func CreateSuggestKeys(texts []string, identifiers []string) *SuggestKeys {
+
+	words := []string{}
+
+	for _, text := range texts {
+		words = append(words, common.NormalizeSearchText(text)...)
+	}
+
+	prefixWords := append([]string{}, words...)
+
+	for _, identifier := range identifiers {
+
+		normalized := common.NormalizeSearchIdentifier(identifier)
+
+		if len(normalized) > 0 {
+			prefixWords = append(prefixWords, normalized)
+		}
+
+	}
+
+	return &SuggestKeys{
+		Prefixes: common.CreateSearchPrefixes(prefixWords),
+		Trigrams: common.CreateSearchTrigrams(words),
+	}
+
+}
+
+func (author *Author) UpdateSuggestKeys() {
+
+	texts := []string{}
+
+	if author.PersonProperties != nil {
+		texts = append(texts, author.PersonProperties.FirstName, author.PersonProperties.LastName)
+	}
+
+	if author.OrganizationProperties != nil {
+		texts = append(texts, author.OrganizationProperties.OrganizationName)
+	}
+
+	author.Suggest = CreateSuggestKeys(texts, nil)
+
+}
+
+func (author *Author) ToSuggestionResponse() SuggestionResponse {
+	return SuggestionResponse{
+		ID:       author.ID,
+		Label:    author.GetDisplayName(),
+		Type:     author.Type.String(),
+		Approved: author.Approved,
+	}
+}
+
+func (source *Source) UpdateSuggestKeys() {
+
+	texts := []string{}
+	identifiers := []string{}
+
+	if source.BookProperties != nil {
+		texts = append(texts, source.BookProperties.Title)
+		identifiers = append(identifiers, source.BookProperties.ISBN, source.BookProperties.EAN, source.BookProperties.DOI)
+	}
+
+	if source.JournalProperties != nil {
+		texts = append(texts, source.JournalProperties.Title, source.JournalProperties.JournalName)
+		identifiers = append(identifiers, source.JournalProperties.DOI)
+	}
+
+	if source.WebProperties != nil {
+		texts = append(texts, source.WebProperties.ArticleName, source.WebProperties.WebsiteName)
+	}
+
+	source.Suggest = CreateSuggestKeys(texts, identifiers)
+
+}
+
+/* The label is the title followed by the publication year, if it is known */
+func (source *Source) ToSuggestionResponse() SuggestionResponse {
+
+	label := source.GetDisplayTitle()
+
+	if publicationDate := source.GetPublicationDate(); publicationDate != nil {
+		label = fmt.Sprintf("%s (%d)", label, publicationDate.Year())
+	}
+
+	return SuggestionResponse{
+		ID:       source.ID,
+		Label:    label,
+		Type:     source.Type.String(),
+		Approved: source.Approved,
+	}
+
+}
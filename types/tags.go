@@ -0,0 +1,65 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+A tag proposed by a submitter. Tags with the same key are the same tag, so "Problem Solving" and "problem-solving"
+are not proposed twice. A merged tag is kept with MergedInto, so proposing its name again uses the target tag.
+*/
+type Tag struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	Name         string              `bson:"name" json:"name"`
+	Key          string              `bson:"key" json:"key"`
+	ProposedBy   string              `bson:"proposed_by" json:"proposedBy"`
+	ProposedDate time.Time           `bson:"proposed_date" json:"proposedDate"`
+	ApprovedBy   *string             `bson:"approved_by" json:"approvedBy"`
+	ApprovedDate *time.Time          `bson:"approved_date" json:"approvedDate"`
+	Approved     bool                `bson:"approved" json:"approved"`
+	MergedInto   *primitive.ObjectID `bson:"merged_into,omitempty" json:"mergedInto,omitempty"`
+}
+
+/* A tag of a definition */
+type TagReference struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Approved bool   `json:"approved"`
+}
+
+type TagResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Approved bool   `json:"approved"`
+	// number of approved definitions with the tag
+	Count int `json:"count"`
+}
+
+func (tag *Tag) ToReference() TagReference {
+	return TagReference{ID: tag.ID.Hex(), Name: tag.Name, Approved: tag.Approved}
+}
+
+/* The name with collapsed whitespace, as it is shown */
+func NormalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+/* The key identifying a tag, e.g. "problem-solving". Empty, if the name has no letters or digits. */
+func CreateTagKey(name string) string {
+	return strings.Join(common.NormalizeSearchText(name), "-")
+}
+
+type MergeTagsRequest struct {
+	SourceIds []string `json:"sourceIds" validate:"required,min=1,dive,required"`
+	TargetId  string   `json:"targetId" validate:"required"`
+}
+
+func (request *MergeTagsRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
@@ -0,0 +1,246 @@
+package types
+
+import (
+	"strings"
+	"time"
+	"yacoid_server/common"
+	"yacoid_server/constants"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/* A subscription of an external URL to lifecycle events. Without Events the webhook receives every event. */
+type Webhook struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	URL            string             `bson:"url" json:"url"`
+	Description    string             `bson:"description" json:"description"`
+	Secret         string             `bson:"secret" json:"-"`
+	Events         []WebhookEvent     `bson:"events" json:"events"`
+	Active         bool               `bson:"active" json:"active"`
+	CreatedBy      string             `bson:"created_by" json:"createdBy"`
+	CreatedDate    time.Time          `bson:"created_date" json:"createdDate"`
+	LastChangeDate time.Time          `bson:"last_change_date" json:"lastChangeDate"`
+}
+
+func (webhook *Webhook) IsSubscribed(event WebhookEvent) bool {
+	return len(webhook.Events) == 0 || common.ArrayContainsOr(&webhook.Events, event)
+}
+
+/*
+One event sent to one webhook. Payload is stored, so redeliveries send the same body. Pending deliveries are
+retried with exponential backoff at NextAttemptDate, until they succeed or the maximum of attempts is reached.
+*/
+type WebhookDelivery struct {
+	ID              primitive.ObjectID    `bson:"_id" json:"id"`
+	WebhookId       primitive.ObjectID    `bson:"webhook_id" json:"webhookId"`
+	Event           WebhookEvent          `bson:"event" json:"event"`
+	Payload         string                `bson:"payload" json:"payload"`
+	Status          WebhookDeliveryStatus `bson:"status" json:"status"`
+	Attempts        []WebhookAttempt      `bson:"attempts" json:"attempts"`
+	NextAttemptDate *time.Time            `bson:"next_attempt_date" json:"nextAttemptDate"`
+	CreatedDate     time.Time             `bson:"created_date" json:"createdDate"`
+	// the domain event and the webhook, so an event creates only one delivery per webhook
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"-"`
+}
+
+type WebhookAttempt struct {
+	Date       time.Time `bson:"date" json:"date"`
+	StatusCode int       `bson:"status_code" json:"statusCode"`
+	Error      string    `bson:"error" json:"error"`
+	// milliseconds until the response was received
+	Duration int64 `bson:"duration" json:"duration"`
+	Manual   bool  `bson:"manual" json:"manual"`
+}
+
+/* The body of a delivery. ID is the ID of the delivery and can be used by receivers to ignore duplicates. */
+type WebhookPayload struct {
+	ID          string             `json:"id"`
+	Event       WebhookEvent       `json:"event"`
+	CreatedDate time.Time          `json:"createdDate"`
+	Data        WebhookPayloadData `json:"data"`
+}
+
+type WebhookPayloadData struct {
+	Entity   string `json:"entity"`
+	EntityId string `json:"entityId"`
+	// the user, who caused the event
+	UserId string `json:"userId,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string          `json:"url" validate:"required,url,max=2000"`
+	Description *string         `json:"description" validate:"omitempty,max=500"`
+	Events      *[]WebhookEvent `json:"events" validate:"omitempty,dive,is-webhook-event"`
+	Active      *bool           `json:"active" validate:"omitempty"`
+}
+
+func (request *CreateWebhookRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type ChangeWebhookRequest struct {
+	ID          string          `json:"id" validate:"required"`
+	URL         *string         `json:"url" validate:"omitempty,url,max=2000"`
+	Description *string         `json:"description" validate:"omitempty,max=500"`
+	Events      *[]WebhookEvent `json:"events" validate:"omitempty,dive,is-webhook-event"`
+	Active      *bool           `json:"active" validate:"omitempty"`
+}
+
+func (request *ChangeWebhookRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+type WebhookDeliveryPageRequest struct {
+	WebhookId string                 `json:"webhookId" validate:"required"`
+	Status    *WebhookDeliveryStatus `json:"status" validate:"omitempty,is-webhook-delivery-status"`
+	Page      int                    `json:"page" validate:"required,min=1"`
+	PageSize  int                    `json:"pageSize" validate:"required,min=1,max=100"`
+}
+
+func (request *WebhookDeliveryPageRequest) Validate(validate *validator.Validate) []string {
+	return common.ValidateStruct(request, validate)
+}
+
+/* Events are named "<entity>.<action>", e.g. "definition.approved" */
+type WebhookEvent string
+
+type webhookEventList struct {
+	Unknown             WebhookEvent
+	DefinitionSubmitted WebhookEvent
+	DefinitionChanged   WebhookEvent
+	DefinitionApproved  WebhookEvent
+	DefinitionRejected  WebhookEvent
+	DefinitionDeleted   WebhookEvent
+	SourceSubmitted     WebhookEvent
+	SourceChanged       WebhookEvent
+	SourceApproved      WebhookEvent
+	SourceDeleted       WebhookEvent
+	AuthorSubmitted     WebhookEvent
+	AuthorChanged       WebhookEvent
+	AuthorApproved      WebhookEvent
+	AuthorDeleted       WebhookEvent
+}
+
+var EnumWebhookEvent = &webhookEventList{
+	Unknown:             "unknown",
+	DefinitionSubmitted: "definition.submitted",
+	DefinitionChanged:   "definition.changed",
+	DefinitionApproved:  "definition.approved",
+	DefinitionRejected:  "definition.rejected",
+	DefinitionDeleted:   "definition.deleted",
+	SourceSubmitted:     "source.submitted",
+	SourceChanged:       "source.changed",
+	SourceApproved:      "source.approved",
+	SourceDeleted:       "source.deleted",
+	AuthorSubmitted:     "author.submitted",
+	AuthorChanged:       "author.changed",
+	AuthorApproved:      "author.approved",
+	AuthorDeleted:       "author.deleted",
+}
+
+var webhookEventMap = map[string]WebhookEvent{
+	"definition.submitted": EnumWebhookEvent.DefinitionSubmitted,
+	"definition.changed":   EnumWebhookEvent.DefinitionChanged,
+	"definition.approved":  EnumWebhookEvent.DefinitionApproved,
+	"definition.rejected":  EnumWebhookEvent.DefinitionRejected,
+	"definition.deleted":   EnumWebhookEvent.DefinitionDeleted,
+	"source.submitted":     EnumWebhookEvent.SourceSubmitted,
+	"source.changed":       EnumWebhookEvent.SourceChanged,
+	"source.approved":      EnumWebhookEvent.SourceApproved,
+	"source.deleted":       EnumWebhookEvent.SourceDeleted,
+	"author.submitted":     EnumWebhookEvent.AuthorSubmitted,
+	"author.changed":       EnumWebhookEvent.AuthorChanged,
+	"author.approved":      EnumWebhookEvent.AuthorApproved,
+	"author.deleted":       EnumWebhookEvent.AuthorDeleted,
+}
+
+func ParseStringToWebhookEvent(str string) (WebhookEvent, error) {
+	event, ok := webhookEventMap[strings.ToLower(str)]
+	if ok {
+		return event, nil
+	} else {
+		return event, constants.ErrorInvalidEnum
+	}
+}
+
+func (event WebhookEvent) String() string {
+	switch event {
+	case EnumWebhookEvent.DefinitionSubmitted:
+		return "definition.submitted"
+	case EnumWebhookEvent.DefinitionChanged:
+		return "definition.changed"
+	case EnumWebhookEvent.DefinitionApproved:
+		return "definition.approved"
+	case EnumWebhookEvent.DefinitionRejected:
+		return "definition.rejected"
+	case EnumWebhookEvent.DefinitionDeleted:
+		return "definition.deleted"
+	case EnumWebhookEvent.SourceSubmitted:
+		return "source.submitted"
+	case EnumWebhookEvent.SourceChanged:
+		return "source.changed"
+	case EnumWebhookEvent.SourceApproved:
+		return "source.approved"
+	case EnumWebhookEvent.SourceDeleted:
+		return "source.deleted"
+	case EnumWebhookEvent.AuthorSubmitted:
+		return "author.submitted"
+	case EnumWebhookEvent.AuthorChanged:
+		return "author.changed"
+	case EnumWebhookEvent.AuthorApproved:
+		return "author.approved"
+	case EnumWebhookEvent.AuthorDeleted:
+		return "author.deleted"
+	}
+	return "unknown"
+}
+
+/* The entity of the event, e.g. "definition" */
+func (event WebhookEvent) GetEntity() string {
+	entity, _, _ := strings.Cut(event.String(), ".")
+	return entity
+}
+
+type WebhookDeliveryStatus string
+
+type webhookDeliveryStatusList struct {
+	Unknown   WebhookDeliveryStatus
+	Pending   WebhookDeliveryStatus
+	Succeeded WebhookDeliveryStatus
+	Failed    WebhookDeliveryStatus
+}
+
+var EnumWebhookDeliveryStatus = &webhookDeliveryStatusList{
+	Unknown:   "unknown",
+	Pending:   "pending",
+	Succeeded: "succeeded",
+	Failed:    "failed",
+}
+
+var webhookDeliveryStatusMap = map[string]WebhookDeliveryStatus{
+	"pending":   EnumWebhookDeliveryStatus.Pending,
+	"succeeded": EnumWebhookDeliveryStatus.Succeeded,
+	"failed":    EnumWebhookDeliveryStatus.Failed,
+}
+
+func ParseStringToWebhookDeliveryStatus(str string) (WebhookDeliveryStatus, error) {
+	status, ok := webhookDeliveryStatusMap[strings.ToLower(str)]
+	if ok {
+		return status, nil
+	} else {
+		return status, constants.ErrorInvalidEnum
+	}
+}
+
+func (status WebhookDeliveryStatus) String() string {
+	switch status {
+	case EnumWebhookDeliveryStatus.Pending:
+		return "pending"
+	case EnumWebhookDeliveryStatus.Succeeded:
+		return "succeeded"
+	case EnumWebhookDeliveryStatus.Failed:
+		return "failed"
+	}
+	return "unknown"
+}
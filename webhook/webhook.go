@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Yacoid-Event"
+	HeaderDelivery  = "X-Yacoid-Delivery"
+	HeaderSignature = "X-Yacoid-Signature"
+
+	// failed deliveries are retried until this number of attempts is reached
+	MaxAttempts = 8
+
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+/*
+The signature of a payload, e.g. "t=1700000000,v1=5257a8...". v1 is the hex encoded HMAC-SHA256 of
+"<t>.<payload>" with the secret of the webhook. Receivers should reject old timestamps to prevent replays.
+*/
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(payload)
+
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+
+}
+
+/* The delay before the next attempt after the given number of failed attempts, doubling from 30 seconds up to 6 hours */
+func Backoff(failedAttempts int) time.Duration {
+
+	delay := firstRetryDelay
+
+	for attempt := 1; attempt < failedAttempts; attempt++ {
+
+		delay *= 2
+
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+
+	}
+
+	return delay
+
+}
+
+/*
+Posts the signed payload to the URL. Returns the status code of the response (0 without response) and an
+error, if the request failed or the status code is not 2xx.
+*/
+func Send(url string, secret string, event string, deliveryId string, payload []byte) (int, error) {
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "yacoid-webhooks")
+	request.Header.Set(HeaderEvent, event)
+	request.Header.Set(HeaderDelivery, deliveryId)
+	request.Header.Set(HeaderSignature, Sign(secret, time.Now(), payload))
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	// the body is drained, so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+
+}
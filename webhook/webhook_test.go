@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+
+	timestamp := time.Unix(1700000000, 0)
+	payload := []byte(`{"event":"definition.approved"}`)
+
+	signature := Sign("secret", timestamp, payload)
+
+	// computed like a receiver verifies it
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(payload)))
+	expected := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if signature != expected {
+		t.Errorf("Sign = %q, expected %q", signature, expected)
+	}
+
+	others := []struct {
+		name      string
+		signature string
+	}{
+		{"secret", Sign("other", timestamp, payload)},
+		{"timestamp", Sign("secret", timestamp.Add(time.Second), payload)},
+		{"payload", Sign("secret", timestamp, []byte(`{"event":"definition.rejected"}`))},
+	}
+
+	for _, other := range others {
+		if strings.SplitN(other.signature, ",", 2)[1] == strings.SplitN(signature, ",", 2)[1] {
+			t.Errorf("another %s creates the same signature", other.name)
+		}
+	}
+
+}
+
+func TestBackoff(t *testing.T) {
+
+	tests := []struct {
+		failedAttempts int
+		expected       time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		// capped at 6 hours
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+
+	for _, test := range tests {
+		if actual := Backoff(test.failedAttempts); actual != test.expected {
+			t.Errorf("Backoff(%d) = %v, expected %v", test.failedAttempts, actual, test.expected)
+		}
+	}
+
+}